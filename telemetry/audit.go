@@ -0,0 +1,76 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AuditCategory is the category attribute of the records written by Audit
+const AuditCategory = "audit"
+
+// auditActorKey is the context key of the actor stored by WithActor
+type auditActorKey struct{}
+
+// WithActor stores who is acting (e.g. a user or service account ID) in ctx, for Audit
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(contextOrBackground(ctx), auditActorKey{}, actor)
+}
+
+// ActorFromContext returns the actor stored by WithActor
+func ActorFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	actor, ok := ctx.Value(auditActorKey{}).(string)
+	return actor, ok && actor != ""
+}
+
+// Audit records that the actor in ctx (see WithActor, "unknown" when unset) performed action,
+// with attrs as extra attributes. Audit records go to their own handlers (Config.AuditHandlers,
+// or stdout) and to the OTLP logs under the <service>/audit scope, apart from the application
+// logs: they carry category=audit, the trace and span IDs even when the trace is not sampled,
+// and are never filtered by log levels, OTLPLogsSampledTracesOnly or LogsEnabled. Handler
+// errors are reported to the OTel error handler.
+func (c *TelemetryClient) Audit(ctx context.Context, action string, attrs map[string]any) {
+	ctx = contextOrBackground(ctx)
+
+	actor, ok := ActorFromContext(ctx)
+	if !ok {
+		actor = "unknown"
+	}
+
+	var pcs [1]uintptr
+	runtime.Callers(2, pcs[:]) // skip runtime.Callers and Audit
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, action, pcs[0])
+	record.AddAttrs(
+		slog.String("category", AuditCategory),
+		slog.String("action", action),
+		slog.String("actor", actor),
+	)
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", spanContext.TraceID().String()),
+			slog.String("span_id", spanContext.SpanID().String()),
+			slog.Bool("trace_sampled", spanContext.IsSampled()),
+		)
+	}
+	if requestID, ok := RequestIDFromContext(ctx); ok {
+		record.AddAttrs(slog.String("request_id", requestID))
+	}
+	for key, value := range attrs {
+		record.AddAttrs(slog.Any(key, value))
+	}
+
+	// The handlers are called directly: Enabled would let a level filter drop the record
+	for _, handler := range c.auditHandlers {
+		if err := handler.Handle(ctx, record.Clone()); err != nil {
+			otel.Handle(tagExportFailure("logs", fmt.Errorf("failed to write audit record %q: %w", action, err)))
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package telemetry
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// bufferState is shared by a buffering handler and everything derived from it
+type bufferState struct {
+	mu      sync.Mutex
+	limit   int
+	records []bufferedRecord
+	dropped int
+}
+
+type bufferedRecord struct {
+	ctx    context.Context
+	record slog.Record
+	derive []func(slog.Handler) slog.Handler
+}
+
+// bufferingHandler holds records in memory until replay hands them to the real handler.
+// Once the buffer holds limit records, newer records are dropped and counted.
+type bufferingHandler struct {
+	state  *bufferState
+	derive []func(slog.Handler) slog.Handler
+}
+
+func newBufferingHandler(limit int) *bufferingHandler {
+	return &bufferingHandler{state: &bufferState{limit: limit}}
+}
+
+func (h *bufferingHandler) Enabled(context.Context, slog.Level) bool {
+	// The real handler decides at replay time
+	return true
+}
+
+func (h *bufferingHandler) Handle(ctx context.Context, record slog.Record) error {
+	h.state.mu.Lock()
+	defer h.state.mu.Unlock()
+
+	if len(h.state.records) >= h.state.limit {
+		h.state.dropped++
+		return nil
+	}
+	h.state.records = append(h.state.records, bufferedRecord{ctx: ctx, record: record.Clone(), derive: h.derive})
+	return nil
+}
+
+func (h *bufferingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithAttrs(attrs) })
+}
+
+func (h *bufferingHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithGroup(name) })
+}
+
+func (h *bufferingHandler) with(derive func(slog.Handler) slog.Handler) *bufferingHandler {
+	chain := append(append([]func(slog.Handler) slog.Handler{}, h.derive...), derive)
+	return &bufferingHandler{state: h.state, derive: chain}
+}
+
+// replay writes the buffered records, in order and with their original contexts, to target
+// and warns about the records that overflowed the buffer
+func (h *bufferingHandler) replay(target slog.Handler) {
+	h.state.mu.Lock()
+	records, dropped := h.state.records, h.state.dropped
+	h.state.records, h.state.dropped = nil, 0
+	h.state.mu.Unlock()
+
+	for _, buffered := range records {
+		handler := target
+		for _, derive := range buffered.derive {
+			handler = derive(handler)
+		}
+		ctx := contextOrBackground(buffered.ctx)
+		if handler.Enabled(ctx, buffered.record.Level) {
+			_ = handler.Handle(ctx, buffered.record)
+		}
+	}
+
+	if dropped > 0 {
+		slog.New(target).Warn("startup log buffer overflowed, records were dropped", "dropped_records", dropped)
+	}
+}
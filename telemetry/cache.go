@@ -0,0 +1,97 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// CacheMetrics records hits and misses of one cache
+type CacheMetrics struct {
+	name   string
+	hits   metric.Int64Counter
+	misses metric.Int64Counter
+
+	// Totals since creation, for the hit ratio gauge
+	hitCount  atomic.Int64
+	missCount atomic.Int64
+
+	labelGuard *cardinalityGuard
+	dynamic    *dynamicAttrs
+	transform  AttributeTransform
+}
+
+// NewCacheMetrics returns metrics for the named cache. All caches share the cache_hits_total and
+// cache_misses_total counters (through the NewCounter registry) with a cache attribute set to
+// name, plus a cache_hit_ratio gauge: hits / (hits + misses) since creation, omitted until the
+// first lookup. Call it once per cache: each call registers another gauge callback.
+func (c *TelemetryClient) NewCacheMetrics(name string) (*CacheMetrics, error) {
+	if name == "" {
+		return nil, fmt.Errorf("cache name must not be empty")
+	}
+
+	hits, err := c.NewCounter("cache_hits", "Total number of cache hits")
+	if err != nil {
+		return nil, err
+	}
+	misses, err := c.NewCounter("cache_misses", "Total number of cache misses")
+	if err != nil {
+		return nil, err
+	}
+
+	m := &CacheMetrics{
+		name:       name,
+		hits:       hits,
+		misses:     misses,
+		labelGuard: c.labelGuard,
+		dynamic:    c.dynamicAttrs,
+		transform:  c.attrTransform,
+	}
+
+	// The SDK keeps only the first callback passed at creation for a name, so each cache
+	// registers its own callback on the shared gauge
+	hitRatio, err := c.Meter.Float64ObservableGauge(
+		"cache_hit_ratio",
+		metric.WithDescription("Ratio of cache lookups that were hits"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache hit ratio gauge: %w", err)
+	}
+	_, err = c.Meter.RegisterCallback(func(_ context.Context, observer metric.Observer) error {
+		hits, misses := m.hitCount.Load(), m.missCount.Load()
+		if hits+misses > 0 {
+			observer.ObserveFloat64(hitRatio, float64(hits)/float64(hits+misses), metric.WithAttributes(attribute.String("cache", name)))
+		}
+		return nil
+	}, hitRatio)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register cache hit ratio callback: %w", err)
+	}
+
+	return m, nil
+}
+
+// RecordHit records a lookup served from the cache
+func (m *CacheMetrics) RecordHit(ctx context.Context) {
+	ctx = contextOrBackground(ctx)
+	m.hitCount.Add(1)
+	m.hits.Add(ctx, 1, metric.WithAttributes(m.attributes(ctx)...))
+}
+
+// RecordMiss records a lookup the cache could not serve
+func (m *CacheMetrics) RecordMiss(ctx context.Context) {
+	ctx = contextOrBackground(ctx)
+	m.missCount.Add(1)
+	m.misses.Add(ctx, 1, metric.WithAttributes(m.attributes(ctx)...))
+}
+
+// attributes adds the cache name, the context labels and the dynamic attributes, then applies
+// the attribute transform
+func (m *CacheMetrics) attributes(ctx context.Context) []attribute.KeyValue {
+	attrs := m.labelGuard.metricAttributes(ctx, attribute.String("cache", m.name))
+	return m.transform.apply(m.labelGuard.dynamicAttributes(m.dynamic, attrs))
+}
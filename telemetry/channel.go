@@ -0,0 +1,95 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// ChannelMetrics measures an in-process channel or queue: its depth, how many items were
+// enqueued and how long they waited before being dequeued
+type ChannelMetrics struct {
+	EnqueuedTotal metric.Int64Counter
+	WaitDuration  metric.Float64Histogram
+
+	labelGuard *cardinalityGuard
+	dynamic    *dynamicAttrs
+	transform  AttributeTransform
+}
+
+// InstrumentChannel creates metrics namespaced with the channel name: the <name>_depth gauge,
+// reading depthFn on every collection (e.g. func() int { return len(ch) }, which must be cheap
+// and safe to call from the SDK's collection goroutine), <name>_enqueued_total and the
+// <name>_wait_seconds histogram of the enqueue-to-dequeue latency, fed by MarkEnqueue and
+// MarkDequeue. A depth close to the capacity, or a growing wait, shows backpressure.
+func (c *TelemetryClient) InstrumentChannel(name string, depthFn func() int) (*ChannelMetrics, error) {
+	if !instrumentNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid channel name %q: must start with a letter and contain only letters, digits, '_', '.', '-' or '/'", name)
+	}
+
+	_, err := c.Meter.Int64ObservableGauge(
+		name+"_depth",
+		metric.WithDescription("Number of items waiting in the "+name+" channel"),
+		metric.WithUnit("1"),
+		metric.WithInt64Callback(func(_ context.Context, observer metric.Int64Observer) error {
+			observer.Observe(int64(depthFn()))
+			return nil
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create channel depth gauge: %w", err)
+	}
+
+	enqueuedTotal, err := c.Meter.Int64Counter(
+		name+"_enqueued_total",
+		metric.WithDescription("Total number of items enqueued in the "+name+" channel"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create enqueued counter: %w", err)
+	}
+
+	waitDuration, err := c.Meter.Float64Histogram(
+		name+"_wait_seconds",
+		metric.WithDescription("Time items waited in the "+name+" channel before being dequeued, in seconds"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wait duration histogram: %w", err)
+	}
+
+	return &ChannelMetrics{
+		EnqueuedTotal: enqueuedTotal,
+		WaitDuration:  waitDuration,
+		labelGuard:    c.labelGuard,
+		dynamic:       c.dynamicAttrs,
+		transform:     c.attrTransform,
+	}, nil
+}
+
+// MarkEnqueue counts an item sent to the channel and returns the time to carry with it (e.g.
+// in the item struct) until MarkDequeue
+func (m *ChannelMetrics) MarkEnqueue(ctx context.Context) time.Time {
+	ctx = contextOrBackground(ctx)
+	m.EnqueuedTotal.Add(ctx, 1, metric.WithAttributes(m.attributes(ctx)...))
+	return time.Now()
+}
+
+// MarkDequeue records how long the item enqueued at enqueuedAt waited in the channel. A zero
+// enqueuedAt (an item not marked on enqueue) is ignored.
+func (m *ChannelMetrics) MarkDequeue(ctx context.Context, enqueuedAt time.Time) {
+	if enqueuedAt.IsZero() {
+		return
+	}
+	ctx = contextOrBackground(ctx)
+	m.WaitDuration.Record(ctx, time.Since(enqueuedAt).Seconds(), metric.WithAttributes(m.attributes(ctx)...))
+}
+
+// attributes adds the context labels and the dynamic attributes, then applies the attribute
+// transform, like WorkerMetrics
+func (m *ChannelMetrics) attributes(ctx context.Context, attrs ...attribute.KeyValue) []attribute.KeyValue {
+	return m.transform.apply(m.labelGuard.dynamicAttributes(m.dynamic, m.labelGuard.metricAttributes(ctx, attrs...)))
+}
@@ -0,0 +1,81 @@
+package telemetry
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+)
+
+// withClock sets the time of every record from clock (Config.Clock)
+func withClock(clock func() time.Time) HandlerOption {
+	return func(opts *handlerOptions) {
+		opts.clock = clock
+	}
+}
+
+// clockTracerProvider timestamps the spans and their events with a clock instead of time.Now,
+// leaving the timestamps set by the caller
+type clockTracerProvider struct {
+	embedded.TracerProvider
+	provider trace.TracerProvider
+	clock    func() time.Time
+}
+
+func (p *clockTracerProvider) Tracer(name string, opts ...trace.TracerOption) trace.Tracer {
+	return &clockTracer{tracer: p.provider.Tracer(name, opts...), clock: p.clock}
+}
+
+type clockTracer struct {
+	embedded.Tracer
+	tracer trace.Tracer
+	clock  func() time.Time
+}
+
+func (t *clockTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if cfg := trace.NewSpanStartConfig(opts...); cfg.Timestamp().IsZero() {
+		opts = append(opts, trace.WithTimestamp(t.clock()))
+	}
+	ctx, span := t.tracer.Start(ctx, name, opts...)
+	span = &clockSpan{Span: span, clock: t.clock}
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+type clockSpan struct {
+	trace.Span
+	clock func() time.Time
+}
+
+func (s *clockSpan) End(opts ...trace.SpanEndOption) {
+	if cfg := trace.NewSpanEndConfig(opts...); cfg.Timestamp().IsZero() {
+		opts = append(opts, trace.WithTimestamp(s.clock()))
+	}
+	s.Span.End(opts...)
+}
+
+func (s *clockSpan) AddEvent(name string, opts ...trace.EventOption) {
+	s.Span.AddEvent(name, s.eventOptions(opts)...)
+}
+
+func (s *clockSpan) RecordError(err error, opts ...trace.EventOption) {
+	s.Span.RecordError(err, s.eventOptions(opts)...)
+}
+
+// eventOptions puts the clock timestamp first so a timestamp from the caller overrides it.
+// trace.NewEventConfig fills in time.Now, so it cannot tell whether the caller set one.
+func (s *clockSpan) eventOptions(opts []trace.EventOption) []trace.EventOption {
+	return append([]trace.EventOption{trace.WithTimestamp(s.clock())}, opts...)
+}
+
+// unsetTimestamp marks an event config whose timestamp the caller did not set
+var unsetTimestamp = time.Unix(0, 1)
+
+// eventTimestamp returns the timestamp set by opts, if any
+func eventTimestamp(opts []trace.EventOption) (time.Time, bool) {
+	cfg := trace.NewEventConfig(append([]trace.EventOption{trace.WithTimestamp(unsetTimestamp)}, opts...)...)
+	if cfg.Timestamp().Equal(unsetTimestamp) {
+		return time.Time{}, false
+	}
+	return cfg.Timestamp(), true
+}
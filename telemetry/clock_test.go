@@ -0,0 +1,61 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestClockTracerProviderTimestamps(t *testing.T) {
+	fixed := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	explicit := fixed.Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		keyCase   KeyCase
+		eventOpts []trace.EventOption
+		wantEvent time.Time
+	}{
+		{name: "clock", wantEvent: fixed},
+		{name: "clock under key case", keyCase: KeyCaseSnake, wantEvent: fixed},
+		{name: "caller timestamp", eventOpts: []trace.EventOption{trace.WithTimestamp(explicit)}, wantEvent: explicit},
+		{name: "caller timestamp under key case", keyCase: KeyCaseDot, eventOpts: []trace.EventOption{trace.WithTimestamp(explicit)}, wantEvent: explicit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			sdkProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+			var provider trace.TracerProvider = &clockTracerProvider{provider: sdkProvider, clock: func() time.Time { return fixed }}
+			if keys := newKeyNormalizer(tt.keyCase); keys != nil {
+				provider = &keyCaseTracerProvider{provider: provider, keys: keys}
+			}
+
+			_, span := provider.Tracer("test").Start(context.Background(), "op")
+			span.AddEvent("event", tt.eventOpts...)
+			span.RecordError(errors.New("boom"), tt.eventOpts...)
+			span.End()
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("got %d spans, want 1", len(spans))
+			}
+			got := spans[0]
+			if !got.StartTime().Equal(fixed) || !got.EndTime().Equal(fixed) {
+				t.Errorf("span times = %v..%v, want %v", got.StartTime(), got.EndTime(), fixed)
+			}
+			if len(got.Events()) != 2 {
+				t.Fatalf("got %d events, want 2", len(got.Events()))
+			}
+			for _, event := range got.Events() {
+				if !event.Time.Equal(tt.wantEvent) {
+					t.Errorf("event %q time = %v, want %v", event.Name, event.Time, tt.wantEvent)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Values of Config.LogFormat
+const (
+	LogFormatJSON        = "json"
+	LogFormatCloudEvents = "cloudevents"
+	LogFormatText        = "text" // slog's key=value text handler, for local development
+)
+
+// CloudEventsLogType is the CloudEvents type of the events written by CloudEventsHandler
+const CloudEventsLogType = "com.github.mmacanmunhoz.otel-helpers.log"
+
+// CloudEventsHandler is a slog.Handler writing each record as a CloudEvents 1.0 JSON envelope,
+// one per line, so logs can go straight to an event bus. The record (level, msg and the
+// attributes, nested by group like the JSON handler) goes in data; under CorrelatedHandler
+// that includes trace_id and span_id, and the span is also set as the traceparent extension.
+type CloudEventsHandler struct {
+	w      io.Writer
+	mu     *sync.Mutex
+	source string
+	opts   slog.HandlerOptions
+	groups []string
+	attrs  []groupedAttrs
+}
+
+// groupedAttrs are attributes added with WithAttrs under the groups open at the time
+type groupedAttrs struct {
+	groups []string
+	attrs  []slog.Attr
+}
+
+// NewCloudEventsHandler creates a handler writing to w with source as the CloudEvents source
+// (usually the service name). opts may be nil; Level and AddSource are honored.
+func NewCloudEventsHandler(w io.Writer, source string, opts *slog.HandlerOptions) *CloudEventsHandler {
+	h := &CloudEventsHandler{w: w, mu: &sync.Mutex{}, source: source}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+func (h *CloudEventsHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+func (h *CloudEventsHandler) Handle(ctx context.Context, record slog.Record) error {
+	data := map[string]any{
+		"level": record.Level.String(),
+		"msg":   record.Message,
+	}
+	if h.opts.AddSource && record.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		data["source"] = map[string]any{"function": frame.Function, "file": frame.File, "line": frame.Line}
+	}
+	for _, grouped := range h.attrs {
+		for _, attr := range grouped.attrs {
+			addCloudEventsAttr(data, grouped.groups, attr)
+		}
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		addCloudEventsAttr(data, h.groups, attr)
+		return true
+	})
+
+	eventTime := record.Time
+	if eventTime.IsZero() {
+		eventTime = time.Now()
+	}
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+
+	line, err := json.Marshal(struct {
+		SpecVersion     string         `json:"specversion"`
+		ID              string         `json:"id"`
+		Source          string         `json:"source"`
+		Type            string         `json:"type"`
+		Time            string         `json:"time"`
+		DataContentType string         `json:"datacontenttype"`
+		TraceParent     string         `json:"traceparent,omitempty"`
+		Data            map[string]any `json:"data"`
+	}{
+		SpecVersion:     "1.0",
+		ID:              uuid.NewString(),
+		Source:          h.source,
+		Type:            CloudEventsLogType,
+		Time:            eventTime.UTC().Format(time.RFC3339Nano),
+		DataContentType: "application/json",
+		TraceParent:     carrier["traceparent"],
+		Data:            data,
+	})
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err = h.w.Write(append(line, '\n'))
+	return err
+}
+
+func (h *CloudEventsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	clone := *h
+	clone.attrs = append(append([]groupedAttrs{}, h.attrs...), groupedAttrs{groups: h.groups, attrs: attrs})
+	return &clone
+}
+
+func (h *CloudEventsHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.groups = append(append([]string{}, h.groups...), name)
+	return &clone
+}
+
+// addCloudEventsAttr adds attr to data under groups, creating the group objects on demand so
+// empty groups are omitted like in the JSON handler
+func addCloudEventsAttr(data map[string]any, groups []string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		members := attr.Value.Group()
+		if len(members) == 0 {
+			return
+		}
+		if attr.Key != "" {
+			groups = append(groups[:len(groups):len(groups)], attr.Key)
+		}
+		for _, member := range members {
+			addCloudEventsAttr(data, groups, member)
+		}
+		return
+	}
+
+	target := data
+	for _, group := range groups {
+		next, ok := target[group].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			target[group] = next
+		}
+		target = next
+	}
+	target[attr.Key] = cloudEventsValue(attr.Value)
+}
+
+// cloudEventsValue converts a resolved non-group value to its JSON form, like the JSON handler
+func cloudEventsValue(value slog.Value) any {
+	switch value.Kind() {
+	case slog.KindTime:
+		return value.Time().Format(time.RFC3339Nano)
+	case slog.KindDuration:
+		return int64(value.Duration())
+	case slog.KindAny:
+		if err, ok := value.Any().(error); ok {
+			return err.Error()
+		}
+	}
+	return value.Any()
+}
@@ -0,0 +1,108 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CodecOption configures TraceCodec
+type CodecOption func(*codecConfig)
+
+type codecConfig struct {
+	span bool
+}
+
+// WithoutCodecSpan skips the codec.<op> span and only records the metrics, for hot paths
+func WithoutCodecSpan() CodecOption {
+	return func(cfg *codecConfig) {
+		cfg.span = false
+	}
+}
+
+type codecMetrics struct {
+	duration metric.Float64Histogram
+	size     metric.Int64Histogram
+}
+
+// TraceCodec measures an encode/decode operation (e.g. op "json.marshal") in a codec.<op> span
+// and in the codec_duration_seconds and codec_payload_bytes histograms, labeled with op. fn
+// returns the payload size in bytes; failed operations only record the duration, with
+// error=true. The error returned by fn is returned unchanged.
+func (c *TelemetryClient) TraceCodec(ctx context.Context, op string, fn func() (int, error), opts ...CodecOption) error {
+	cfg := &codecConfig{span: true}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	ctx = contextOrBackground(ctx)
+	var span trace.Span
+	if cfg.span {
+		ctx, span = c.StartSpan(ctx, "codec."+op, trace.WithAttributes(attribute.String("codec.op", op)))
+		defer span.End()
+	}
+
+	start := time.Now()
+	size, err := fn()
+	duration := time.Since(start)
+
+	metrics := c.codecInstruments()
+	metrics.duration.Record(ctx, duration.Seconds(), metric.WithAttributes(
+		attribute.String("op", op),
+		attribute.Bool("error", err != nil),
+	))
+	if err != nil {
+		if span != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return err
+	}
+
+	metrics.size.Record(ctx, int64(size), metric.WithAttributes(attribute.String("op", op)))
+	if span != nil {
+		span.SetAttributes(attribute.Int("codec.payload_bytes", size))
+	}
+	return nil
+}
+
+// codecInstruments creates the TraceCodec histograms on first use
+func (c *TelemetryClient) codecInstruments() *codecMetrics {
+	c.codecMetricsOnce.Do(func() {
+		metrics, err := newCodecMetrics(c.Meter)
+		if err != nil {
+			c.Logger.Error("failed to create codec metrics, falling back to no-op", "error", err)
+			metrics, _ = newCodecMetrics(noop.NewMeterProvider().Meter(""))
+		}
+		c.codecMetrics = metrics
+	})
+	return c.codecMetrics
+}
+
+func newCodecMetrics(meter metric.Meter) (*codecMetrics, error) {
+	duration, err := meter.Float64Histogram(
+		"codec_duration_seconds",
+		metric.WithDescription("Duration of payload serialization/deserialization in seconds"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create codec duration histogram: %w", err)
+	}
+
+	size, err := meter.Int64Histogram(
+		"codec_payload_bytes",
+		metric.WithDescription("Size of serialized/deserialized payloads in bytes"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create codec payload histogram: %w", err)
+	}
+
+	return &codecMetrics{duration: duration, size: size}, nil
+}
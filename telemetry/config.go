@@ -0,0 +1,110 @@
+package telemetry
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Validate reports the Config fields that are invalid or that contradict each other, such as
+// sampling options with traces disabled, naming the fields involved. Options that would
+// otherwise be ignored silently are rejected, so a misconfiguration fails at startup instead
+// of going unnoticed. SetupWithConfig, NewClient and Reconfigure call it; every problem found
+// is joined in the returned error.
+func (config Config) Validate() error {
+	var errs []error
+	conflict := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if config.MaxConcurrentExports < 0 {
+		conflict("invalid MaxConcurrentExports %d: must be positive, or 0 for unbounded", config.MaxConcurrentExports)
+	}
+
+	if !isEnabled(config.TracesEnabled) {
+		if len(config.RouteSampling) > 0 {
+			conflict("RouteSampling is set but TracesEnabled is false")
+		}
+		if config.ParentSampling != nil {
+			conflict("ParentSampling is set but TracesEnabled is false")
+		}
+		if config.ForceTraceHeader != "" {
+			conflict("ForceTraceHeader is set but TracesEnabled is false")
+		}
+		if config.PrioritySampling {
+			conflict("PrioritySampling is set but TracesEnabled is false")
+		}
+		if len(config.SpanProcessors) > 0 {
+			conflict("SpanProcessors is set but TracesEnabled is false")
+		}
+		if config.TracerProvider != nil {
+			conflict("TracerProvider is set but TracesEnabled is false")
+		}
+	}
+	if config.TracerProvider != nil {
+		if config.ParentSampling != nil {
+			conflict("ParentSampling is set with TracerProvider, configure its sampler instead")
+		}
+		if config.ForceTraceHeader != "" {
+			conflict("ForceTraceHeader is set with TracerProvider, which has no YAML sampler to take over")
+		}
+		if config.PrioritySampling {
+			conflict("PrioritySampling is set with TracerProvider, which has no YAML sampler to take over")
+		}
+		if len(config.SpanProcessors) > 0 {
+			conflict("SpanProcessors is set with TracerProvider, register them on it instead")
+		}
+	}
+	if config.RouteSamplingDefault != nil && len(config.RouteSampling) == 0 {
+		conflict("RouteSamplingDefault is set without RouteSampling, use ParentSampling.Root for a global ratio")
+	}
+	if config.ForceTraceHeader == "" {
+		if config.ForceTraceSecret != "" {
+			conflict("ForceTraceSecret is set without ForceTraceHeader")
+		}
+		if len(config.ForceTraceAllowedNetworks) > 0 {
+			conflict("ForceTraceAllowedNetworks is set without ForceTraceHeader")
+		}
+	}
+
+	if !isEnabled(config.MetricsEnabled) {
+		if config.DebugMetrics {
+			conflict("DebugMetrics is set but MetricsEnabled is false")
+		}
+		if config.ErrorLogCounter != "" {
+			conflict("ErrorLogCounter is set but MetricsEnabled is false")
+		}
+		if len(config.MetricReaderFiles) > 0 {
+			conflict("MetricReaderFiles is set but MetricsEnabled is false")
+		}
+	}
+
+	if !isEnabled(config.LogsEnabled) {
+		if config.OTLPLogsSampledTracesOnly {
+			conflict("OTLPLogsSampledTracesOnly is set but LogsEnabled is false")
+		}
+	}
+	if len(config.LogHandlers) > 0 {
+		if config.LogFormat != "" {
+			conflict("LogFormat %q is set but LogHandlers replaces the default handler", config.LogFormat)
+		}
+		if config.LogTimeKey != "" || config.LogTimeFormat != "" {
+			conflict("LogTimeKey/LogTimeFormat are set but LogHandlers replaces the default handler")
+		}
+	} else if config.LogFormat == LogFormatCloudEvents && (config.LogTimeKey != "" || config.LogTimeFormat != "") {
+		conflict("LogTimeKey/LogTimeFormat are set but LogFormat %q fixes the time attribute", LogFormatCloudEvents)
+	}
+	if config.LogColorByTrace && (config.LogFormat != LogFormatText || len(config.LogHandlers) > 0) {
+		conflict("LogColorByTrace is set but LogFormat is not %q", LogFormatText)
+	}
+
+	switch config.AttributeKeyCase {
+	case KeyCasePassthrough, KeyCaseSnake, KeyCaseDot:
+	default:
+		conflict("invalid AttributeKeyCase %q: must be %q, %q or empty", config.AttributeKeyCase, KeyCaseSnake, KeyCaseDot)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid telemetry config: %w", errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package telemetry
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestValidate(t *testing.T) {
+	off := new(bool)
+	ratio := 0.5
+	provider := sdktrace.NewTracerProvider()
+	processor := sdktrace.NewSimpleSpanProcessor(nil)
+	handler := slog.NewJSONHandler(nil, nil)
+
+	tests := []struct {
+		name   string
+		config Config
+		want   []string // substrings of the error naming the conflicting fields; none when valid
+	}{
+		{name: "zero config"},
+		{
+			name: "valid combination",
+			config: Config{
+				RouteSampling:    map[string]float64{"/health": 0},
+				ForceTraceHeader: "X-Force-Trace",
+				ForceTraceSecret: "secret",
+				LogFormat:        LogFormatText,
+				LogColorByTrace:  true,
+				LogTimeKey:       "ts",
+				AttributeKeyCase: KeyCaseSnake,
+			},
+		},
+
+		{name: "RouteSampling without traces", config: Config{TracesEnabled: off, RouteSampling: map[string]float64{"/": 1}}, want: []string{"RouteSampling", "TracesEnabled"}},
+		{name: "ParentSampling without traces", config: Config{TracesEnabled: off, ParentSampling: &ParentSamplingConfig{}}, want: []string{"ParentSampling", "TracesEnabled"}},
+		{name: "ForceTraceHeader without traces", config: Config{TracesEnabled: off, ForceTraceHeader: "X-Force"}, want: []string{"ForceTraceHeader", "TracesEnabled"}},
+		{name: "PrioritySampling without traces", config: Config{TracesEnabled: off, PrioritySampling: true}, want: []string{"PrioritySampling", "TracesEnabled"}},
+		{name: "SpanProcessors without traces", config: Config{TracesEnabled: off, SpanProcessors: []sdktrace.SpanProcessor{processor}}, want: []string{"SpanProcessors", "TracesEnabled"}},
+		{name: "TracerProvider without traces", config: Config{TracesEnabled: off, TracerProvider: provider}, want: []string{"TracerProvider", "TracesEnabled"}},
+
+		{name: "TracerProvider and ParentSampling", config: Config{TracerProvider: provider, ParentSampling: &ParentSamplingConfig{}}, want: []string{"ParentSampling", "TracerProvider"}},
+		{name: "TracerProvider and ForceTraceHeader", config: Config{TracerProvider: provider, ForceTraceHeader: "X-Force"}, want: []string{"ForceTraceHeader", "TracerProvider"}},
+		{name: "TracerProvider and PrioritySampling", config: Config{TracerProvider: provider, PrioritySampling: true}, want: []string{"PrioritySampling", "TracerProvider"}},
+		{name: "TracerProvider and SpanProcessors", config: Config{TracerProvider: provider, SpanProcessors: []sdktrace.SpanProcessor{processor}}, want: []string{"SpanProcessors", "TracerProvider"}},
+
+		{name: "RouteSamplingDefault without RouteSampling", config: Config{RouteSamplingDefault: &ratio}, want: []string{"RouteSamplingDefault", "RouteSampling"}},
+		{name: "ForceTraceSecret without header", config: Config{ForceTraceSecret: "secret"}, want: []string{"ForceTraceSecret", "ForceTraceHeader"}},
+		{name: "ForceTraceAllowedNetworks without header", config: Config{ForceTraceAllowedNetworks: []string{"10.0.0.0/8"}}, want: []string{"ForceTraceAllowedNetworks", "ForceTraceHeader"}},
+
+		{name: "DebugMetrics without metrics", config: Config{MetricsEnabled: off, DebugMetrics: true}, want: []string{"DebugMetrics", "MetricsEnabled"}},
+		{name: "ErrorLogCounter without metrics", config: Config{MetricsEnabled: off, ErrorLogCounter: "errors"}, want: []string{"ErrorLogCounter", "MetricsEnabled"}},
+		{name: "MetricReaderFiles without metrics", config: Config{MetricsEnabled: off, MetricReaderFiles: []string{"reader.yaml"}}, want: []string{"MetricReaderFiles", "MetricsEnabled"}},
+
+		{name: "LogHandlers without OTLP logs", config: Config{LogsEnabled: off, LogHandlers: []slog.Handler{handler}}},
+		{name: "OTLPLogsSampledTracesOnly without logs", config: Config{LogsEnabled: off, OTLPLogsSampledTracesOnly: true}, want: []string{"OTLPLogsSampledTracesOnly", "LogsEnabled"}},
+		{name: "LogHandlers and LogFormat", config: Config{LogHandlers: []slog.Handler{handler}, LogFormat: LogFormatText}, want: []string{"LogFormat", "LogHandlers"}},
+		{name: "LogHandlers and LogTimeKey", config: Config{LogHandlers: []slog.Handler{handler}, LogTimeKey: "ts"}, want: []string{"LogTimeKey", "LogHandlers"}},
+		{name: "cloudevents and LogTimeFormat", config: Config{LogFormat: LogFormatCloudEvents, LogTimeFormat: "2006"}, want: []string{"LogTimeFormat", "LogFormat"}},
+		{name: "LogColorByTrace with JSON", config: Config{LogColorByTrace: true}, want: []string{"LogColorByTrace", "LogFormat"}},
+		{name: "invalid AttributeKeyCase", config: Config{AttributeKeyCase: "camel"}, want: []string{"AttributeKeyCase"}},
+		{name: "negative MaxConcurrentExports", config: Config{MaxConcurrentExports: -1}, want: []string{"MaxConcurrentExports"}},
+
+		{
+			name:   "every problem joined",
+			config: Config{TracesEnabled: off, MetricsEnabled: off, PrioritySampling: true, DebugMetrics: true},
+			want:   []string{"PrioritySampling", "DebugMetrics"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("Validate = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate = nil, want an error naming %v", tt.want)
+			}
+			for _, field := range tt.want {
+				if !strings.Contains(err.Error(), field) {
+					t.Errorf("Validate = %q, want it to name %s", err, field)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type dbMetrics struct {
+	returned metric.Int64Histogram
+	affected metric.Int64Histogram
+}
+
+// RecordDBRowsReturned records the number of rows a query returned in the db_rows_returned
+// histogram, labeled with operation (e.g. "SELECT orders"), and as db.response.returned_rows on
+// the active span. Call it after the query when the count is known; it is optional, callers
+// without a count just skip it. Past 100 distinct operations the metric records "other".
+func (c *TelemetryClient) RecordDBRowsReturned(ctx context.Context, operation string, rows int64) {
+	ctx = contextOrBackground(ctx)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("db.response.returned_rows", rows))
+	c.dbInstruments().returned.Record(ctx, rows, metric.WithAttributes(
+		attribute.String("operation", c.labelGuard.value("db.operation", operation)),
+	))
+}
+
+// RecordDBRowsAffected records the number of rows a statement changed (sql.Result.RowsAffected)
+// in the db_rows_affected histogram, labeled with operation, and as db.response.affected_rows
+// on the active span. Like RecordDBRowsReturned it is optional.
+func (c *TelemetryClient) RecordDBRowsAffected(ctx context.Context, operation string, rows int64) {
+	ctx = contextOrBackground(ctx)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("db.response.affected_rows", rows))
+	c.dbInstruments().affected.Record(ctx, rows, metric.WithAttributes(
+		attribute.String("operation", c.labelGuard.value("db.operation", operation)),
+	))
+}
+
+// dbInstruments creates the row count histograms on first use
+func (c *TelemetryClient) dbInstruments() *dbMetrics {
+	c.dbMetricsOnce.Do(func() {
+		metrics, err := newDBMetrics(c.Meter)
+		if err != nil {
+			c.Logger.Error("failed to create db metrics, falling back to no-op", "error", err)
+			metrics, _ = newDBMetrics(noop.NewMeterProvider().Meter(""))
+		}
+		c.dbMetrics = metrics
+	})
+	return c.dbMetrics
+}
+
+func newDBMetrics(meter metric.Meter) (*dbMetrics, error) {
+	returned, err := meter.Int64Histogram(
+		"db_rows_returned",
+		metric.WithDescription("Number of rows returned by database queries"),
+		metric.WithUnit("{row}"),
+		metric.WithExplicitBucketBoundaries(countBuckets...),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create db rows returned histogram: %w", err)
+	}
+
+	affected, err := meter.Int64Histogram(
+		"db_rows_affected",
+		metric.WithDescription("Number of rows affected by database statements"),
+		metric.WithUnit("{row}"),
+		metric.WithExplicitBucketBoundaries(countBuckets...),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create db rows affected histogram: %w", err)
+	}
+
+	return &dbMetrics{returned: returned, affected: affected}, nil
+}
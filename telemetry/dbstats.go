@@ -0,0 +1,91 @@
+package telemetry
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"go.opentelemetry.io/otel/metric"
+)
+
+// RegisterDBStats exposes the connection pool stats of a database/sql pool as metrics
+// namespaced with the pool name (e.g. "orders_db"): the <name>_open_connections,
+// <name>_in_use_connections, <name>_idle_connections and <name>_max_open_connections gauges, and
+// the <name>_wait_count_total and <name>_wait_duration_seconds_total counters of the calls that
+// waited for a free connection. stats is usually db.Stats and is called once per collection.
+// Waits growing while in_use sits at max_open mean the pool is too small for the load.
+func (c *TelemetryClient) RegisterDBStats(name string, stats func() sql.DBStats) error {
+	if !instrumentNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid pool name %q: must start with a letter and contain only letters, digits, '_', '.', '-' or '/'", name)
+	}
+
+	open, err := c.Meter.Int64ObservableGauge(
+		name+"_open_connections",
+		metric.WithDescription("Number of established connections of the "+name+" pool, in use or idle"),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create open connections gauge: %w", err)
+	}
+
+	inUse, err := c.Meter.Int64ObservableGauge(
+		name+"_in_use_connections",
+		metric.WithDescription("Number of connections of the "+name+" pool currently in use"),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create in use connections gauge: %w", err)
+	}
+
+	idle, err := c.Meter.Int64ObservableGauge(
+		name+"_idle_connections",
+		metric.WithDescription("Number of idle connections of the "+name+" pool"),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create idle connections gauge: %w", err)
+	}
+
+	maxOpen, err := c.Meter.Int64ObservableGauge(
+		name+"_max_open_connections",
+		metric.WithDescription("Maximum number of open connections of the "+name+" pool (0 is unlimited)"),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create max open connections gauge: %w", err)
+	}
+
+	waitCount, err := c.Meter.Int64ObservableCounter(
+		name+"_wait_count_total",
+		metric.WithDescription("Total number of connections waited for in the "+name+" pool"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create wait count counter: %w", err)
+	}
+
+	waitDuration, err := c.Meter.Float64ObservableCounter(
+		name+"_wait_duration_seconds_total",
+		metric.WithDescription("Total time blocked waiting for a connection of the "+name+" pool in seconds"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create wait duration counter: %w", err)
+	}
+
+	_, err = c.Meter.RegisterCallback(func(_ context.Context, observer metric.Observer) error {
+		s := stats()
+		observer.ObserveInt64(open, int64(s.OpenConnections))
+		observer.ObserveInt64(inUse, int64(s.InUse))
+		observer.ObserveInt64(idle, int64(s.Idle))
+		observer.ObserveInt64(maxOpen, int64(s.MaxOpenConnections))
+		observer.ObserveInt64(waitCount, s.WaitCount)
+		observer.ObserveFloat64(waitDuration, s.WaitDuration.Seconds())
+		return nil
+	}, open, inUse, idle, maxOpen, waitCount, waitDuration)
+	if err != nil {
+		return fmt.Errorf("failed to register db stats callback: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,458 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/embedded"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+const openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// DebugMetricsHandler serves a one-shot OpenMetrics dump of the current metric values.
+// It requires Config.DebugMetrics, otherwise it responds 404 so it is safe to mount everywhere.
+func (c *TelemetryClient) DebugMetricsHandler() http.Handler {
+	if c.debugMetrics == nil {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "debug metrics disabled (Config.DebugMetrics)", http.StatusNotFound)
+		})
+	}
+	return c.debugMetrics
+}
+
+// serveDebugMetrics serves the dump of the current SDK, which Reconfigure may replace
+func (c *TelemetryClient) serveDebugMetrics(w http.ResponseWriter, r *http.Request) {
+	c.sdkMu.Lock()
+	handler := c.sdk.debugMetrics
+	c.sdkMu.Unlock()
+	handler.ServeHTTP(w, r)
+}
+
+// newDebugMeterProvider tees the SDK meter provider into an in-process provider whose
+// reader is collected on demand by the returned handler. otelconf only builds readers
+// from the YAML file, so a reader cannot be added to its provider directly.
+func newDebugMeterProvider(provider metric.MeterProvider) (*teeMeterProvider, http.Handler, error) {
+	registry := prometheus.NewRegistry()
+	reader, err := otelprometheus.New(
+		otelprometheus.WithRegisterer(registry),
+		otelprometheus.WithoutTargetInfo(),
+		otelprometheus.WithoutScopeInfo(),
+		otelprometheus.WithoutUnits(),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create debug metrics reader: %w", err)
+	}
+
+	debugProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	metrics := promhttp.HandlerFor(registry, promhttp.HandlerOpts{EnableOpenMetrics: true})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.Clone(r.Context())
+		r.Header.Set("Accept", openMetricsContentType)
+		metrics.ServeHTTP(w, r)
+	})
+
+	return &teeMeterProvider{primary: provider, debug: debugProvider}, handler, nil
+}
+
+// teeMeterProvider creates every instrument on both providers
+type teeMeterProvider struct {
+	embedded.MeterProvider
+	primary metric.MeterProvider
+	debug   *sdkmetric.MeterProvider
+}
+
+func (p *teeMeterProvider) Meter(name string, opts ...metric.MeterOption) metric.Meter {
+	return &teeMeter{primary: p.primary.Meter(name, opts...), debug: p.debug.Meter(name, opts...)}
+}
+
+func (p *teeMeterProvider) shutdown(ctx context.Context) error {
+	return p.debug.Shutdown(ctx)
+}
+
+type teeMeter struct {
+	embedded.Meter
+	primary metric.Meter
+	debug   metric.Meter
+}
+
+// tee creates an instrument on both meters; if only the debug side fails the primary
+// instrument is used alone, the debug dump just misses it
+func tee[T any](primary func() (T, error), debug func() (T, error), combine func(primary, debug T) T) (T, error) {
+	instrument, err := primary()
+	if err != nil {
+		return instrument, err
+	}
+	debugInstrument, err := debug()
+	if err != nil {
+		otel.Handle(fmt.Errorf("debug metrics: %w", err))
+		return instrument, nil
+	}
+	return combine(instrument, debugInstrument), nil
+}
+
+func (m *teeMeter) Int64Counter(name string, options ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	return tee(
+		func() (metric.Int64Counter, error) { return m.primary.Int64Counter(name, options...) },
+		func() (metric.Int64Counter, error) { return m.debug.Int64Counter(name, options...) },
+		func(a, b metric.Int64Counter) metric.Int64Counter { return &teeInt64Counter{a: a, b: b} },
+	)
+}
+
+func (m *teeMeter) Int64UpDownCounter(name string, options ...metric.Int64UpDownCounterOption) (metric.Int64UpDownCounter, error) {
+	return tee(
+		func() (metric.Int64UpDownCounter, error) { return m.primary.Int64UpDownCounter(name, options...) },
+		func() (metric.Int64UpDownCounter, error) { return m.debug.Int64UpDownCounter(name, options...) },
+		func(a, b metric.Int64UpDownCounter) metric.Int64UpDownCounter {
+			return &teeInt64UpDownCounter{a: a, b: b}
+		},
+	)
+}
+
+func (m *teeMeter) Int64Histogram(name string, options ...metric.Int64HistogramOption) (metric.Int64Histogram, error) {
+	return tee(
+		func() (metric.Int64Histogram, error) { return m.primary.Int64Histogram(name, options...) },
+		func() (metric.Int64Histogram, error) { return m.debug.Int64Histogram(name, options...) },
+		func(a, b metric.Int64Histogram) metric.Int64Histogram { return &teeInt64Histogram{a: a, b: b} },
+	)
+}
+
+func (m *teeMeter) Int64Gauge(name string, options ...metric.Int64GaugeOption) (metric.Int64Gauge, error) {
+	return tee(
+		func() (metric.Int64Gauge, error) { return m.primary.Int64Gauge(name, options...) },
+		func() (metric.Int64Gauge, error) { return m.debug.Int64Gauge(name, options...) },
+		func(a, b metric.Int64Gauge) metric.Int64Gauge { return &teeInt64Gauge{a: a, b: b} },
+	)
+}
+
+func (m *teeMeter) Float64Counter(name string, options ...metric.Float64CounterOption) (metric.Float64Counter, error) {
+	return tee(
+		func() (metric.Float64Counter, error) { return m.primary.Float64Counter(name, options...) },
+		func() (metric.Float64Counter, error) { return m.debug.Float64Counter(name, options...) },
+		func(a, b metric.Float64Counter) metric.Float64Counter { return &teeFloat64Counter{a: a, b: b} },
+	)
+}
+
+func (m *teeMeter) Float64UpDownCounter(name string, options ...metric.Float64UpDownCounterOption) (metric.Float64UpDownCounter, error) {
+	return tee(
+		func() (metric.Float64UpDownCounter, error) { return m.primary.Float64UpDownCounter(name, options...) },
+		func() (metric.Float64UpDownCounter, error) { return m.debug.Float64UpDownCounter(name, options...) },
+		func(a, b metric.Float64UpDownCounter) metric.Float64UpDownCounter {
+			return &teeFloat64UpDownCounter{a: a, b: b}
+		},
+	)
+}
+
+func (m *teeMeter) Float64Histogram(name string, options ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	return tee(
+		func() (metric.Float64Histogram, error) { return m.primary.Float64Histogram(name, options...) },
+		func() (metric.Float64Histogram, error) { return m.debug.Float64Histogram(name, options...) },
+		func(a, b metric.Float64Histogram) metric.Float64Histogram { return &teeFloat64Histogram{a: a, b: b} },
+	)
+}
+
+func (m *teeMeter) Float64Gauge(name string, options ...metric.Float64GaugeOption) (metric.Float64Gauge, error) {
+	return tee(
+		func() (metric.Float64Gauge, error) { return m.primary.Float64Gauge(name, options...) },
+		func() (metric.Float64Gauge, error) { return m.debug.Float64Gauge(name, options...) },
+		func(a, b metric.Float64Gauge) metric.Float64Gauge { return &teeFloat64Gauge{a: a, b: b} },
+	)
+}
+
+// Callbacks passed as options are registered on both meters as they are
+func (m *teeMeter) Int64ObservableCounter(name string, options ...metric.Int64ObservableCounterOption) (metric.Int64ObservableCounter, error) {
+	return tee(
+		func() (metric.Int64ObservableCounter, error) {
+			return m.primary.Int64ObservableCounter(name, options...)
+		},
+		func() (metric.Int64ObservableCounter, error) { return m.debug.Int64ObservableCounter(name, options...) },
+		func(a, b metric.Int64ObservableCounter) metric.Int64ObservableCounter {
+			return &teeInt64ObservableCounter{Int64ObservableCounter: a, debug: b}
+		},
+	)
+}
+
+func (m *teeMeter) Int64ObservableUpDownCounter(name string, options ...metric.Int64ObservableUpDownCounterOption) (metric.Int64ObservableUpDownCounter, error) {
+	return tee(
+		func() (metric.Int64ObservableUpDownCounter, error) {
+			return m.primary.Int64ObservableUpDownCounter(name, options...)
+		},
+		func() (metric.Int64ObservableUpDownCounter, error) {
+			return m.debug.Int64ObservableUpDownCounter(name, options...)
+		},
+		func(a, b metric.Int64ObservableUpDownCounter) metric.Int64ObservableUpDownCounter {
+			return &teeInt64ObservableUpDownCounter{Int64ObservableUpDownCounter: a, debug: b}
+		},
+	)
+}
+
+func (m *teeMeter) Int64ObservableGauge(name string, options ...metric.Int64ObservableGaugeOption) (metric.Int64ObservableGauge, error) {
+	return tee(
+		func() (metric.Int64ObservableGauge, error) { return m.primary.Int64ObservableGauge(name, options...) },
+		func() (metric.Int64ObservableGauge, error) { return m.debug.Int64ObservableGauge(name, options...) },
+		func(a, b metric.Int64ObservableGauge) metric.Int64ObservableGauge {
+			return &teeInt64ObservableGauge{Int64ObservableGauge: a, debug: b}
+		},
+	)
+}
+
+func (m *teeMeter) Float64ObservableCounter(name string, options ...metric.Float64ObservableCounterOption) (metric.Float64ObservableCounter, error) {
+	return tee(
+		func() (metric.Float64ObservableCounter, error) {
+			return m.primary.Float64ObservableCounter(name, options...)
+		},
+		func() (metric.Float64ObservableCounter, error) {
+			return m.debug.Float64ObservableCounter(name, options...)
+		},
+		func(a, b metric.Float64ObservableCounter) metric.Float64ObservableCounter {
+			return &teeFloat64ObservableCounter{Float64ObservableCounter: a, debug: b}
+		},
+	)
+}
+
+func (m *teeMeter) Float64ObservableUpDownCounter(name string, options ...metric.Float64ObservableUpDownCounterOption) (metric.Float64ObservableUpDownCounter, error) {
+	return tee(
+		func() (metric.Float64ObservableUpDownCounter, error) {
+			return m.primary.Float64ObservableUpDownCounter(name, options...)
+		},
+		func() (metric.Float64ObservableUpDownCounter, error) {
+			return m.debug.Float64ObservableUpDownCounter(name, options...)
+		},
+		func(a, b metric.Float64ObservableUpDownCounter) metric.Float64ObservableUpDownCounter {
+			return &teeFloat64ObservableUpDownCounter{Float64ObservableUpDownCounter: a, debug: b}
+		},
+	)
+}
+
+func (m *teeMeter) Float64ObservableGauge(name string, options ...metric.Float64ObservableGaugeOption) (metric.Float64ObservableGauge, error) {
+	return tee(
+		func() (metric.Float64ObservableGauge, error) {
+			return m.primary.Float64ObservableGauge(name, options...)
+		},
+		func() (metric.Float64ObservableGauge, error) { return m.debug.Float64ObservableGauge(name, options...) },
+		func(a, b metric.Float64ObservableGauge) metric.Float64ObservableGauge {
+			return &teeFloat64ObservableGauge{Float64ObservableGauge: a, debug: b}
+		},
+	)
+}
+
+// RegisterCallback registers f on both meters, translating the tee instruments it observes
+func (m *teeMeter) RegisterCallback(f metric.Callback, instruments ...metric.Observable) (metric.Registration, error) {
+	primaries := make([]metric.Observable, 0, len(instruments))
+	debugs := make([]metric.Observable, 0, len(instruments))
+	for _, instrument := range instruments {
+		if t, ok := instrument.(teeObservable); ok {
+			primary, debug := t.observables()
+			primaries = append(primaries, primary)
+			debugs = append(debugs, debug)
+		} else {
+			primaries = append(primaries, instrument)
+		}
+	}
+
+	primary, err := m.primary.RegisterCallback(func(ctx context.Context, observer metric.Observer) error {
+		return f(ctx, &teeObserver{observer: observer})
+	}, primaries...)
+	if err != nil {
+		return nil, err
+	}
+	if len(debugs) == 0 {
+		return primary, nil
+	}
+
+	debug, err := m.debug.RegisterCallback(func(ctx context.Context, observer metric.Observer) error {
+		return f(ctx, &teeObserver{observer: observer, debug: true})
+	}, debugs...)
+	if err != nil {
+		otel.Handle(fmt.Errorf("debug metrics: %w", err))
+		return primary, nil
+	}
+	return &teeRegistration{primary: primary, debug: debug}, nil
+}
+
+type teeRegistration struct {
+	embedded.Registration
+	primary metric.Registration
+	debug   metric.Registration
+}
+
+func (r *teeRegistration) Unregister() error {
+	return errors.Join(r.primary.Unregister(), r.debug.Unregister())
+}
+
+// teeObserver hands each side the instrument created on its own meter, the SDK rejects
+// instruments it did not create
+type teeObserver struct {
+	embedded.Observer
+	observer metric.Observer
+	debug    bool
+}
+
+func (o *teeObserver) ObserveInt64(instrument metric.Int64Observable, value int64, opts ...metric.ObserveOption) {
+	if t, ok := instrument.(teeObservable); ok {
+		instrument, _ = o.pick(t).(metric.Int64Observable)
+	}
+	o.observer.ObserveInt64(instrument, value, opts...)
+}
+
+func (o *teeObserver) ObserveFloat64(instrument metric.Float64Observable, value float64, opts ...metric.ObserveOption) {
+	if t, ok := instrument.(teeObservable); ok {
+		instrument, _ = o.pick(t).(metric.Float64Observable)
+	}
+	o.observer.ObserveFloat64(instrument, value, opts...)
+}
+
+func (o *teeObserver) pick(instrument teeObservable) metric.Observable {
+	primary, debug := instrument.observables()
+	if o.debug {
+		return debug
+	}
+	return primary
+}
+
+// teeObservable is implemented by the observable tee instruments, which embed the primary
+// instrument to satisfy the API interfaces
+type teeObservable interface {
+	observables() (primary, debug metric.Observable)
+}
+
+type teeInt64ObservableCounter struct {
+	metric.Int64ObservableCounter
+	debug metric.Int64ObservableCounter
+}
+
+func (i *teeInt64ObservableCounter) observables() (metric.Observable, metric.Observable) {
+	return i.Int64ObservableCounter, i.debug
+}
+
+type teeInt64ObservableUpDownCounter struct {
+	metric.Int64ObservableUpDownCounter
+	debug metric.Int64ObservableUpDownCounter
+}
+
+func (i *teeInt64ObservableUpDownCounter) observables() (metric.Observable, metric.Observable) {
+	return i.Int64ObservableUpDownCounter, i.debug
+}
+
+type teeInt64ObservableGauge struct {
+	metric.Int64ObservableGauge
+	debug metric.Int64ObservableGauge
+}
+
+func (i *teeInt64ObservableGauge) observables() (metric.Observable, metric.Observable) {
+	return i.Int64ObservableGauge, i.debug
+}
+
+type teeFloat64ObservableCounter struct {
+	metric.Float64ObservableCounter
+	debug metric.Float64ObservableCounter
+}
+
+func (i *teeFloat64ObservableCounter) observables() (metric.Observable, metric.Observable) {
+	return i.Float64ObservableCounter, i.debug
+}
+
+type teeFloat64ObservableUpDownCounter struct {
+	metric.Float64ObservableUpDownCounter
+	debug metric.Float64ObservableUpDownCounter
+}
+
+func (i *teeFloat64ObservableUpDownCounter) observables() (metric.Observable, metric.Observable) {
+	return i.Float64ObservableUpDownCounter, i.debug
+}
+
+type teeFloat64ObservableGauge struct {
+	metric.Float64ObservableGauge
+	debug metric.Float64ObservableGauge
+}
+
+func (i *teeFloat64ObservableGauge) observables() (metric.Observable, metric.Observable) {
+	return i.Float64ObservableGauge, i.debug
+}
+
+type teeInt64Counter struct {
+	embedded.Int64Counter
+	a, b metric.Int64Counter
+}
+
+func (i *teeInt64Counter) Add(ctx context.Context, incr int64, options ...metric.AddOption) {
+	i.a.Add(ctx, incr, options...)
+	i.b.Add(ctx, incr, options...)
+}
+
+type teeInt64UpDownCounter struct {
+	embedded.Int64UpDownCounter
+	a, b metric.Int64UpDownCounter
+}
+
+func (i *teeInt64UpDownCounter) Add(ctx context.Context, incr int64, options ...metric.AddOption) {
+	i.a.Add(ctx, incr, options...)
+	i.b.Add(ctx, incr, options...)
+}
+
+type teeInt64Histogram struct {
+	embedded.Int64Histogram
+	a, b metric.Int64Histogram
+}
+
+func (i *teeInt64Histogram) Record(ctx context.Context, value int64, options ...metric.RecordOption) {
+	i.a.Record(ctx, value, options...)
+	i.b.Record(ctx, value, options...)
+}
+
+type teeInt64Gauge struct {
+	embedded.Int64Gauge
+	a, b metric.Int64Gauge
+}
+
+func (i *teeInt64Gauge) Record(ctx context.Context, value int64, options ...metric.RecordOption) {
+	i.a.Record(ctx, value, options...)
+	i.b.Record(ctx, value, options...)
+}
+
+type teeFloat64Counter struct {
+	embedded.Float64Counter
+	a, b metric.Float64Counter
+}
+
+func (i *teeFloat64Counter) Add(ctx context.Context, incr float64, options ...metric.AddOption) {
+	i.a.Add(ctx, incr, options...)
+	i.b.Add(ctx, incr, options...)
+}
+
+type teeFloat64UpDownCounter struct {
+	embedded.Float64UpDownCounter
+	a, b metric.Float64UpDownCounter
+}
+
+func (i *teeFloat64UpDownCounter) Add(ctx context.Context, incr float64, options ...metric.AddOption) {
+	i.a.Add(ctx, incr, options...)
+	i.b.Add(ctx, incr, options...)
+}
+
+type teeFloat64Histogram struct {
+	embedded.Float64Histogram
+	a, b metric.Float64Histogram
+}
+
+func (i *teeFloat64Histogram) Record(ctx context.Context, value float64, options ...metric.RecordOption) {
+	i.a.Record(ctx, value, options...)
+	i.b.Record(ctx, value, options...)
+}
+
+type teeFloat64Gauge struct {
+	embedded.Float64Gauge
+	a, b metric.Float64Gauge
+}
+
+func (i *teeFloat64Gauge) Record(ctx context.Context, value float64, options ...metric.RecordOption) {
+	i.a.Record(ctx, value, options...)
+	i.b.Record(ctx, value, options...)
+}
@@ -0,0 +1,122 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultDependencyCheckTimeout bounds CheckDependency when the context has no deadline
+const DefaultDependencyCheckTimeout = 5 * time.Second
+
+type dependencyMetrics struct {
+	duration metric.Float64Histogram
+
+	mu sync.Mutex
+	up map[string]int64 // last result per dependency, read by the dependency_up callback
+}
+
+// CheckDependency runs a health check of a downstream dependency (e.g. name "postgres") in a
+// dependency.<name> span, bounded by the context deadline or DefaultDependencyCheckTimeout
+// when there is none. The latency goes to dependency_check_duration_seconds and the result to
+// the dependency_up gauge (1 when check returned nil, 0 otherwise), both labeled with
+// dependency; failures are also logged at warn level. It returns the error of check.
+func (c *TelemetryClient) CheckDependency(ctx context.Context, name string, check func(ctx context.Context) error) error {
+	ctx = contextOrBackground(ctx)
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultDependencyCheckTimeout)
+		defer cancel()
+	}
+
+	ctx, span := c.StartSpan(ctx, "dependency."+name, trace.WithAttributes(attribute.String("dependency.name", name)))
+	defer span.End()
+
+	start := time.Now()
+	err := check(ctx)
+	duration := time.Since(start)
+
+	dependency := c.labelGuard.value("dependency", name)
+	metrics := c.dependencyInstruments()
+	metrics.duration.Record(ctx, duration.Seconds(), metric.WithAttributes(
+		attribute.String("dependency", dependency),
+		attribute.Bool("error", err != nil),
+	))
+	up := int64(1)
+	if err != nil {
+		up = 0
+	}
+	metrics.mu.Lock()
+	metrics.up[dependency] = up
+	metrics.mu.Unlock()
+
+	span.SetAttributes(attribute.Bool("dependency.up", err == nil))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		c.Logger.WarnContext(ctx, "dependency check failed",
+			"dependency", name,
+			"duration", duration.String(),
+			"error", err,
+		)
+	}
+	return err
+}
+
+// dependencyInstruments creates the CheckDependency instruments on first use
+func (c *TelemetryClient) dependencyInstruments() *dependencyMetrics {
+	c.dependencyMetricsOnce.Do(func() {
+		metrics, err := newDependencyMetrics(c.Meter)
+		if err != nil {
+			c.Logger.Error("failed to create dependency metrics, falling back to no-op", "error", err)
+			metrics, _ = newDependencyMetrics(noop.NewMeterProvider().Meter(""))
+		}
+		c.dependencyMetrics = metrics
+	})
+	return c.dependencyMetrics
+}
+
+func newDependencyMetrics(meter metric.Meter) (*dependencyMetrics, error) {
+	m := &dependencyMetrics{up: map[string]int64{}}
+
+	duration, err := meter.Float64Histogram(
+		"dependency_check_duration_seconds",
+		metric.WithDescription("Duration of dependency health checks in seconds"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dependency check duration histogram: %w", err)
+	}
+	m.duration = duration
+
+	// The SDK keeps only the first callback passed at creation for a name, so the callback is
+	// registered separately
+	up, err := meter.Int64ObservableGauge(
+		"dependency_up",
+		metric.WithDescription("Whether the last health check of the dependency succeeded (1) or failed (0)"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dependency up gauge: %w", err)
+	}
+	_, err = meter.RegisterCallback(func(_ context.Context, observer metric.Observer) error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for dependency, value := range m.up {
+			observer.ObserveInt64(up, value, metric.WithAttributes(attribute.String("dependency", dependency)))
+		}
+		return nil
+	}, up)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register dependency up callback: %w", err)
+	}
+
+	return m, nil
+}
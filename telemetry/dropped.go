@@ -0,0 +1,166 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// DroppedSpansProcessor is a batch span processor that counts the spans it drops. The SDK
+// batch processor drops spans silently when its queue is full, so this one puts its own
+// bounded queue in front of a blocking batch processor: when the exporter falls behind, the
+// front queue fills up and the spans that do not fit are dropped and counted.
+type DroppedSpansProcessor struct {
+	batch   sdktrace.SpanProcessor
+	queue   chan sdktrace.ReadOnlySpan
+	done    chan struct{}
+	dropped atomic.Int64
+	pending atomic.Int64 // spans accepted but not yet handed to the batch processor
+
+	mu      sync.RWMutex // guards stopped against OnEnd sending on the closed queue
+	stopped bool
+}
+
+// NewDroppedSpansProcessor creates the processor exporting through exporter. queueSize <= 0
+// uses sdktrace.DefaultMaxQueueSize; opts configure the underlying batch processor, which
+// always blocks on a full queue.
+func NewDroppedSpansProcessor(exporter sdktrace.SpanExporter, queueSize int, opts ...sdktrace.BatchSpanProcessorOption) *DroppedSpansProcessor {
+	if queueSize <= 0 {
+		queueSize = sdktrace.DefaultMaxQueueSize
+	}
+	p := &DroppedSpansProcessor{
+		batch: sdktrace.NewBatchSpanProcessor(tracesExporter{exporter}, append(opts, sdktrace.WithBlocking())...),
+		queue: make(chan sdktrace.ReadOnlySpan, queueSize),
+		done:  make(chan struct{}),
+	}
+	go p.forward()
+	return p
+}
+
+func (p *DroppedSpansProcessor) forward() {
+	defer close(p.done)
+	for span := range p.queue {
+		p.batch.OnEnd(span)
+		p.pending.Add(-1)
+	}
+}
+
+// Dropped returns the number of spans dropped since creation
+func (p *DroppedSpansProcessor) Dropped() int64 {
+	return p.dropped.Load()
+}
+
+func (p *DroppedSpansProcessor) OnStart(ctx context.Context, span sdktrace.ReadWriteSpan) {
+	p.batch.OnStart(ctx, span)
+}
+
+func (p *DroppedSpansProcessor) OnEnd(span sdktrace.ReadOnlySpan) {
+	if !span.SpanContext().IsSampled() {
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.stopped {
+		return
+	}
+	p.pending.Add(1)
+	select {
+	case p.queue <- span:
+	default:
+		p.pending.Add(-1)
+		p.dropped.Add(1)
+	}
+}
+
+// ForceFlush waits for the queued spans to reach the batch processor, then flushes it
+func (p *DroppedSpansProcessor) ForceFlush(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for p.pending.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return p.batch.ForceFlush(ctx)
+}
+
+// Shutdown stops accepting spans, hands the queued ones to the batch processor and shuts it down
+func (p *DroppedSpansProcessor) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.stopped {
+		p.stopped = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return p.batch.Shutdown(ctx)
+}
+
+// retainedProcessor registers a Config.SpanProcessors entry on the SDKs built by NewClient and
+// Reconfigure: their shutdown only flushes it, it is shut down once with the client
+type retainedProcessor struct {
+	sdktrace.SpanProcessor
+}
+
+func (p retainedProcessor) Shutdown(ctx context.Context) error {
+	return p.ForceFlush(ctx)
+}
+
+// shutdownSpanProcessors shuts down the Config.SpanProcessors entries
+func shutdownSpanProcessors(ctx context.Context, processors []sdktrace.SpanProcessor) error {
+	var errs []error
+	for _, processor := range processors {
+		if err := processor.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to shut down span processor: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// registerDroppedSpans exposes the drops of the DroppedSpansProcessor entries as spans_dropped_total
+func registerDroppedSpans(meter metric.Meter, processors []sdktrace.SpanProcessor) error {
+	var dropping []*DroppedSpansProcessor
+	for _, processor := range processors {
+		if p, ok := processor.(*DroppedSpansProcessor); ok {
+			dropping = append(dropping, p)
+		}
+	}
+	if len(dropping) == 0 {
+		return nil
+	}
+
+	counter, err := meter.Int64ObservableCounter(
+		"spans_dropped_total",
+		metric.WithDescription("Total number of spans dropped because the export queue was full"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create dropped spans counter: %w", err)
+	}
+	_, err = meter.RegisterCallback(func(_ context.Context, observer metric.Observer) error {
+		var dropped int64
+		for _, p := range dropping {
+			dropped += p.Dropped()
+		}
+		observer.ObserveInt64(counter, dropped)
+		return nil
+	}, counter)
+	if err != nil {
+		return fmt.Errorf("failed to register dropped spans callback: %w", err)
+	}
+	return nil
+}
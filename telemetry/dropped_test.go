@@ -0,0 +1,136 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// callLog records the processor callbacks in call order
+type callLog struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (l *callLog) add(call string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.calls = append(l.calls, call)
+}
+
+func (l *callLog) list() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.calls...)
+}
+
+// orderProcessor logs its callbacks with the dynamic attribute seen at each one, and
+// tags the span in OnStart like a cost-tagging processor would
+type orderProcessor struct {
+	name string
+	log  *callLog
+}
+
+func (p orderProcessor) OnStart(_ context.Context, span sdktrace.ReadWriteSpan) {
+	p.log.add(fmt.Sprintf("%s.OnStart region=%q", p.name, spanRegion(span)))
+	span.SetAttributes(attribute.String("tagged."+p.name, "yes"))
+}
+
+func (p orderProcessor) OnEnd(span sdktrace.ReadOnlySpan) {
+	var tags []string
+	for _, name := range []string{"first", "second"} {
+		if _, ok := spanAttr(span, attribute.Key("tagged."+name)); ok {
+			tags = append(tags, name)
+		}
+	}
+	p.log.add(fmt.Sprintf("%s.OnEnd region=%q tags=%v", p.name, spanRegion(span), tags))
+}
+
+func (p orderProcessor) Shutdown(context.Context) error {
+	p.log.add(p.name + ".Shutdown")
+	return nil
+}
+
+func (p orderProcessor) ForceFlush(context.Context) error { return nil }
+
+func spanRegion(span sdktrace.ReadOnlySpan) string {
+	value, _ := spanAttr(span, "deployment.region")
+	return value.AsString()
+}
+
+func TestSpanProcessorsOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		processors []string
+		want       []string
+	}{
+		{
+			name:       "one",
+			processors: []string{"first"},
+			want: []string{
+				`first.OnStart region=""`,
+				`first.OnEnd region="eu-west-1" tags=[first]`,
+			},
+		},
+		{
+			name:       "slice order",
+			processors: []string{"first", "second"},
+			want: []string{
+				`first.OnStart region=""`,
+				`second.OnStart region=""`,
+				`first.OnEnd region="eu-west-1" tags=[first second]`,
+				`second.OnEnd region="eu-west-1" tags=[first second]`,
+			},
+		},
+		{
+			name:       "reversed slice",
+			processors: []string{"second", "first"},
+			want: []string{
+				`second.OnStart region=""`,
+				`first.OnStart region=""`,
+				`second.OnEnd region="eu-west-1" tags=[first second]`,
+				`first.OnEnd region="eu-west-1" tags=[first second]`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := &callLog{}
+			var processors []sdktrace.SpanProcessor
+			for _, name := range tt.processors {
+				processors = append(processors, orderProcessor{name: name, log: log})
+			}
+			client, err := NewClient(context.Background(), Config{
+				ConfigPath:     writeConfig(t, "file_format: \"0.3\"\ntracer_provider: {}\n"),
+				SpanProcessors: processors,
+			})
+			if err != nil {
+				t.Fatalf("NewClient: %v", err)
+			}
+			// The dynamic attributes processor is registered after Config.SpanProcessors
+			client.SetDynamicAttr("deployment.region", "eu-west-1")
+
+			_, span := client.StartSpan(context.Background(), "op")
+			span.End()
+			got := log.list()
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("calls =\n%v\nwant\n%v", got, tt.want)
+			}
+
+			if err := client.Shutdown(context.Background()); err != nil {
+				t.Fatalf("Shutdown: %v", err)
+			}
+			var wantShutdowns []string
+			for _, name := range tt.processors {
+				wantShutdowns = append(wantShutdowns, name+".Shutdown")
+			}
+			if shutdowns := log.list()[len(got):]; fmt.Sprint(shutdowns) != fmt.Sprint(wantShutdowns) {
+				t.Errorf("calls after Shutdown = %v, want %v", shutdowns, wantShutdowns)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package telemetry
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// dynamicAttrs holds attributes that may change at runtime. Readers load an immutable
+// snapshot, writers copy it, so the hot paths (every log, span and metric) never lock.
+type dynamicAttrs struct {
+	mu       sync.Mutex // serializes writers
+	snapshot atomic.Pointer[map[string]string]
+}
+
+func newDynamicAttrs() *dynamicAttrs {
+	d := &dynamicAttrs{}
+	d.snapshot.Store(&map[string]string{})
+	return d
+}
+
+func (d *dynamicAttrs) set(key, value string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	current := *d.snapshot.Load()
+	next := make(map[string]string, len(current)+1)
+	for k, v := range current {
+		next[k] = v
+	}
+	if value == "" {
+		delete(next, key)
+	} else {
+		next[key] = value
+	}
+	d.snapshot.Store(&next)
+}
+
+func (d *dynamicAttrs) load() map[string]string {
+	if d == nil {
+		return nil
+	}
+	return *d.snapshot.Load()
+}
+
+// SetDynamicAttr sets an attribute added to every span started, log written and HTTP/worker
+// metric recorded from now on (e.g. deployment.color during a blue/green switch). An empty
+// value removes it. These are not resource attributes: they are copied onto each signal, and
+// every distinct value creates new metric series, so keep the values few and stable.
+func (c *TelemetryClient) SetDynamicAttr(key, value string) {
+	c.dynamicAttrs.set(key, value)
+}
+
+// dynamicLogAttrs returns the dynamic attributes as log attributes, skipping keys already in the record
+func dynamicLogAttrs(dynamic *dynamicAttrs, record slog.Record) []slog.Attr {
+	current := dynamic.load()
+	if len(current) == 0 {
+		return nil
+	}
+
+	present := make(map[string]bool, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		present[attr.Key] = true
+		return true
+	})
+
+	attrs := make([]slog.Attr, 0, len(current))
+	for key, value := range current {
+		if !present[key] {
+			attrs = append(attrs, slog.String(key, value))
+		}
+	}
+	return attrs
+}
+
+// dynamicAttrsProcessor sets the dynamic attributes on spans when they start
+type dynamicAttrsProcessor struct {
+	dynamic *dynamicAttrs
+	keys    *keyNormalizer
+}
+
+func (p dynamicAttrsProcessor) OnStart(_ context.Context, span sdktrace.ReadWriteSpan) {
+	for key, value := range p.dynamic.load() {
+		span.SetAttributes(attribute.String(p.keys.key(key), value))
+	}
+}
+
+func (p dynamicAttrsProcessor) OnEnd(sdktrace.ReadOnlySpan)      {}
+func (p dynamicAttrsProcessor) Shutdown(context.Context) error   { return nil }
+func (p dynamicAttrsProcessor) ForceFlush(context.Context) error { return nil }
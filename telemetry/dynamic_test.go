@@ -0,0 +1,128 @@
+package telemetry
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestSetDynamicAttr(t *testing.T) {
+	tests := []struct {
+		name string
+		sets [][2]string
+		want map[string]string
+	}{
+		{name: "set", sets: [][2]string{{"deployment.color", "blue"}}, want: map[string]string{"deployment.color": "blue"}},
+		{name: "overwrite", sets: [][2]string{{"deployment.color", "blue"}, {"deployment.color", "green"}}, want: map[string]string{"deployment.color": "green"}},
+		{name: "empty removes", sets: [][2]string{{"deployment.color", "blue"}, {"deployment.color", ""}}, want: map[string]string{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTestTelemetry(t, Config{})
+			for _, set := range tc.sets {
+				tt.client.SetDynamicAttr(set[0], set[1])
+			}
+			tt.client.Logger.InfoContext(context.Background(), "log")
+			_, span := tt.client.StartSpan(context.Background(), "span")
+			span.End()
+
+			line := tt.logs.lines(t)[0]
+			for key, want := range tc.want {
+				if line[key] != want {
+					t.Errorf("log %s = %v, want %s", key, line[key], want)
+				}
+				if got, _ := spanAttr(tt.spans.Ended()[0], attribute.Key(key)); got.AsString() != want {
+					t.Errorf("span %s = %q, want %s", key, got.AsString(), want)
+				}
+			}
+			if len(tc.want) == 0 {
+				if _, ok := line["deployment.color"]; ok {
+					t.Error("removed attribute still logged")
+				}
+				if _, ok := spanAttr(tt.spans.Ended()[0], "deployment.color"); ok {
+					t.Error("removed attribute still on the span")
+				}
+			}
+		})
+	}
+}
+
+// TestSetDynamicAttrConcurrent flips an attribute while logs, spans and metrics are recorded
+// from other goroutines; run with -race. Every signal must see a valid value, and the copy on
+// write must never lose the untouched attribute.
+func TestSetDynamicAttrConcurrent(t *testing.T) {
+	tt := newTestTelemetry(t, Config{})
+	metrics, err := tt.client.NewHTTPMetrics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt.client.SetDynamicAttr("deployment.color", "blue")
+	tt.client.SetDynamicAttr("deployment.region", "eu")
+
+	const readers, iterations = 8, 200
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			tt.client.SetDynamicAttr("deployment.color", []string{"blue", "green"}[i%2])
+		}
+	}()
+
+	var readersWG sync.WaitGroup
+	for r := 0; r < readers; r++ {
+		readersWG.Add(1)
+		go func() {
+			defer readersWG.Done()
+			for i := 0; i < iterations; i++ {
+				ctx, span := tt.client.StartSpan(context.Background(), "op")
+				tt.client.Logger.InfoContext(ctx, "tick")
+				metrics.RecordRequest(ctx, "GET", "/", "200", time.Millisecond)
+				span.End()
+			}
+		}()
+	}
+	readersWG.Wait()
+	close(stop)
+	wg.Wait()
+
+	validColor := map[string]bool{"blue": true, "green": true}
+	for _, line := range tt.logs.lines(t) {
+		if color, _ := line["deployment.color"].(string); !validColor[color] || line["deployment.region"] != "eu" {
+			t.Fatalf("log with missing dynamic attributes: %v", line)
+		}
+	}
+	spans := tt.spans.Ended()
+	if len(spans) != readers*iterations {
+		t.Fatalf("got %d spans, want %d", len(spans), readers*iterations)
+	}
+	for _, span := range spans {
+		color, _ := spanAttr(span, "deployment.color")
+		region, _ := spanAttr(span, "deployment.region")
+		if !validColor[color.AsString()] || region.AsString() != "eu" {
+			t.Fatalf("span with missing dynamic attributes: %v", span.Attributes())
+		}
+	}
+	var total int64
+	for _, dp := range tt.metric(t, "http_requests_total").Data.(metricdata.Sum[int64]).DataPoints {
+		color, _ := dp.Attributes.Value("deployment.color")
+		region, _ := dp.Attributes.Value("deployment.region")
+		if !validColor[color.AsString()] || region.AsString() != "eu" {
+			t.Fatalf("metric point with missing dynamic attributes: %v", dp.Attributes)
+		}
+		total += dp.Value
+	}
+	if total != readers*iterations {
+		t.Errorf("http_requests_total = %d, want %d", total, readers*iterations)
+	}
+}
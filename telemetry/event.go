@@ -0,0 +1,156 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Event is something that happened, recorded by Record as a log line, a span event and a
+// metric sharing the same attributes
+type Event struct {
+	// Name is the log message and the span event name
+	Name string
+	// Level is the log level (info when zero)
+	Level slog.Level
+	// Attrs are the attributes of the three sinks
+	Attrs map[string]any
+
+	// MetricName names the metric; empty skips it. Counters get the _total suffix.
+	MetricName string
+	// MetricValue is added to the counter, or recorded in the histogram with MetricHistogram.
+	// For counters 0 counts the event once.
+	MetricValue float64
+	// MetricHistogram records MetricValue in a histogram (e.g. a duration or a size) instead
+	// of adding it to a counter
+	MetricHistogram bool
+
+	// SkipLog and SkipSpanEvent turn off the log line and the span event
+	SkipLog       bool
+	SkipSpanEvent bool
+}
+
+// Record emits event as a correlated log line at event.Level, an event on the active span of
+// ctx and a metric, replacing the separate log, span and metric calls that otherwise repeat the
+// same attributes. The attributes go through AttributeTransform first. The span event follows
+// the span attribute limits. On the metric every attribute value is recorded as a string,
+// capped at 100 distinct values per key ("other" afterwards), with the context labels and
+// dynamic attributes added; keep high-cardinality attributes such as IDs out of events that
+// record a metric, or accept them collapsing into "other". Invalid metric names are logged and
+// the metric is dropped.
+func (c *TelemetryClient) Record(ctx context.Context, event Event) {
+	ctx = contextOrBackground(ctx)
+	attrs := c.attrTransform.applyMap(event.Attrs)
+	keys := slices.Sorted(maps.Keys(attrs))
+
+	if !event.SkipSpanEvent {
+		if span := trace.SpanFromContext(ctx); span.IsRecording() {
+			spanAttrs := make([]attribute.KeyValue, 0, len(keys))
+			for _, key := range keys {
+				spanAttrs = append(spanAttrs, attributeFromValue(key, attrs[key]))
+			}
+			span.AddEvent(event.Name, trace.WithAttributes(c.attrLimits.apply(spanAttrs)...))
+		}
+	}
+
+	if event.MetricName != "" {
+		c.recordEventMetric(ctx, event, attrs, keys)
+	}
+
+	if !event.SkipLog {
+		args := make([]any, 0, len(keys)*2)
+		for _, key := range keys {
+			args = append(args, key, attrs[key])
+		}
+		c.log(ctx, event.Level, event.Name, args...)
+	}
+}
+
+func (c *TelemetryClient) recordEventMetric(ctx context.Context, event Event, attrs map[string]any, keys []string) {
+	kvs := make([]attribute.KeyValue, 0, len(keys))
+	for _, key := range keys {
+		value := c.labelGuard.value(event.MetricName+"."+key, fmt.Sprint(attrs[key]))
+		kvs = append(kvs, attribute.String(key, value))
+	}
+	kvs = c.labelGuard.dynamicAttributes(c.dynamicAttrs, c.labelGuard.metricAttributes(ctx, kvs...))
+	options := metric.WithAttributes(kvs...)
+
+	if event.MetricHistogram {
+		histogram, err := c.eventHistogram(event.MetricName)
+		if err != nil {
+			c.Logger.ErrorContext(ctx, "failed to record event metric", "error", err)
+			return
+		}
+		histogram.Record(ctx, event.MetricValue, options)
+		return
+	}
+
+	counter, err := c.eventCounter(event.MetricName)
+	if err != nil {
+		c.Logger.ErrorContext(ctx, "failed to record event metric", "error", err)
+		return
+	}
+	value := event.MetricValue
+	if value == 0 {
+		value = 1
+	}
+	counter.Add(ctx, value, options)
+}
+
+// eventCounter returns the cached <name>_total counter of Record
+func (c *TelemetryClient) eventCounter(name string) (metric.Float64Counter, error) {
+	if !strings.HasSuffix(name, "_total") {
+		name += "_total"
+	}
+	if !instrumentNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid event counter name %q: must start with a letter and contain only letters, digits, '_', '.', '-' or '/' (max 255 characters)", name)
+	}
+
+	if counter, ok := c.eventInstruments.Load(name); ok {
+		if counter, ok := counter.(metric.Float64Counter); ok {
+			return counter, nil
+		}
+		return nil, fmt.Errorf("event metric %q is already a histogram", name)
+	}
+
+	counter, err := c.Meter.Float64Counter(name, metric.WithDescription("Total of "+strings.TrimSuffix(name, "_total")+" events"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event counter %q: %w", name, err)
+	}
+	actual, _ := c.eventInstruments.LoadOrStore(name, counter)
+	if counter, ok := actual.(metric.Float64Counter); ok {
+		return counter, nil
+	}
+	return nil, fmt.Errorf("event metric %q is already a histogram", name)
+}
+
+// eventHistogram returns the cached histogram of Record
+func (c *TelemetryClient) eventHistogram(name string) (metric.Float64Histogram, error) {
+	if !instrumentNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid event histogram name %q: must start with a letter and contain only letters, digits, '_', '.', '-' or '/' (max 255 characters)", name)
+	}
+
+	if histogram, ok := c.eventInstruments.Load(name); ok {
+		if histogram, ok := histogram.(metric.Float64Histogram); ok {
+			return histogram, nil
+		}
+		return nil, fmt.Errorf("event metric %q is already a counter", name)
+	}
+
+	histogram, err := c.Meter.Float64Histogram(name, metric.WithDescription("Distribution of "+name+" event values"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event histogram %q: %w", name, err)
+	}
+	actual, _ := c.eventInstruments.LoadOrStore(name, histogram)
+	if histogram, ok := actual.(metric.Float64Histogram); ok {
+		return histogram, nil
+	}
+	return nil, fmt.Errorf("event metric %q is already a counter", name)
+}
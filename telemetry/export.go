@@ -0,0 +1,201 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// exportSignals are the values of the signal attribute of otel_export_failures_total
+var exportSignals = []string{"traces", "metrics", "logs", "unknown"}
+
+// exportErrorHandler counts the errors reported by the SDK through otel.Handle and logs them,
+// to stderr like the OTel default handler or, with Config.RouteOTelErrorsToLogger, through
+// the client logger
+type exportErrorHandler struct {
+	failures      [4]atomic.Int64 // indexed like exportSignals
+	logger        atomic.Pointer[slog.Logger]
+	routeToLogger atomic.Bool
+}
+
+// exportErrors is process-wide, like the otel error handler it is installed as
+var exportErrors = &exportErrorHandler{}
+
+// ExportErrorHandler returns the handler installed by SetupWithConfig. Custom handlers set
+// with otel.SetErrorHandler replace it, so call its Handle from them to keep
+// otel_export_failures_total and the error logs.
+func ExportErrorHandler() otel.ErrorHandler {
+	return exportErrors
+}
+
+func (h *exportErrorHandler) Handle(err error) {
+	if err == nil {
+		return
+	}
+	signal := "unknown"
+	var failure *exportFailure
+	if errors.As(err, &failure) {
+		signal = failure.signal
+	}
+	for i, name := range exportSignals {
+		if name == signal {
+			h.failures[i].Add(1)
+		}
+	}
+
+	if !h.routeToLogger.Load() {
+		log.Print(err)
+		return
+	}
+	logger := h.logger.Load()
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.Warn("OpenTelemetry SDK error", "component", "otel-sdk", "signal", signal, "error", err)
+}
+
+func (h *exportErrorHandler) setLogger(logger *slog.Logger) {
+	h.logger.Store(logger)
+}
+
+// exportFailure is an error from an export path built by this package, tagged with its signal.
+// Other SDK errors carry no type, so they are counted as unknown rather than guessed from
+// their message.
+type exportFailure struct {
+	signal string
+	err    error
+}
+
+func (e *exportFailure) Error() string { return e.err.Error() }
+func (e *exportFailure) Unwrap() error { return e.err }
+
+// tagExportFailure wraps a non-nil err as a failure of signal
+func tagExportFailure(signal string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exportFailure{signal: signal, err: err}
+}
+
+// tracesExporter tags the errors of a span exporter as traces failures, which the processors
+// report through otel.Handle
+type tracesExporter struct {
+	sdktrace.SpanExporter
+}
+
+func (e tracesExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	return tagExportFailure("traces", e.SpanExporter.ExportSpans(ctx, spans))
+}
+
+// registerExportFailures exposes the failure counts as otel_export_failures_total
+func (h *exportErrorHandler) registerExportFailures(meter metric.Meter) error {
+	_, err := meter.Int64ObservableCounter(
+		"otel_export_failures_total",
+		metric.WithDescription("Total number of errors reported by the OpenTelemetry SDK, by signal"),
+		metric.WithUnit("1"),
+		metric.WithInt64Callback(func(_ context.Context, observer metric.Int64Observer) error {
+			for i, signal := range exportSignals {
+				observer.Observe(h.failures[i].Load(), metric.WithAttributes(attribute.String("signal", signal)))
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create export failures counter: %w", err)
+	}
+	return nil
+}
+
+// exportLimiter is a semaphore bounding the exports running at the same time, shared by the
+// exporters of every signal. A nil limiter does not limit.
+type exportLimiter chan struct{}
+
+func newExportLimiter(limit int) exportLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	return make(exportLimiter, limit)
+}
+
+// acquire waits for a slot, giving up when ctx is done
+func (l exportLimiter) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case l <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l exportLimiter) release() {
+	if l != nil {
+		<-l
+	}
+}
+
+// NewLimitedSpanExporter bounds how many ExportSpans calls of exporter run at the same time,
+// like Config.MaxConcurrentExports does for the exporters of the YAML file, for the exporters
+// of processors built in code (Config.SpanProcessors). A call waiting for a slot gives up when
+// its context is done. limit <= 0 returns exporter unchanged.
+func NewLimitedSpanExporter(exporter sdktrace.SpanExporter, limit int) sdktrace.SpanExporter {
+	if limit <= 0 {
+		return exporter
+	}
+	return limitedSpanExporter{SpanExporter: exporter, limit: newExportLimiter(limit)}
+}
+
+// limitedSpanExporter exports under limit and tags its errors as traces failures
+type limitedSpanExporter struct {
+	sdktrace.SpanExporter
+	limit exportLimiter
+}
+
+func (e limitedSpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if err := e.limit.acquire(ctx); err != nil {
+		return tagExportFailure("traces", err)
+	}
+	defer e.limit.release()
+	return tracesExporter{e.SpanExporter}.ExportSpans(ctx, spans)
+}
+
+// limitedLogExporter exports under limit and tags its errors as logs failures
+type limitedLogExporter struct {
+	sdklog.Exporter
+	limit exportLimiter
+}
+
+func (e limitedLogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	if err := e.limit.acquire(ctx); err != nil {
+		return tagExportFailure("logs", err)
+	}
+	defer e.limit.release()
+	return tagExportFailure("logs", e.Exporter.Export(ctx, records))
+}
+
+// limitedMetricExporter exports under limit and tags its errors as metrics failures
+type limitedMetricExporter struct {
+	sdkmetric.Exporter
+	limit exportLimiter
+}
+
+func (e limitedMetricExporter) Export(ctx context.Context, metrics *metricdata.ResourceMetrics) error {
+	if err := e.limit.acquire(ctx); err != nil {
+		return tagExportFailure("metrics", err)
+	}
+	defer e.limit.release()
+	return tagExportFailure("metrics", e.Exporter.Export(ctx, metrics))
+}
@@ -0,0 +1,288 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// blockingExporter holds every export until release is closed, tracking the peak concurrency
+type blockingExporter struct {
+	*tracetest.InMemoryExporter
+	release  chan struct{}
+	inFlight atomic.Int32
+	peak     atomic.Int32
+}
+
+func (e *blockingExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	n := e.inFlight.Add(1)
+	defer e.inFlight.Add(-1)
+	for {
+		peak := e.peak.Load()
+		if n <= peak || e.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	<-e.release
+	return e.InMemoryExporter.ExportSpans(ctx, spans)
+}
+
+func TestNewLimitedSpanExporter(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    int
+		exports  int
+		wantPeak int32
+	}{
+		{name: "bounded", limit: 2, exports: 8, wantPeak: 2},
+		{name: "limit of one", limit: 1, exports: 4, wantPeak: 1},
+		{name: "unbounded", limit: 0, exports: 4, wantPeak: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &blockingExporter{InMemoryExporter: tracetest.NewInMemoryExporter(), release: make(chan struct{})}
+			exporter := NewLimitedSpanExporter(inner, tt.limit)
+			if tt.limit <= 0 && exporter != sdktrace.SpanExporter(inner) {
+				t.Fatal("NewLimitedSpanExporter wrapped the exporter without a limit")
+			}
+
+			_, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "op")
+			span.End()
+			spans := []sdktrace.ReadOnlySpan{span.(sdktrace.ReadOnlySpan)}
+
+			var wg sync.WaitGroup
+			for range tt.exports {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if err := exporter.ExportSpans(context.Background(), spans); err != nil {
+						t.Error(err)
+					}
+				}()
+			}
+			// Let every goroutine reach the exporter or the limiter
+			deadline := time.Now().Add(time.Second)
+			for inner.inFlight.Load() < tt.wantPeak && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			time.Sleep(20 * time.Millisecond)
+			close(inner.release)
+			wg.Wait()
+
+			if got := inner.peak.Load(); got != tt.wantPeak {
+				t.Errorf("peak concurrent exports = %d, want %d", got, tt.wantPeak)
+			}
+			if got := len(inner.GetSpans()); got != tt.exports {
+				t.Errorf("exported %d spans, want %d", got, tt.exports)
+			}
+		})
+	}
+}
+
+func TestLimitedSpanExporterContextDone(t *testing.T) {
+	inner := &blockingExporter{InMemoryExporter: tracetest.NewInMemoryExporter(), release: make(chan struct{})}
+	exporter := NewLimitedSpanExporter(inner, 1)
+
+	held := make(chan error, 1)
+	go func() { held <- exporter.ExportSpans(context.Background(), nil) }()
+	for inner.inFlight.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := exporter.ExportSpans(ctx, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ExportSpans waiting for a slot = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(inner.release)
+	if err := <-held; err != nil {
+		t.Errorf("held ExportSpans = %v", err)
+	}
+}
+
+// failingHandler fails every record
+type failingHandler struct {
+	slog.Handler
+}
+
+func (failingHandler) Handle(context.Context, slog.Record) error {
+	return errors.New("disk full")
+}
+
+// failingExporter fails every export
+type failingExporter struct {
+	*tracetest.InMemoryExporter
+}
+
+func (failingExporter) ExportSpans(context.Context, []sdktrace.ReadOnlySpan) error {
+	return errors.New("rpc error: code = Unavailable desc = connection refused")
+}
+
+func TestExportFailureSignal(t *testing.T) {
+	tests := []struct {
+		name   string
+		report func(t *testing.T)
+		want   string
+	}{
+		{
+			name:   "untyped error naming a signal",
+			report: func(t *testing.T) { exportErrors.Handle(errors.New("failed to upload traces: span export")) },
+			want:   "unknown",
+		},
+		{
+			name:   "untyped error",
+			report: func(t *testing.T) { exportErrors.Handle(errors.New("context deadline exceeded")) },
+			want:   "unknown",
+		},
+		{
+			name: "limited span exporter",
+			report: func(t *testing.T) {
+				exporter := NewLimitedSpanExporter(failingExporter{tracetest.NewInMemoryExporter()}, 1)
+				exportErrors.Handle(exporter.ExportSpans(context.Background(), nil))
+			},
+			want: "traces",
+		},
+		{
+			name: "dropped spans processor",
+			report: func(t *testing.T) {
+				processor := NewDroppedSpansProcessor(failingExporter{tracetest.NewInMemoryExporter()}, 0)
+				provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(processor))
+				_, span := provider.Tracer("test").Start(context.Background(), "op")
+				span.End()
+				defer func() { _ = provider.Shutdown(context.Background()) }()
+				// The batch processor returns the export error from ForceFlush, and reports it
+				// through otel.Handle when it exports on its own
+				exportErrors.Handle(provider.ForceFlush(context.Background()))
+			},
+			want: "traces",
+		},
+		{
+			name: "yaml metric reader",
+			report: func(t *testing.T) {
+				collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusBadRequest)
+				}))
+				defer collector.Close()
+				client, err := NewClient(context.Background(), Config{
+					ConfigPath: writeConfig(t, "file_format: \"0.3\"\nmeter_provider:\n  readers:\n"+
+						"    - periodic:\n        exporter:\n          otlp:\n            protocol: http/protobuf\n"+
+						"            endpoint: "+collector.URL+"/v1/metrics\n"),
+					LogHandlers: []slog.Handler{slog.NewJSONHandler(io.Discard, nil)},
+				})
+				if err != nil {
+					t.Fatalf("NewClient: %v", err)
+				}
+				// The periodic reader exports on shutdown and returns the export error
+				exportErrors.Handle(client.Shutdown(context.Background()))
+			},
+			want: "metrics",
+		},
+		{
+			name: "audit handler",
+			report: func(t *testing.T) {
+				tt := newTestTelemetry(t, Config{AuditHandlers: []slog.Handler{failingHandler{}}})
+				tt.client.Audit(context.Background(), "user.deleted", nil)
+			},
+			want: "logs",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The handler prints the errors with the standard logger
+			output := log.Writer()
+			log.SetOutput(io.Discard)
+			defer log.SetOutput(output)
+			before := exportFailureCounts()
+			tt.report(t)
+			after := exportFailureCounts()
+
+			for signal, count := range after {
+				want := before[signal]
+				if signal == tt.want {
+					want++
+				}
+				if count != want {
+					t.Errorf("failures{signal=%q} went from %d to %d, want %d", signal, before[signal], count, want)
+				}
+			}
+		})
+	}
+}
+
+func exportFailureCounts() map[string]int64 {
+	counts := map[string]int64{}
+	for i, signal := range exportSignals {
+		counts[signal] = exportErrors.failures[i].Load()
+	}
+	return counts
+}
+
+func TestMaxConcurrentExports(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			current := peak.Load()
+			if n <= current || peak.CompareAndSwap(current, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	// Each simple processor exports one record at a time, so only the shared limit keeps the
+	// three processors below from exporting concurrently
+	exporter := func(path string) string {
+		return "exporter:\n          otlp:\n            protocol: http/protobuf\n            endpoint: " + collector.URL + path + "\n"
+	}
+	client, err := NewClient(context.Background(), Config{
+		ConfigPath: writeConfig(t, "file_format: \"0.3\"\n"+
+			"tracer_provider:\n  processors:\n"+
+			"    - simple:\n        "+exporter("/v1/traces")+
+			"    - simple:\n        "+exporter("/v1/traces")+
+			"logger_provider:\n  processors:\n"+
+			"    - simple:\n        "+exporter("/v1/logs")),
+		LogHandlers:          []slog.Handler{slog.NewJSONHandler(io.Discard, nil)},
+		MaxConcurrentExports: 1,
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, span := client.StartSpan(context.Background(), "op")
+			span.End()
+		}()
+		go func() {
+			defer wg.Done()
+			client.Logger.Info("exported")
+		}()
+	}
+	wg.Wait()
+	if err := client.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	if got := peak.Load(); got != 1 {
+		t.Errorf("peak concurrent exports = %d, want 1", got)
+	}
+}
@@ -0,0 +1,34 @@
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RecordFlagEvaluation records a feature flag evaluation following the OTel feature flag
+// semantic conventions: a feature_flag event with feature_flag.key and feature_flag.variant on
+// the active span, and one increment of feature_flag_evaluations_total with the same
+// attributes. Use low-cardinality variant names ("on", "off", "treatment-b"), never user IDs
+// or computed values; past 100 distinct keys, or variants of one key, the metric records
+// "other".
+func (c *TelemetryClient) RecordFlagEvaluation(ctx context.Context, flagKey, variant string) {
+	ctx = contextOrBackground(ctx)
+
+	trace.SpanFromContext(ctx).AddEvent("feature_flag", trace.WithAttributes(
+		attribute.String("feature_flag.key", flagKey),
+		attribute.String("feature_flag.variant", variant),
+	))
+
+	counter, err := c.NewCounter("feature_flag_evaluations", "Total number of feature flag evaluations")
+	if err != nil {
+		c.Logger.ErrorContext(ctx, "failed to create feature flag counter", "error", err)
+		return
+	}
+	counter.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("feature_flag.key", c.labelGuard.value("feature_flag.key", flagKey)),
+		attribute.String("feature_flag.variant", c.labelGuard.value("feature_flag.variant/"+flagKey, variant)),
+	))
+}
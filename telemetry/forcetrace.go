@@ -0,0 +1,210 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+
+	otelconf "go.opentelemetry.io/contrib/otelconf/v0.3.0"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+)
+
+type forceSampleKey struct{}
+
+// forceTraceGuard decides whether a request may force its trace to be sampled
+type forceTraceGuard struct {
+	header   string
+	secret   string
+	networks []netip.Prefix
+}
+
+func newForceTraceGuard(config Config) (*forceTraceGuard, error) {
+	if config.ForceTraceHeader == "" {
+		return nil, nil
+	}
+	guard := &forceTraceGuard{header: config.ForceTraceHeader, secret: config.ForceTraceSecret}
+	for _, network := range config.ForceTraceAllowedNetworks {
+		prefix, err := netip.ParsePrefix(network)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ForceTraceAllowedNetworks entry %q: %w", network, err)
+		}
+		guard.networks = append(guard.networks, prefix.Masked())
+	}
+	return guard, nil
+}
+
+// allowed reports whether the request carries a valid force header from an allowed network
+func (g *forceTraceGuard) allowed(r *http.Request) bool {
+	if g == nil {
+		return false
+	}
+	value := r.Header.Get(g.header)
+	if value == "" {
+		return false
+	}
+	if g.secret != "" {
+		if subtle.ConstantTimeCompare([]byte(value), []byte(g.secret)) != 1 {
+			return false
+		}
+	} else if value != "1" && value != "true" {
+		return false
+	}
+	if len(g.networks) == 0 {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, network := range g.networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// withForcedSampling marks the context so spans started from it are sampled
+func withForcedSampling(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceSampleKey{}, true)
+}
+
+// WithPriority marks ctx as a priority request (e.g. a payment): the spans started from it,
+// and from the contexts derived from it, are sampled whatever the configured ratios, even
+// under an unsampled parent, like a request forced with ForceTraceHeader. It needs
+// Config.PrioritySampling (or ForceTraceHeader) to move sampling out of the SDK; otherwise
+// the mark is ignored. Spans started before, such as the server span of the middleware,
+// keep their decision, so mark the context as early as possible.
+func (c *TelemetryClient) WithPriority(ctx context.Context) context.Context {
+	return withForcedSampling(contextOrBackground(ctx))
+}
+
+// HasPriority reports whether ctx was marked by WithPriority or by a forced request
+func HasPriority(ctx context.Context) bool {
+	return ctx != nil && isSamplingForced(ctx)
+}
+
+// NewPrioritySampler returns a sampler that samples the spans started from a context marked
+// by WithPriority and defers to base otherwise, for tracer providers built in code
+func NewPrioritySampler(base sdktrace.Sampler) sdktrace.Sampler {
+	return forceSampler{base: base}
+}
+
+func isSamplingForced(ctx context.Context) bool {
+	forced, _ := ctx.Value(forceSampleKey{}).(bool)
+	return forced
+}
+
+// forceSampler samples every span started from a context marked by withForcedSampling and
+// defers to the base sampler otherwise
+type forceSampler struct {
+	base sdktrace.Sampler
+}
+
+func (s forceSampler) ShouldSample(parameters sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	if isSamplingForced(parameters.ParentContext) {
+		return sdktrace.SamplingResult{
+			Decision:   sdktrace.RecordAndSample,
+			Tracestate: trace.SpanContextFromContext(parameters.ParentContext).TraceState(),
+		}
+	}
+	return s.base.ShouldSample(parameters)
+}
+
+func (s forceSampler) Description() string {
+	return fmt.Sprintf("ForceSampler{%s}", s.base.Description())
+}
+
+// samplingTracerProvider applies a sampler to every span before the SDK sees them. It takes
+// over from the YAML sampler, which is set to always_on, because samplers cannot override the
+// provider's decision to drop a span.
+type samplingTracerProvider struct {
+	embedded.TracerProvider
+	provider trace.TracerProvider
+	sampler  sdktrace.Sampler
+}
+
+func (p *samplingTracerProvider) Tracer(name string, opts ...trace.TracerOption) trace.Tracer {
+	return &samplingTracer{tracer: p.provider.Tracer(name, opts...), sampler: p.sampler}
+}
+
+type samplingTracer struct {
+	embedded.Tracer
+	tracer  trace.Tracer
+	sampler sdktrace.Sampler
+}
+
+func (t *samplingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return startSampled(ctx, t.tracer, t.sampler, name, opts)
+}
+
+// takeOverSampler replaces the YAML sampler with always_on and returns it as an SDK sampler,
+// built like otelconf does, for samplingTracerProvider to apply. option names the Config field
+// that needs it, for the error.
+func takeOverSampler(conf *otelconf.OpenTelemetryConfiguration, option string) (sdktrace.Sampler, error) {
+	sampler, err := samplerFromConfig(conf.TracerProvider.Sampler)
+	if err != nil {
+		return nil, fmt.Errorf("%s does not support the configured sampler: %w", option, err)
+	}
+	conf.TracerProvider.Sampler = &otelconf.Sampler{AlwaysOn: otelconf.SamplerAlwaysOn{}}
+	return sampler, nil
+}
+
+func samplerFromConfig(sampler *otelconf.Sampler) (sdktrace.Sampler, error) {
+	switch {
+	case sampler == nil:
+		return sdktrace.ParentBased(sdktrace.AlwaysSample()), nil
+	case sampler.ParentBased != nil:
+		root := sdktrace.AlwaysSample()
+		if sampler.ParentBased.Root != nil {
+			var err error
+			if root, err = samplerFromConfig(sampler.ParentBased.Root); err != nil {
+				return nil, err
+			}
+		}
+		var opts []sdktrace.ParentBasedSamplerOption
+		for _, child := range []struct {
+			sampler *otelconf.Sampler
+			option  func(sdktrace.Sampler) sdktrace.ParentBasedSamplerOption
+		}{
+			{sampler.ParentBased.RemoteParentSampled, sdktrace.WithRemoteParentSampled},
+			{sampler.ParentBased.RemoteParentNotSampled, sdktrace.WithRemoteParentNotSampled},
+			{sampler.ParentBased.LocalParentSampled, sdktrace.WithLocalParentSampled},
+			{sampler.ParentBased.LocalParentNotSampled, sdktrace.WithLocalParentNotSampled},
+		} {
+			if child.sampler == nil {
+				continue
+			}
+			childSampler, err := samplerFromConfig(child.sampler)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, child.option(childSampler))
+		}
+		return sdktrace.ParentBased(root, opts...), nil
+	case sampler.AlwaysOff != nil:
+		return sdktrace.NeverSample(), nil
+	case sampler.AlwaysOn != nil:
+		return sdktrace.AlwaysSample(), nil
+	case sampler.TraceIDRatioBased != nil:
+		ratio := 1.0
+		if sampler.TraceIDRatioBased.Ratio != nil {
+			ratio = *sampler.TraceIDRatioBased.Ratio
+		}
+		return sdktrace.TraceIDRatioBased(ratio), nil
+	default:
+		return nil, errors.New("unsupported sampler type")
+	}
+}
@@ -0,0 +1,117 @@
+package telemetry
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestPrioritySamplerDecision(t *testing.T) {
+	remoteParent := func(flags trace.TraceFlags) context.Context {
+		return trace.ContextWithRemoteSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{1},
+			SpanID:     trace.SpanID{1},
+			TraceFlags: flags,
+			Remote:     true,
+		}))
+	}
+	client := &TelemetryClient{}
+
+	tests := []struct {
+		name     string
+		base     sdktrace.Sampler
+		ctx      context.Context
+		priority bool
+		want     sdktrace.SamplingDecision
+	}{
+		{name: "base samples", base: sdktrace.AlwaysSample(), ctx: context.Background(), want: sdktrace.RecordAndSample},
+		{name: "base drops", base: sdktrace.NeverSample(), ctx: context.Background(), want: sdktrace.Drop},
+		{name: "priority over base drop", base: sdktrace.NeverSample(), ctx: context.Background(), priority: true, want: sdktrace.RecordAndSample},
+		{name: "priority over zero ratio", base: sdktrace.ParentBased(sdktrace.TraceIDRatioBased(0)), ctx: context.Background(), priority: true, want: sdktrace.RecordAndSample},
+		{name: "unsampled parent", base: sdktrace.ParentBased(sdktrace.AlwaysSample()), ctx: remoteParent(0), want: sdktrace.Drop},
+		{name: "priority over unsampled parent", base: sdktrace.ParentBased(sdktrace.AlwaysSample()), ctx: remoteParent(0), priority: true, want: sdktrace.RecordAndSample},
+		{name: "sampled parent", base: sdktrace.ParentBased(sdktrace.NeverSample()), ctx: remoteParent(trace.FlagsSampled), want: sdktrace.RecordAndSample},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if tt.priority {
+				ctx = client.WithPriority(ctx)
+			}
+			if HasPriority(ctx) != tt.priority {
+				t.Errorf("HasPriority = %v, want %v", HasPriority(ctx), tt.priority)
+			}
+			result := NewPrioritySampler(tt.base).ShouldSample(sdktrace.SamplingParameters{
+				ParentContext: ctx,
+				TraceID:       trace.TraceID{2},
+				Name:          "op",
+			})
+			if result.Decision != tt.want {
+				t.Errorf("decision = %v, want %v", result.Decision, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrioritySamplingClient(t *testing.T) {
+	tests := []struct {
+		name     string
+		priority bool
+		want     int
+	}{
+		{name: "always_off drops", want: 0},
+		{name: "priority samples the tree", priority: true, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, "file_format: \"0.3\"\ntracer_provider:\n  sampler:\n    always_off: {}\n")
+			recorder := tracetest.NewSpanRecorder()
+			client, err := NewClient(context.Background(), Config{
+				ConfigPath:       path,
+				PrioritySampling: true,
+				SpanProcessors:   []sdktrace.SpanProcessor{recorder},
+			})
+			if err != nil {
+				t.Fatalf("NewClient: %v", err)
+			}
+			t.Cleanup(func() { _ = client.Shutdown(context.Background()) })
+
+			ctx := context.Background()
+			if tt.priority {
+				ctx = client.WithPriority(ctx)
+			}
+			ctx, parent := client.StartSpan(ctx, "payment")
+			_, child := client.StartSpan(ctx, "charge")
+			child.End()
+			parent.End()
+
+			if got := len(recorder.Ended()); got != tt.want {
+				t.Errorf("sampled spans = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTakeOverSamplerError(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   string
+	}{
+		{name: "ForceTraceHeader", config: Config{ForceTraceHeader: "X-Force-Trace"}, want: "ForceTraceHeader does not support"},
+		{name: "PrioritySampling", config: Config{PrioritySampling: true}, want: "PrioritySampling does not support"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.ConfigPath = writeConfig(t, "file_format: \"0.3\"\ntracer_provider:\n  sampler:\n    jaeger_remote: {}\n")
+			_, err := NewClient(context.Background(), tt.config)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewClient error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
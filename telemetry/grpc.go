@@ -0,0 +1,355 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// metadataCarrier adapts gRPC metadata to a propagation.TextMapCarrier
+type metadataCarrier metadata.MD
+
+func (mc metadataCarrier) Get(key string) string {
+	values := metadata.MD(mc).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (mc metadataCarrier) Set(key, value string) {
+	metadata.MD(mc).Set(key, value)
+}
+
+func (mc metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(mc))
+	for key := range mc {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// injectOutgoingMetadata propagates the span context through outgoing gRPC metadata
+func injectOutgoingMetadata(ctx context.Context) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	otel.GetTextMapPropagator().Inject(ctx, metadataCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// rpcClientMetrics holds the instruments recorded by the gRPC client interceptors
+type rpcClientMetrics struct {
+	requestsTotal   metric.Int64Counter
+	requestDuration metric.Float64Histogram
+}
+
+func newRPCClientMetrics(meter metric.Meter) (*rpcClientMetrics, error) {
+	requestsTotal, err := meter.Int64Counter(
+		"rpc_client_requests_total",
+		metric.WithDescription("Total number of outbound gRPC calls"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rpc requests counter: %w", err)
+	}
+
+	requestDuration, err := meter.Float64Histogram(
+		"rpc_client_duration_seconds",
+		metric.WithDescription("Duration of outbound gRPC calls in seconds"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rpc duration histogram: %w", err)
+	}
+
+	return &rpcClientMetrics{requestsTotal: requestsTotal, requestDuration: requestDuration}, nil
+}
+
+func (c *TelemetryClient) rpcClientMetrics() *rpcClientMetrics {
+	metrics, err := newRPCClientMetrics(c.Meter)
+	if err != nil {
+		c.Logger.Error("failed to create gRPC client metrics, falling back to no-op", "error", err)
+		metrics, _ = newRPCClientMetrics(noop.NewMeterProvider().Meter(""))
+	}
+	return metrics
+}
+
+// startRPCSpan starts a client span for the gRPC method and injects it into the outgoing metadata
+func (c *TelemetryClient) startRPCSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	ctx, span := c.Tracer.Start(ctx, method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.method", method),
+		),
+	)
+	return injectOutgoingMetadata(ctx), span
+}
+
+// finishRPC maps the gRPC status to the span status and records client metrics
+func (m *rpcClientMetrics) finishRPC(ctx context.Context, span trace.Span, method string, err error, duration time.Duration) {
+	code := status.Code(err)
+	span.SetAttributes(attribute.String("rpc.grpc.status_code", code.String()))
+	if code != codes.OK {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, status.Convert(err).Message())
+	}
+
+	attrs := metric.WithAttributes(
+		attribute.String("method", method),
+		attribute.String("status_code", code.String()),
+	)
+	m.requestsTotal.Add(ctx, 1, attrs)
+	m.requestDuration.Record(ctx, duration.Seconds(), attrs)
+}
+
+// UnaryClientInterceptor traces outbound unary gRPC calls and records client metrics
+func (c *TelemetryClient) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	metrics := c.rpcClientMetrics()
+
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		startTime := time.Now()
+		ctx, span := c.startRPCSpan(ctx, method)
+		defer span.End()
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		metrics.finishRPC(ctx, span, method, err, time.Since(startTime))
+		return err
+	}
+}
+
+// StreamClientInterceptor traces outbound streaming gRPC calls. The span ends when the
+// stream fails, when the server closes it (RecvMsg returns io.EOF), after the single response
+// of a client-streaming call (CloseAndRecv) or when the call context is done, so an abandoned
+// stream whose context is canceled is still recorded.
+func (c *TelemetryClient) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	metrics := c.rpcClientMetrics()
+
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		startTime := time.Now()
+		ctx, span := c.startRPCSpan(ctx, method)
+
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			metrics.finishRPC(ctx, span, method, err, time.Since(startTime))
+			span.End()
+			return nil, err
+		}
+
+		traced := &tracedClientStream{
+			ClientStream:  stream,
+			serverStreams: desc.ServerStreams,
+			done:          make(chan struct{}),
+			finish: func(err error) {
+				metrics.finishRPC(ctx, span, method, err, time.Since(startTime))
+				span.End()
+			},
+		}
+		go func() {
+			select {
+			case <-ctx.Done():
+				traced.end(status.FromContextError(ctx.Err()).Err())
+			case <-traced.done:
+			}
+		}()
+		return traced, nil
+	}
+}
+
+// tracedClientStream ends the call span once the stream completes
+type tracedClientStream struct {
+	grpc.ClientStream
+	serverStreams bool
+	finish        func(error)
+	once          sync.Once
+	done          chan struct{} // closed by end, stops the context watcher
+}
+
+// end finishes the call once, with err nil on success
+func (s *tracedClientStream) end(err error) {
+	s.once.Do(func() {
+		s.finish(err)
+		close(s.done)
+	})
+}
+
+func (s *tracedClientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == nil && !s.serverStreams:
+		// The only response of a unary-response stream
+		s.end(nil)
+	case errors.Is(err, io.EOF):
+		s.end(nil)
+	case err != nil:
+		s.end(err)
+	}
+	return err
+}
+
+func (s *tracedClientStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	if err != nil && !errors.Is(err, io.EOF) {
+		s.end(err)
+	}
+	return err
+}
+
+// ServerInterceptorOption configures the gRPC server interceptors
+type ServerInterceptorOption func(*serverInterceptorConfig)
+
+type serverInterceptorConfig struct {
+	metadataKeys []string
+}
+
+// WithLoggedMetadata stashes the given incoming metadata keys in the context for the
+// correlated logger (see WithRPCAttrs). Only list low-cardinality keys (e.g. "x-client-name",
+// "x-tenant-tier"); never credentials such as "authorization".
+func WithLoggedMetadata(keys ...string) ServerInterceptorOption {
+	return func(cfg *serverInterceptorConfig) {
+		for _, key := range keys {
+			cfg.metadataKeys = append(cfg.metadataKeys, strings.ToLower(key))
+		}
+	}
+}
+
+// rpcInfo is the server call information read by the correlated logger
+type rpcInfo struct {
+	method   string
+	peer     string
+	metadata []slog.Attr
+}
+
+type rpcInfoKey struct{}
+
+// withRPCInfo stashes the method, the peer host (without the ephemeral port) and the allowed
+// incoming metadata in the context
+func withRPCInfo(ctx context.Context, method string, metadataKeys []string) context.Context {
+	info := &rpcInfo{method: method}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		info.peer = p.Addr.String()
+		if host, _, err := net.SplitHostPort(info.peer); err == nil {
+			info.peer = host
+		}
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, key := range metadataKeys {
+			if values := md.Get(key); len(values) > 0 {
+				info.metadata = append(info.metadata, slog.String(key, values[0]))
+			}
+		}
+	}
+	return context.WithValue(ctx, rpcInfoKey{}, info)
+}
+
+// rpcLogAttrs returns the stashed call information as log attributes
+func rpcLogAttrs(ctx context.Context) []slog.Attr {
+	info, ok := ctx.Value(rpcInfoKey{}).(*rpcInfo)
+	if !ok {
+		return nil
+	}
+	attrs := []slog.Attr{slog.String("rpc_method", info.method)}
+	if info.peer != "" {
+		attrs = append(attrs, slog.String("rpc_peer", info.peer))
+	}
+	if len(info.metadata) > 0 {
+		attrs = append(attrs, slog.Attr{Key: "rpc_metadata", Value: slog.GroupValue(info.metadata...)})
+	}
+	return attrs
+}
+
+// startServerRPCSpan extracts the caller trace context from the incoming metadata and starts
+// the server span
+func (c *TelemetryClient) startServerRPCSpan(ctx context.Context, method string, cfg *serverInterceptorConfig) (context.Context, trace.Span) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+	}
+	ctx, span := c.Tracer.Start(ctx, method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.method", method),
+		),
+	)
+	return withRPCInfo(ctx, method, cfg.metadataKeys), span
+}
+
+// finishServerRPC maps the returned gRPC status to the span status
+func finishServerRPC(span trace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttributes(attribute.String("rpc.grpc.status_code", code.String()))
+	if code != codes.OK {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, status.Convert(err).Message())
+	}
+}
+
+func newServerInterceptorConfig(opts []ServerInterceptorOption) *serverInterceptorConfig {
+	cfg := &serverInterceptorConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// UnaryServerInterceptor traces inbound unary gRPC calls and stashes the method, peer and
+// allowed metadata in the context, so logs written with it carry them (see WithRPCAttrs)
+func (c *TelemetryClient) UnaryServerInterceptor(opts ...ServerInterceptorOption) grpc.UnaryServerInterceptor {
+	cfg := newServerInterceptorConfig(opts)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, span := c.startServerRPCSpan(ctx, info.FullMethod, cfg)
+		defer span.End()
+
+		resp, err := handler(ctx, req)
+		finishServerRPC(span, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streaming calls; the handler reads the
+// enriched context from stream.Context()
+func (c *TelemetryClient) StreamServerInterceptor(opts ...ServerInterceptorOption) grpc.StreamServerInterceptor {
+	cfg := newServerInterceptorConfig(opts)
+
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := c.startServerRPCSpan(stream.Context(), info.FullMethod, cfg)
+		defer span.End()
+
+		err := handler(srv, &serverStream{ServerStream: stream, ctx: ctx})
+		finishServerRPC(span, err)
+		return err
+	}
+}
+
+// serverStream overrides the stream context with the enriched one
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
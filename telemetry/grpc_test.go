@@ -0,0 +1,145 @@
+package telemetry
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	testpb "google.golang.org/grpc/interop/grpc_testing"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// streamingServer answers the client-streaming call once the client closes its side and
+// keeps the server-streaming call open until the client goes away
+type streamingServer struct {
+	testpb.UnimplementedTestServiceServer
+}
+
+func (streamingServer) StreamingInputCall(stream grpc.ClientStreamingServer[testpb.StreamingInputCallRequest, testpb.StreamingInputCallResponse]) error {
+	var size int32
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&testpb.StreamingInputCallResponse{AggregatedPayloadSize: size})
+		}
+		if err != nil {
+			return err
+		}
+		size += int32(len(req.GetPayload().GetBody()))
+	}
+}
+
+func (streamingServer) StreamingOutputCall(_ *testpb.StreamingOutputCallRequest, stream grpc.ServerStreamingServer[testpb.StreamingOutputCallResponse]) error {
+	if err := stream.Send(&testpb.StreamingOutputCallResponse{}); err != nil {
+		return err
+	}
+	<-stream.Context().Done()
+	return stream.Context().Err()
+}
+
+// dialBufconn serves streamingServer over an in-memory listener and returns a client
+// connection using the stream interceptor of tt
+func dialBufconn(t *testing.T, tt *testTelemetry) testpb.TestServiceClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	testpb.RegisterTestServiceServer(server, streamingServer{})
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStreamInterceptor(tt.client.StreamClientInterceptor()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return testpb.NewTestServiceClient(conn)
+}
+
+func TestStreamClientInterceptorFinishes(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		call     func(ctx context.Context, client testpb.TestServiceClient, cancel context.CancelFunc) error
+		wantCode codes.Code
+	}{
+		{
+			name:   "client stream CloseAndRecv",
+			method: testpb.TestService_StreamingInputCall_FullMethodName,
+			call: func(ctx context.Context, client testpb.TestServiceClient, _ context.CancelFunc) error {
+				stream, err := client.StreamingInputCall(ctx)
+				if err != nil {
+					return err
+				}
+				for range 3 {
+					if err := stream.Send(&testpb.StreamingInputCallRequest{Payload: &testpb.Payload{Body: []byte("ab")}}); err != nil {
+						return err
+					}
+				}
+				_, err = stream.CloseAndRecv()
+				return err
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name:   "abandoned server stream",
+			method: testpb.TestService_StreamingOutputCall_FullMethodName,
+			call: func(ctx context.Context, client testpb.TestServiceClient, cancel context.CancelFunc) error {
+				stream, err := client.StreamingOutputCall(ctx, &testpb.StreamingOutputCallRequest{})
+				if err != nil {
+					return err
+				}
+				if _, err := stream.Recv(); err != nil {
+					return err
+				}
+				// The caller stops reading and cancels, without a final Recv
+				cancel()
+				return nil
+			},
+			wantCode: codes.Canceled,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTestTelemetry(t, Config{})
+			client := dialBufconn(t, tt)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if err := tc.call(ctx, client, cancel); err != nil {
+				t.Fatalf("call: %v", err)
+			}
+
+			deadline := time.Now().Add(2 * time.Second)
+			for len(tt.spans.Ended()) == 0 && time.Now().Before(deadline) {
+				time.Sleep(5 * time.Millisecond)
+			}
+			spans := tt.spans.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("got %d ended spans, want 1", len(spans))
+			}
+			if code, _ := spanAttr(spans[0], "rpc.grpc.status_code"); code.AsString() != tc.wantCode.String() {
+				t.Errorf("rpc.grpc.status_code = %q, want %q", code.AsString(), tc.wantCode)
+			}
+			attrs := []attribute.KeyValue{
+				attribute.String("method", tc.method),
+				attribute.String("status_code", tc.wantCode.String()),
+			}
+			if got := tt.sum(t, "rpc_client_requests_total", attrs...); got != 1 {
+				t.Errorf("rpc_client_requests_total = %v, want 1", got)
+			}
+			if got := tt.count(t, "rpc_client_duration_seconds", attrs...); got != 1 {
+				t.Errorf("rpc_client_duration_seconds count = %v, want 1", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package telemetry
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+)
+
+// KeyCase is a naming convention for attribute keys, see Config.AttributeKeyCase
+type KeyCase string
+
+const (
+	// KeyCasePassthrough keeps the keys as written (the default)
+	KeyCasePassthrough KeyCase = ""
+	// KeyCaseSnake lowercases the keys and separates every word with '_':
+	// "User-ID" -> "user_id", "http.method" -> "http_method", "paramA" -> "param_a"
+	KeyCaseSnake KeyCase = "snake_case"
+	// KeyCaseDot follows the OTel semantic conventions: dots separate namespaces and the words
+	// within them are snake_case: "User-ID" -> "user_id", "http.statusCode" -> "http.status_code"
+	KeyCaseDot KeyCase = "dot.notation"
+)
+
+// keyCacheLimit bounds the normalized keys kept in memory; keys beyond it are normalized on
+// every use
+const keyCacheLimit = 4096
+
+// keyNormalizer rewrites attribute keys to a KeyCase, caching the results since the same keys
+// come back on every call. A nil normalizer keeps the keys.
+type keyNormalizer struct {
+	keepDots bool
+	cache    sync.Map // original key -> normalized key
+	size     atomic.Int64
+}
+
+func newKeyNormalizer(keyCase KeyCase) *keyNormalizer {
+	switch keyCase {
+	case KeyCaseSnake:
+		return &keyNormalizer{}
+	case KeyCaseDot:
+		return &keyNormalizer{keepDots: true}
+	default:
+		return nil
+	}
+}
+
+func (n *keyNormalizer) key(key string) string {
+	if n == nil {
+		return key
+	}
+	if normalized, ok := n.cache.Load(key); ok {
+		return normalized.(string)
+	}
+	normalized := normalizeKey(key, n.keepDots)
+	if n.size.Load() < keyCacheLimit {
+		if _, loaded := n.cache.LoadOrStore(key, normalized); !loaded {
+			n.size.Add(1)
+		}
+	}
+	return normalized
+}
+
+// normalizeKey lowercases key and joins its words with '_', keeping '.' when keepDots is set.
+// Words are split on non-alphanumeric runes and on case changes ("paramA", "HTTPStatus").
+func normalizeKey(key string, keepDots bool) string {
+	runes := []rune(key)
+	var b strings.Builder
+	b.Grow(len(key) + 4)
+	pendingSep := false
+	lastSep := true // at the start, or right after a '.'
+	for i, r := range runes {
+		switch {
+		case r == '.' && keepDots:
+			b.WriteRune('.')
+			pendingSep, lastSep = false, true
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if unicode.IsUpper(r) && i > 0 {
+				prev := runes[i-1]
+				nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+				if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && nextLower {
+					pendingSep = true
+				}
+			}
+			if pendingSep && !lastSep {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			pendingSep, lastSep = false, false
+		default:
+			pendingSep = true
+		}
+	}
+	return b.String()
+}
+
+func (n *keyNormalizer) attributes(attrs []attribute.KeyValue) []attribute.KeyValue {
+	if n == nil || len(attrs) == 0 {
+		return attrs
+	}
+	normalized := make([]attribute.KeyValue, len(attrs))
+	for i, attr := range attrs {
+		normalized[i] = attribute.KeyValue{Key: attribute.Key(n.key(string(attr.Key))), Value: attr.Value}
+	}
+	return normalized
+}
+
+// transform returns t followed by the key normalization, for the metric helpers
+func (n *keyNormalizer) transform(t AttributeTransform) AttributeTransform {
+	if n == nil {
+		return t
+	}
+	return func(key string, value any) (string, any, bool) {
+		if t != nil {
+			var ok bool
+			if key, value, ok = t(key, value); !ok {
+				return key, value, false
+			}
+		}
+		return n.key(key), value, true
+	}
+}
+
+// WithKeyCase rewrites the keys of every record attribute and group to keyCase, before
+// WithDroppedAttrs (which matches either form) and the span events. The trace correlation keys
+// (trace_id, span_id) are already snake_case, so log-trace links keep working.
+func WithKeyCase(keyCase KeyCase) HandlerOption {
+	return func(opts *handlerOptions) {
+		opts.keys = newKeyNormalizer(keyCase)
+	}
+}
+
+// keyCaseTracerProvider normalizes the attribute keys of every span before the SDK records them
+type keyCaseTracerProvider struct {
+	embedded.TracerProvider
+	provider trace.TracerProvider
+	keys     *keyNormalizer
+}
+
+func (p *keyCaseTracerProvider) Tracer(name string, opts ...trace.TracerOption) trace.Tracer {
+	return &keyCaseTracer{tracer: p.provider.Tracer(name, opts...), keys: p.keys}
+}
+
+type keyCaseTracer struct {
+	embedded.Tracer
+	tracer trace.Tracer
+	keys   *keyNormalizer
+}
+
+func (t *keyCaseTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+	startOpts := []trace.SpanStartOption{
+		trace.WithAttributes(t.keys.attributes(cfg.Attributes())...),
+		trace.WithLinks(t.keys.links(cfg.Links())...),
+		trace.WithSpanKind(cfg.SpanKind()),
+	}
+	if !cfg.Timestamp().IsZero() {
+		startOpts = append(startOpts, trace.WithTimestamp(cfg.Timestamp()))
+	}
+	if cfg.NewRoot() {
+		startOpts = append(startOpts, trace.WithNewRoot())
+	}
+
+	ctx, span := t.tracer.Start(ctx, name, startOpts...)
+	span = &keyCaseSpan{Span: span, keys: t.keys}
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+func (n *keyNormalizer) links(links []trace.Link) []trace.Link {
+	for i := range links {
+		links[i].Attributes = n.attributes(links[i].Attributes)
+	}
+	return links
+}
+
+// keyCaseSpan normalizes the attributes set on a span after it started
+type keyCaseSpan struct {
+	trace.Span
+	keys *keyNormalizer
+}
+
+func (s *keyCaseSpan) SetAttributes(kv ...attribute.KeyValue) {
+	s.Span.SetAttributes(s.keys.attributes(kv)...)
+}
+
+func (s *keyCaseSpan) AddEvent(name string, opts ...trace.EventOption) {
+	s.Span.AddEvent(name, s.keys.eventOptions(opts)...)
+}
+
+func (s *keyCaseSpan) RecordError(err error, opts ...trace.EventOption) {
+	s.Span.RecordError(err, s.keys.eventOptions(opts)...)
+}
+
+func (s *keyCaseSpan) AddLink(link trace.Link) {
+	link.Attributes = s.keys.attributes(link.Attributes)
+	s.Span.AddLink(link)
+}
+
+func (n *keyNormalizer) eventOptions(opts []trace.EventOption) []trace.EventOption {
+	cfg := trace.NewEventConfig(opts...)
+	eventOpts := []trace.EventOption{
+		trace.WithAttributes(n.attributes(cfg.Attributes())...),
+		trace.WithStackTrace(cfg.StackTrace()),
+	}
+	// Only the caller's timestamp, so a clockSpan underneath can set its own
+	if timestamp, ok := eventTimestamp(opts); ok {
+		eventOpts = append(eventOpts, trace.WithTimestamp(timestamp))
+	}
+	return eventOpts
+}
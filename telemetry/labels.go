@@ -0,0 +1,145 @@
+package telemetry
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxLabelValues is the number of distinct values a label key may take on metrics
+// before new values are collapsed into overflowLabelValue
+const maxLabelValues = 100
+
+const overflowLabelValue = "other"
+
+// LabelSet holds attributes shared by spans, logs and metrics
+type LabelSet map[string]string
+
+type labelsKey struct{}
+
+// WithLabels stores the label set in the context, merged over any labels already present,
+// and sets the labels on the active span. Logs written with the returned context and
+// HTTPMetrics recorders pick them up automatically. Per-call attributes always take
+// precedence over labels with the same key.
+func (c *TelemetryClient) WithLabels(ctx context.Context, labels LabelSet) context.Context {
+	ctx = contextOrBackground(ctx)
+	span := trace.SpanFromContext(ctx)
+	for key, value := range labels {
+		span.SetAttributes(attribute.String(key, value))
+	}
+	return mergeLabels(ctx, labels)
+}
+
+// mergeLabels stores labels merged over the context ones, without touching the span
+func mergeLabels(ctx context.Context, labels LabelSet) context.Context {
+	merged := LabelSet{}
+	for key, value := range LabelsFromContext(ctx) {
+		merged[key] = value
+	}
+	for key, value := range labels {
+		merged[key] = value
+	}
+	return context.WithValue(ctx, labelsKey{}, merged)
+}
+
+// LabelsFromContext returns the label set stored in the context
+func LabelsFromContext(ctx context.Context) LabelSet {
+	if ctx == nil {
+		return nil
+	}
+	labels, _ := ctx.Value(labelsKey{}).(LabelSet)
+	return labels
+}
+
+// labelLogAttrs returns the context labels as log attributes, skipping keys already in the record
+func labelLogAttrs(ctx context.Context, record slog.Record) []slog.Attr {
+	labels := LabelsFromContext(ctx)
+	if len(labels) == 0 {
+		return nil
+	}
+
+	present := make(map[string]bool, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		present[attr.Key] = true
+		return true
+	})
+
+	attrs := make([]slog.Attr, 0, len(labels))
+	for key, value := range labels {
+		if !present[key] {
+			attrs = append(attrs, slog.String(key, value))
+		}
+	}
+	return attrs
+}
+
+// cardinalityGuard caps the number of distinct values each label key can emit on metrics
+type cardinalityGuard struct {
+	mu     sync.Mutex
+	values map[string]map[string]struct{}
+}
+
+func newCardinalityGuard() *cardinalityGuard {
+	return &cardinalityGuard{values: map[string]map[string]struct{}{}}
+}
+
+// value returns the label value, or overflowLabelValue once the key exceeded maxLabelValues
+func (g *cardinalityGuard) value(key, value string) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	seen, ok := g.values[key]
+	if !ok {
+		seen = map[string]struct{}{}
+		g.values[key] = seen
+	}
+	if _, ok := seen[value]; ok {
+		return value
+	}
+	if len(seen) >= maxLabelValues {
+		return overflowLabelValue
+	}
+	seen[value] = struct{}{}
+	return value
+}
+
+// metricAttributes appends the guarded context labels to attrs, without overriding them
+func (g *cardinalityGuard) metricAttributes(ctx context.Context, attrs ...attribute.KeyValue) []attribute.KeyValue {
+	labels := LabelsFromContext(ctx)
+	if g == nil || len(labels) == 0 {
+		return attrs
+	}
+
+	present := make(map[attribute.Key]bool, len(attrs))
+	for _, attr := range attrs {
+		present[attr.Key] = true
+	}
+	for key, value := range labels {
+		if !present[attribute.Key(key)] {
+			attrs = append(attrs, attribute.String(key, g.value(key, value)))
+		}
+	}
+	return attrs
+}
+
+// dynamicAttributes appends the guarded dynamic attributes to attrs, without overriding them
+func (g *cardinalityGuard) dynamicAttributes(dynamic *dynamicAttrs, attrs []attribute.KeyValue) []attribute.KeyValue {
+	current := dynamic.load()
+	if g == nil || len(current) == 0 {
+		return attrs
+	}
+
+	present := make(map[attribute.Key]bool, len(attrs))
+	for _, attr := range attrs {
+		present[attr.Key] = true
+	}
+	for key, value := range current {
+		if !present[attribute.Key(key)] {
+			attrs = append(attrs, attribute.String(key, g.value(key, value)))
+		}
+	}
+	return attrs
+}
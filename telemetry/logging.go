@@ -2,57 +2,564 @@ package telemetry
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"maps"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
 
+// DefaultMaxAttrLength is the string attribute length used by WithAttrTruncation when none is given
+const DefaultMaxAttrLength = 1024
+
+const truncatedSuffix = "…[truncated]"
+
 type CorrelatedHandler struct {
 	handler slog.Handler
+	opts    *handlerOptions
+}
+
+// HandlerOption configures a CorrelatedHandler
+type HandlerOption func(*handlerOptions)
+
+type handlerOptions struct {
+	maxAttrLength int
+	dropKeys      map[string]bool
+	dynamic       *dynamicAttrs
+	rpcAttrs      bool
+	contextAttrs  *contextAttrsRegistry
+
+	severityTextKey   string
+	severityNumberKey string // empty unless WithSeverityAttrs
+
+	spanEvents *attributeLimits // nil unless WithSpanEvents
+
+	keys *keyNormalizer // nil unless WithKeyCase
+
+	alwaysTraceSampled bool
+
+	clock func() time.Time // nil unless withClock
+}
+
+// ContextAttrsFunc derives log attributes from the context a record is logged with (e.g. a
+// role stored by an auth middleware). It runs for every record, so keep it cheap and return
+// nil when the context holds nothing relevant.
+type ContextAttrsFunc func(ctx context.Context) []slog.Attr
+
+// contextAttrsRegistry holds the ContextAttrsFunc of a handler and the handlers derived from
+// it. Like dynamicAttrs, readers load an immutable snapshot so Handle never locks.
+type contextAttrsRegistry struct {
+	mu  sync.Mutex // serializes writers
+	fns atomic.Pointer[[]ContextAttrsFunc]
+}
+
+func (r *contextAttrsRegistry) add(fns ...ContextAttrsFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var next []ContextAttrsFunc
+	if current := r.fns.Load(); current != nil {
+		next = append(next, *current...)
+	}
+	next = append(next, fns...)
+	r.fns.Store(&next)
+}
+
+func (r *contextAttrsRegistry) load() []ContextAttrsFunc {
+	if fns := r.fns.Load(); fns != nil {
+		return *fns
+	}
+	return nil
+}
+
+// contextLogAttrs calls fns in order and returns their attributes, skipping keys already in
+// the record or returned by an earlier function
+func contextLogAttrs(ctx context.Context, fns []ContextAttrsFunc, record slog.Record) []slog.Attr {
+	var attrs []slog.Attr
+	for _, fn := range fns {
+		attrs = append(attrs, fn(ctx)...)
+	}
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	present := make(map[string]bool, record.NumAttrs()+len(attrs))
+	record.Attrs(func(attr slog.Attr) bool {
+		present[attr.Key] = true
+		return true
+	})
+	kept := attrs[:0]
+	for _, attr := range attrs {
+		if !present[attr.Key] {
+			present[attr.Key] = true
+			kept = append(kept, attr)
+		}
+	}
+	return kept
 }
 
-func NewCorrelatedLogger(handler slog.Handler) *slog.Logger {
-	return slog.New(&CorrelatedHandler{handler: handler})
+// WithAttrTruncation truncates string attribute values longer than maxLength bytes.
+// A maxLength <= 0 uses DefaultMaxAttrLength.
+func WithAttrTruncation(maxLength int) HandlerOption {
+	return func(opts *handlerOptions) {
+		if maxLength <= 0 {
+			maxLength = DefaultMaxAttrLength
+		}
+		opts.maxAttrLength = maxLength
+	}
+}
+
+// WithDroppedAttrs removes attributes with the given keys from every record
+func WithDroppedAttrs(keys ...string) HandlerOption {
+	return func(opts *handlerOptions) {
+		if opts.dropKeys == nil {
+			opts.dropKeys = map[string]bool{}
+		}
+		for _, key := range keys {
+			opts.dropKeys[key] = true
+		}
+	}
+}
+
+// withDynamicAttrs adds the client's dynamic attributes (SetDynamicAttr) to every record
+func withDynamicAttrs(dynamic *dynamicAttrs) HandlerOption {
+	return func(opts *handlerOptions) {
+		opts.dynamic = dynamic
+	}
+}
+
+// WithRPCAttrs adds the gRPC method, peer host and allowed metadata stashed by the server
+// interceptors (rpc_method, rpc_peer, rpc_metadata) to records logged with the call context
+func WithRPCAttrs() HandlerOption {
+	return func(opts *handlerOptions) {
+		opts.rpcAttrs = true
+	}
+}
+
+// WithAlwaysTraceSampled adds trace_sampled to every record logged with a valid span context,
+// false for unsampled traces, instead of only when true. Records of unsampled (non-recording)
+// spans then also carry trace_id and span_id, so log pipelines can route them apart.
+func WithAlwaysTraceSampled() HandlerOption {
+	return func(opts *handlerOptions) {
+		opts.alwaysTraceSampled = true
+	}
+}
+
+// DefaultSeverityNumberKey is the key of the severity number added by WithSeverityAttrs
+const DefaultSeverityNumberKey = "severity_number"
+
+// WithSeverityAttrs adds the record level as attributes for ingesters that do not read slog's
+// "level": the OTel severity number (see SeverityFromLevel, e.g. 17 for error) under numberKey
+// (DefaultSeverityNumberKey when empty) and, when textKey is not empty, the level text (e.g.
+// "ERROR") under textKey. Without it records only carry slog's textual level.
+func WithSeverityAttrs(textKey, numberKey string) HandlerOption {
+	return func(opts *handlerOptions) {
+		if numberKey == "" {
+			numberKey = DefaultSeverityNumberKey
+		}
+		opts.severityTextKey, opts.severityNumberKey = textKey, numberKey
+	}
+}
+
+// WithSpanEvents also adds every record logged with a recording span in its context to that
+// span as an event named after the message, with the level and the record attributes (groups
+// flattened into dotted keys). The attributes of each event are capped like the span attribute
+// helpers: countLimit attributes (<= 0 uses DefaultSpanAttributeCountLimit) and string values
+// of valueLengthLimit bytes (0 disables). Events count towards the span size and the SDK event
+// limit (128 by default, older events are dropped), so enable it only where logs are sparse.
+func WithSpanEvents(countLimit, valueLengthLimit int) HandlerOption {
+	return func(opts *handlerOptions) {
+		limits := newAttributeLimits(countLimit, valueLengthLimit)
+		opts.spanEvents = &limits
+	}
+}
+
+// spanEventAttrs converts the record level and attributes to span event attributes, skipping
+// the trace correlation keys the span already carries
+func spanEventAttrs(record slog.Record, limits attributeLimits) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, record.NumAttrs()+1)
+	attrs = append(attrs, attribute.String("level", record.Level.String()))
+	record.Attrs(func(attr slog.Attr) bool {
+		if !correlationKeys[attr.Key] {
+			attrs = appendSpanEventAttr(attrs, "", attr)
+		}
+		return true
+	})
+	return limits.apply(attrs)
+}
+
+// appendSpanEventAttr converts a slog attribute under the key prefix, flattening groups like
+// appendLogAttr
+func appendSpanEventAttr(attrs []attribute.KeyValue, prefix string, attr slog.Attr) []attribute.KeyValue {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return attrs
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, member := range attr.Value.Group() {
+			attrs = appendSpanEventAttr(attrs, prefix, member)
+		}
+		return attrs
+	}
+	key := prefix + attr.Key
+	switch attr.Value.Kind() {
+	case slog.KindDuration:
+		return append(attrs, attribute.String(key, attr.Value.Duration().String()))
+	case slog.KindTime:
+		return append(attrs, attribute.String(key, attr.Value.Time().Format(time.RFC3339Nano)))
+	case slog.KindUint64:
+		return append(attrs, attribute.String(key, attr.Value.String()))
+	default:
+		return append(attrs, attributeFromValue(key, attr.Value.Any()))
+	}
+}
+
+// replaceTimeAttr returns a slog ReplaceAttr renaming the record time to key and formatting it
+// with layout, or nil when both are empty
+func replaceTimeAttr(key, layout string) func(groups []string, attr slog.Attr) slog.Attr {
+	if key == "" && layout == "" {
+		return nil
+	}
+	return func(groups []string, attr slog.Attr) slog.Attr {
+		if len(groups) > 0 || attr.Key != slog.TimeKey || attr.Value.Kind() != slog.KindTime {
+			return attr
+		}
+		if key != "" {
+			attr.Key = key
+		}
+		if layout != "" {
+			attr.Value = slog.StringValue(attr.Value.Time().Format(layout))
+		}
+		return attr
+	}
+}
+
+// WithContextAttrs registers functions adding attributes derived from the record context, see
+// CorrelatedHandler.RegisterContextAttrs
+func WithContextAttrs(fns ...ContextAttrsFunc) HandlerOption {
+	return func(opts *handlerOptions) {
+		opts.contextAttrs.add(fns...)
+	}
+}
+
+// NewCorrelatedHandler wraps a handler with trace correlation
+func NewCorrelatedHandler(handler slog.Handler, opts ...HandlerOption) *CorrelatedHandler {
+	options := &handlerOptions{contextAttrs: &contextAttrsRegistry{}}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return &CorrelatedHandler{handler: handler, opts: options}
+}
+
+func NewCorrelatedLogger(handler slog.Handler, opts ...HandlerOption) *slog.Logger {
+	return slog.New(NewCorrelatedHandler(handler, opts...))
 }
 
 // Handle processes log records and injects trace correlation data
 func (h *CorrelatedHandler) Handle(ctx context.Context, record slog.Record) error {
-	// Extract trace information from context
-	span := trace.SpanFromContext(ctx)
-	if span.IsRecording() {
-		spanContext := span.SpanContext()
-		if spanContext.IsValid() {
-			// Add trace and span IDs to the log record
-			record.AddAttrs(
-				slog.String("trace_id", spanContext.TraceID().String()),
-				slog.String("span_id", spanContext.SpanID().String()),
-			)
-
-			// Add trace flags if present
-			if spanContext.TraceFlags().IsSampled() {
-				record.AddAttrs(slog.Bool("trace_sampled", true))
+	if h.opts.clock != nil {
+		record.Time = h.opts.clock()
+	}
+	if ctx != nil {
+		// Fast path: most records carry no span (slog's non-Context methods pass
+		// context.Background, background jobs derive their own contexts), so look the span
+		// context up once and skip the trace attributes without it
+		if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+			// A remote span context (RestoreTrace in a worker) is not recording but still names the trace
+			if spanContext.IsRemote() || h.opts.alwaysTraceSampled || trace.SpanFromContext(ctx).IsRecording() {
+				// Add trace and span IDs (and trace flags if present) in a single call
+				attrs := [3]slog.Attr{
+					slog.String("trace_id", spanContext.TraceID().String()),
+					slog.String("span_id", spanContext.SpanID().String()),
+				}
+				n := 2
+				if sampled := spanContext.TraceFlags().IsSampled(); sampled || h.opts.alwaysTraceSampled {
+					attrs[n] = slog.Bool("trace_sampled", sampled)
+					n++
+				}
+				record.AddAttrs(attrs[:n]...)
+			}
+		}
+
+		if requestID, ok := RequestIDFromContext(ctx); ok {
+			record.AddAttrs(slog.String("request_id", requestID))
+		}
+
+		if h.opts.rpcAttrs {
+			record.AddAttrs(rpcLogAttrs(ctx)...)
+		}
+
+		// Add labels stored with WithLabels, per-call attributes win
+		record.AddAttrs(labelLogAttrs(ctx, record)...)
+
+		if fns := h.opts.contextAttrs.load(); len(fns) > 0 {
+			record.AddAttrs(contextLogAttrs(ctx, fns, record)...)
+		}
+	}
+	record.AddAttrs(dynamicLogAttrs(h.opts.dynamic, record)...)
+
+	if h.opts.severityNumberKey != "" {
+		if h.opts.severityTextKey != "" {
+			record.AddAttrs(slog.String(h.opts.severityTextKey, record.Level.String()))
+		}
+		record.AddAttrs(slog.Int(h.opts.severityNumberKey, int(SeverityFromLevel(record.Level))))
+	}
+
+	if h.opts.limitsAttrs() {
+		limited := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+		record.Attrs(func(attr slog.Attr) bool {
+			if attr, ok := h.opts.limitAttr(attr); ok {
+				limited.AddAttrs(attr)
 			}
+			return true
+		})
+		record = limited
+	}
+
+	if h.opts.spanEvents != nil && ctx != nil {
+		if span := trace.SpanFromContext(ctx); span.IsRecording() {
+			span.AddEvent(record.Message, trace.WithTimestamp(record.Time), trace.WithAttributes(spanEventAttrs(record, *h.opts.spanEvents)...))
 		}
 	}
 
 	return h.handler.Handle(ctx, record)
 }
 
+func (o *handlerOptions) limitsAttrs() bool {
+	return o.maxAttrLength > 0 || len(o.dropKeys) > 0 || o.keys != nil
+}
+
+// truncateString cuts value to at most limit bytes on a rune boundary, so the output stays
+// valid UTF-8
+func truncateString(value string, limit int) string {
+	if len(value) <= limit {
+		return value
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return value[:cut]
+}
+
+// limitAttr truncates long string values, normalizes the key casing and reports false for
+// dropped keys, matched before or after the normalization
+func (o *handlerOptions) limitAttr(attr slog.Attr) (slog.Attr, bool) {
+	if o.dropKeys[attr.Key] {
+		return attr, false
+	}
+	if o.keys != nil {
+		if attr.Key = o.keys.key(attr.Key); o.dropKeys[attr.Key] {
+			return attr, false
+		}
+	}
+
+	attr.Value = attr.Value.Resolve()
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		if value := attr.Value.String(); o.maxAttrLength > 0 && len(value) > o.maxAttrLength {
+			attr.Value = slog.StringValue(truncateString(value, o.maxAttrLength) + truncatedSuffix)
+		}
+	case slog.KindGroup:
+		group := attr.Value.Group()
+		limited := make([]slog.Attr, 0, len(group))
+		for _, member := range group {
+			if member, ok := o.limitAttr(member); ok {
+				limited = append(limited, member)
+			}
+		}
+		attr.Value = slog.GroupValue(limited...)
+	}
+	return attr, true
+}
+
+// RegisterContextAttrs adds fn to the functions called for every record logged with a
+// non-empty context, on this handler and on those derived from it with WithAttrs/WithGroup.
+// They run in registration order after the trace, request, RPC and label attributes and
+// before the dynamic attributes and the truncation/drop limits; keys already present in the
+// record win. It is safe to call while logging.
+func (h *CorrelatedHandler) RegisterContextAttrs(fn ContextAttrsFunc) {
+	h.opts.contextAttrs.add(fn)
+}
+
 func (h *CorrelatedHandler) Enabled(ctx context.Context, level slog.Level) bool {
 	return h.handler.Enabled(ctx, level)
 }
 
 func (h *CorrelatedHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return &CorrelatedHandler{handler: h.handler.WithAttrs(attrs)}
+	if h.opts.limitsAttrs() {
+		limited := make([]slog.Attr, 0, len(attrs))
+		for _, attr := range attrs {
+			if attr, ok := h.opts.limitAttr(attr); ok {
+				limited = append(limited, attr)
+			}
+		}
+		attrs = limited
+	}
+	return &CorrelatedHandler{handler: h.handler.WithAttrs(attrs), opts: h.opts}
 }
 
 func (h *CorrelatedHandler) WithGroup(name string) slog.Handler {
-	return &CorrelatedHandler{handler: h.handler.WithGroup(name)}
+	return &CorrelatedHandler{handler: h.handler.WithGroup(h.opts.keys.key(name)), opts: h.opts}
+}
+
+// MultiHandler fans log records out to several handlers, each filtering by its own level
+type MultiHandler struct {
+	handlers []slog.Handler
+}
+
+// NewMultiHandler creates a handler that writes every record to all enabled handlers
+func NewMultiHandler(handlers ...slog.Handler) *MultiHandler {
+	return &MultiHandler{handlers: handlers}
+}
+
+func (h *MultiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *MultiHandler) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, record.Level) {
+			errs = append(errs, handler.Handle(ctx, record.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *MultiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return &MultiHandler{handlers: handlers}
+}
+
+func (h *MultiHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return &MultiHandler{handlers: handlers}
+}
+
+// log writes a record attributed to the caller of the exported helper, so Config.LogSource
+// reports the application call site. slog.Logger methods would capture the helper itself.
+// The handler chain (CorrelatedHandler, MultiHandler) does not affect the source: it comes
+// from the record PC, captured here.
+func (c *TelemetryClient) log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	if !c.Logger.Enabled(ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:]) // skip runtime.Callers, log and the exported helper
+	record := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	record.Add(args...)
+	_ = c.Logger.Handler().Handle(ctx, record)
+}
+
+// RegisterLogAttrs registers fn on the client logger, see CorrelatedHandler.RegisterContextAttrs.
+// It does nothing when logs are disabled.
+func (c *TelemetryClient) RegisterLogAttrs(fn ContextAttrsFunc) {
+	if handler, ok := c.Logger.Handler().(*CorrelatedHandler); ok {
+		handler.RegisterContextAttrs(fn)
+	}
+}
+
+// LogError records the error on the active span and logs it with trace correlation. With
+// Config.ErrorLogCounter it also increments that counter.
+func (c *TelemetryClient) LogError(ctx context.Context, err error, msg string, args ...any) {
+	ctx = contextOrBackground(ctx)
+
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, msg)
+
+	allArgs := []any{"error", err}
+	if causes := errorCauses(err); len(causes) > 0 {
+		span.SetAttributes(attribute.StringSlice("error.causes", causes))
+		allArgs = append(allArgs, "error_causes", causes)
+	}
+	if c.errorOrigin {
+		// Caller 1 is the code calling LogError
+		if _, file, line, ok := runtime.Caller(1); ok {
+			origin := fmt.Sprintf("%s:%d", file, line)
+			span.SetAttributes(attribute.String("error.origin", origin))
+			allArgs = append(allArgs, "error_origin", origin)
+		}
+	}
+
+	c.log(ctx, slog.LevelError, msg, append(allArgs, args...)...)
+
+	if c.errorLogCounter != nil {
+		// Measured with the span context so the SDK can attach a trace exemplar
+		c.errorLogCounter.Add(ctx, 1)
+	}
+}
+
+// maxErrorCauses caps how many wrapped causes LogError records
+const maxErrorCauses = 10
+
+// errorCauses walks the wrapped errors (fmt.Errorf %w and errors.Join) breadth-first and
+// returns their messages, excluding err itself
+func errorCauses(err error) []string {
+	var causes []string
+	queue := unwrapAll(err)
+	for len(queue) > 0 && len(causes) < maxErrorCauses {
+		cause := queue[0]
+		queue = append(queue[1:], unwrapAll(cause)...)
+		causes = append(causes, cause.Error())
+	}
+	return causes
+}
+
+func unwrapAll(err error) []error {
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		if cause := e.Unwrap(); cause != nil {
+			return []error{cause}
+		}
+	case interface{ Unwrap() []error }:
+		return e.Unwrap()
+	}
+	return nil
+}
+
+// LogWithSpanAttributes logs a message and sets the same attributes on the active span
+func (c *TelemetryClient) LogWithSpanAttributes(ctx context.Context, level slog.Level, msg string, attrs map[string]any) {
+	ctx = contextOrBackground(ctx)
+	attrs = c.attrTransform.applyMap(attrs)
+	spanAttrs := make([]attribute.KeyValue, 0, len(attrs))
+	args := make([]any, 0, len(attrs)*2)
+	for _, key := range slices.Sorted(maps.Keys(attrs)) {
+		spanAttrs = append(spanAttrs, attributeFromValue(key, attrs[key]))
+		args = append(args, key, attrs[key])
+	}
+	trace.SpanFromContext(ctx).SetAttributes(c.attrLimits.apply(spanAttrs)...)
+
+	c.log(ctx, level, msg, args...)
 }
 
 // LogHTTPRequest logs HTTP request details with trace correlation
 func (c *TelemetryClient) LogHTTPRequest(ctx context.Context, method, path string, statusCode int, duration time.Duration, args ...any) {
+	ctx = contextOrBackground(ctx)
 	allArgs := append([]any{
 		"http_method", method,
 		"http_path", path,
@@ -60,13 +567,17 @@ func (c *TelemetryClient) LogHTTPRequest(ctx context.Context, method, path strin
 		"duration_ms", duration.Milliseconds(),
 	}, args...)
 
-	level := slog.LevelInfo
-	if statusCode >= 400 {
-		level = slog.LevelWarn
-	}
-	if statusCode >= 500 {
-		level = slog.LevelError
-	}
+	c.log(ctx, c.httpStatusLevel(statusCode), "HTTP request completed", allArgs...)
+}
 
-	c.Logger.Log(ctx, level, "HTTP request completed", allArgs...)
+// DefaultHTTPStatusLevel logs 5xx as error, 4xx as warn and everything else as info
+func DefaultHTTPStatusLevel(statusCode int) slog.Level {
+	switch {
+	case statusCode >= 500:
+		return slog.LevelError
+	case statusCode >= 400:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
 }
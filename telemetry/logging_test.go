@@ -0,0 +1,319 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestLogHTTPRequestStatusLevel(t *testing.T) {
+	custom := func(statusCode int) slog.Level {
+		switch {
+		case statusCode == 404:
+			return slog.LevelInfo
+		case statusCode == 499:
+			return slog.LevelWarn
+		default:
+			return DefaultHTTPStatusLevel(statusCode)
+		}
+	}
+	tests := []struct {
+		name       string
+		mapping    func(int) slog.Level
+		statusCode int
+		want       string
+	}{
+		{name: "default 200", statusCode: 200, want: "INFO"},
+		{name: "default 404", statusCode: 404, want: "WARN"},
+		{name: "default 503", statusCode: 503, want: "ERROR"},
+		{name: "custom 404", mapping: custom, statusCode: 404, want: "INFO"},
+		{name: "custom 499", mapping: custom, statusCode: 499, want: "WARN"},
+		{name: "custom falls back", mapping: custom, statusCode: 500, want: "ERROR"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTestTelemetry(t, Config{HTTPStatusLevel: tc.mapping})
+			tt.client.LogHTTPRequest(context.Background(), "GET", "/users", tc.statusCode, time.Millisecond)
+
+			lines := tt.logs.lines(t)
+			if len(lines) != 1 {
+				t.Fatalf("got %d log lines, want 1", len(lines))
+			}
+			if got := lines[0]["level"]; got != tc.want {
+				t.Errorf("level = %v, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMultiHandlerFanOut(t *testing.T) {
+	info, debug := &syncBuffer{}, &syncBuffer{}
+	logger := NewCorrelatedLogger(NewMultiHandler(
+		slog.NewJSONHandler(info, &slog.HandlerOptions{Level: slog.LevelInfo}),
+		slog.NewJSONHandler(debug, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	)).With("service", "orders").WithGroup("req")
+
+	logger.Debug("cache miss", "key", "k1")
+	logger.Info("order created", "id", 7)
+
+	tests := []struct {
+		name     string
+		buf      *syncBuffer
+		wantMsgs []string
+	}{
+		{name: "info handler", buf: info, wantMsgs: []string{"order created"}},
+		{name: "debug handler", buf: debug, wantMsgs: []string{"cache miss", "order created"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			lines := tc.buf.lines(t)
+			if len(lines) != len(tc.wantMsgs) {
+				t.Fatalf("got %d lines, want %d", len(lines), len(tc.wantMsgs))
+			}
+			for i, line := range lines {
+				if line["msg"] != tc.wantMsgs[i] {
+					t.Errorf("line %d msg = %v, want %q", i, line["msg"], tc.wantMsgs[i])
+				}
+				// WithAttrs and WithGroup reach every child
+				if line["service"] != "orders" {
+					t.Errorf("line %d service = %v, want orders", i, line["service"])
+				}
+				if group, ok := line["req"].(map[string]any); !ok || len(group) == 0 {
+					t.Errorf("line %d has no req group: %v", i, line)
+				}
+			}
+		})
+	}
+}
+
+func TestLogSourcePointsToCaller(t *testing.T) {
+	tests := []struct {
+		name string
+		// log logs "here" and returns the line it did so from
+		log func(c *TelemetryClient) int
+	}{
+		{name: "Logger", log: func(c *TelemetryClient) int {
+			_, _, line, _ := runtime.Caller(0)
+			c.Logger.InfoContext(context.Background(), "here")
+			return line + 1
+		}},
+		{name: "LogError", log: func(c *TelemetryClient) int {
+			_, _, line, _ := runtime.Caller(0)
+			c.LogError(context.Background(), errors.New("boom"), "here")
+			return line + 1
+		}},
+		{name: "LogWithSpanAttributes", log: func(c *TelemetryClient) int {
+			_, _, line, _ := runtime.Caller(0)
+			c.LogWithSpanAttributes(context.Background(), slog.LevelInfo, "here", nil)
+			return line + 1
+		}},
+	}
+	_, file, _, _ := runtime.Caller(0)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stop := captureStdout(t)
+			tt := newTestTelemetry(t, Config{LogSource: true, LogHandlers: []slog.Handler{}})
+			wantLine := tc.log(tt.client)
+
+			for _, line := range stop().lines(t) {
+				if line["msg"] != "here" {
+					continue
+				}
+				source, _ := line["source"].(map[string]any)
+				if source["file"] != file || source["line"] != float64(wantLine) {
+					t.Errorf("source = %v:%v, want %s:%d", source["file"], source["line"], file, wantLine)
+				}
+				return
+			}
+			t.Fatal("record not logged")
+		})
+	}
+}
+
+func TestLogTimeKeyAndFormat(t *testing.T) {
+	const layout = "2006-01-02T15:04:05Z07:00"
+	tests := []struct {
+		name    string
+		format  string
+		key     string
+		layout  string
+		wantKey string
+	}{
+		{name: "json default", wantKey: "time"},
+		{name: "json key", key: "@timestamp", wantKey: "@timestamp"},
+		{name: "json key and format", key: "@timestamp", layout: layout, wantKey: "@timestamp"},
+		{name: "json format", layout: layout, wantKey: "time"},
+		{name: "text key and format", format: LogFormatText, key: "ts", layout: layout, wantKey: "ts"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stop := captureStdout(t)
+			config := Config{LogFormat: tc.format, LogTimeKey: tc.key, LogTimeFormat: tc.layout}
+			if tc.format == "" {
+				config.LogHandlers = []slog.Handler{}
+			}
+			tt := newTestTelemetry(t, config)
+			tt.client.Logger.Info("stamped")
+			out := stop()
+
+			var value string
+			if tc.format == LogFormatText {
+				for _, line := range strings.Split(out.buf.String(), "\n") {
+					if !strings.Contains(line, "msg=stamped") {
+						continue
+					}
+					if strings.Contains(line, "time=") && tc.wantKey != "time" {
+						t.Errorf("default time key still present: %s", line)
+					}
+					for _, field := range strings.Fields(line) {
+						if v, ok := strings.CutPrefix(field, tc.wantKey+"="); ok {
+							value = v
+						}
+					}
+				}
+			} else {
+				for _, line := range out.lines(t) {
+					if line["msg"] != "stamped" {
+						continue
+					}
+					if _, ok := line["time"]; ok && tc.wantKey != "time" {
+						t.Errorf("default time key still present: %v", line)
+					}
+					value, _ = line[tc.wantKey].(string)
+				}
+			}
+			if value == "" {
+				t.Fatalf("record has no %q time", tc.wantKey)
+			}
+
+			wantLayout := time.RFC3339Nano
+			if tc.layout != "" {
+				wantLayout = tc.layout
+			}
+			stamp, err := time.Parse(wantLayout, value)
+			if err != nil {
+				t.Fatalf("time %q does not match layout %q: %v", value, wantLayout, err)
+			}
+			if tc.layout != "" && stamp.Format(tc.layout) != value {
+				t.Errorf("time = %q, want it formatted with %q", value, tc.layout)
+			}
+			if since := time.Since(stamp); since < -time.Second || since > time.Minute {
+				t.Errorf("time = %v, want about now", stamp)
+			}
+		})
+	}
+}
+
+func TestLogsDisabledKeepStdout(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled *bool
+	}{
+		{name: "enabled"},
+		{name: "disabled", enabled: Bool(false)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stop := captureStdout(t)
+			tt := newTestTelemetry(t, Config{LogsEnabled: tc.enabled, LogHandlers: []slog.Handler{}})
+			tt.client.Logger.Info("kept")
+			out := stop()
+
+			var found bool
+			for _, line := range out.lines(t) {
+				found = found || line["msg"] == "kept"
+			}
+			if !found {
+				t.Errorf("stdout has no record, got:\n%s", out.buf.String())
+			}
+		})
+	}
+}
+
+func TestTraceSampledModes(t *testing.T) {
+	sampled := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.AlwaysSample())).Tracer("test")
+	unsampled := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.NeverSample())).Tracer("test")
+	remote := trace.ContextWithRemoteSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  trace.SpanID{1},
+		Remote:  true,
+	}))
+
+	tests := []struct {
+		name        string
+		always      bool
+		ctx         func() context.Context
+		wantSampled any // nil when trace_sampled must be absent
+		wantTraceID bool
+	}{
+		{name: "default sampled", ctx: func() context.Context { ctx, _ := sampled.Start(context.Background(), "op"); return ctx }, wantSampled: true, wantTraceID: true},
+		{name: "default unsampled", ctx: func() context.Context { ctx, _ := unsampled.Start(context.Background(), "op"); return ctx }},
+		{name: "default remote unsampled", ctx: func() context.Context { return remote }, wantTraceID: true},
+		{name: "default no span", ctx: context.Background},
+		{name: "always sampled", always: true, ctx: func() context.Context { ctx, _ := sampled.Start(context.Background(), "op"); return ctx }, wantSampled: true, wantTraceID: true},
+		{name: "always unsampled", always: true, ctx: func() context.Context { ctx, _ := unsampled.Start(context.Background(), "op"); return ctx }, wantSampled: false, wantTraceID: true},
+		{name: "always remote unsampled", always: true, ctx: func() context.Context { return remote }, wantSampled: false, wantTraceID: true},
+		{name: "always no span", always: true, ctx: context.Background},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var opts []HandlerOption
+			if tc.always {
+				opts = append(opts, WithAlwaysTraceSampled())
+			}
+			buf := &syncBuffer{}
+			NewCorrelatedLogger(slog.NewJSONHandler(buf, nil), opts...).InfoContext(tc.ctx(), "work")
+
+			line := buf.lines(t)[0]
+			got, ok := line["trace_sampled"]
+			if tc.wantSampled == nil {
+				if ok {
+					t.Errorf("trace_sampled = %v, want absent", got)
+				}
+			} else if got != tc.wantSampled {
+				t.Errorf("trace_sampled = %v (present %v), want %v", got, ok, tc.wantSampled)
+			}
+			if _, ok := line["trace_id"]; ok != tc.wantTraceID {
+				t.Errorf("trace_id present = %v, want %v", ok, tc.wantTraceID)
+			}
+		})
+	}
+}
+
+func BenchmarkHandle(b *testing.B) {
+	tracer := sdktrace.NewTracerProvider().Tracer("bench")
+	spanCtx, span := tracer.Start(context.Background(), "op")
+	defer span.End()
+	cancelCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	contexts := []struct {
+		name string
+		ctx  context.Context
+	}{
+		{name: "background", ctx: context.Background()},
+		{name: "derived without span", ctx: cancelCtx},
+		{name: "request id without span", ctx: context.WithValue(cancelCtx, requestIDKey{}, "req-1")},
+		{name: "span", ctx: spanCtx},
+	}
+	for _, bc := range contexts {
+		b.Run(bc.name, func(b *testing.B) {
+			handler := NewCorrelatedHandler(slog.NewJSONHandler(io.Discard, nil))
+			record := slog.NewRecord(time.Now(), slog.LevelInfo, "request served", 0)
+			record.AddAttrs(slog.String("route", "/orders"), slog.Int("status", 200))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				_ = handler.Handle(bc.ctx, record)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package telemetry
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// MergeAttrs merges attribute maps (e.g. request, user and derived attributes) for the
+// map-based helpers like LogWithSpanAttributes and AddSpanEvent. Later maps win on conflicting
+// keys; nil maps are skipped. The inputs are never modified.
+func MergeAttrs(maps ...map[string]any) map[string]any {
+	size := 0
+	for _, attrs := range maps {
+		size += len(attrs)
+	}
+	merged := make(map[string]any, size)
+	for _, attrs := range maps {
+		for key, value := range attrs {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// MergeKV merges attribute sets with the same semantics as MergeAttrs: the last value of a key
+// wins, and keys keep the position of their first occurrence
+func MergeKV(sets ...[]attribute.KeyValue) []attribute.KeyValue {
+	size := 0
+	for _, attrs := range sets {
+		size += len(attrs)
+	}
+	merged := make([]attribute.KeyValue, 0, size)
+	index := make(map[attribute.Key]int, size)
+	for _, attrs := range sets {
+		for _, attr := range attrs {
+			if i, ok := index[attr.Key]; ok {
+				merged[i] = attr
+				continue
+			}
+			index[attr.Key] = len(merged)
+			merged = append(merged, attr)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,105 @@
+package telemetry
+
+import (
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestMergeAttrs(t *testing.T) {
+	tests := []struct {
+		name string
+		maps []map[string]any
+		want map[string]any
+	}{
+		{name: "no maps", want: map[string]any{}},
+		{name: "nil maps", maps: []map[string]any{nil, nil}, want: map[string]any{}},
+		{
+			name: "nil among maps",
+			maps: []map[string]any{{"user.id": "42"}, nil, {"order.id": 7}},
+			want: map[string]any{"user.id": "42", "order.id": 7},
+		},
+		{
+			name: "last wins",
+			maps: []map[string]any{
+				{"tenant": "request", "route": "/orders"},
+				{"tenant": "user"},
+				{"tenant": "derived", "retry": true},
+			},
+			want: map[string]any{"tenant": "derived", "route": "/orders", "retry": true},
+		},
+		{
+			name: "conflict changes type",
+			maps: []map[string]any{{"code": 500}, {"code": "E500"}},
+			want: map[string]any{"code": "E500"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MergeAttrs(tt.maps...)
+			if got == nil {
+				t.Fatal("MergeAttrs returned nil, want an empty map")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeAttrs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeAttrsDoesNotModifyInputs(t *testing.T) {
+	first := map[string]any{"tenant": "request"}
+	merged := MergeAttrs(first, map[string]any{"tenant": "user"})
+	merged["extra"] = 1
+	if !reflect.DeepEqual(first, map[string]any{"tenant": "request"}) {
+		t.Errorf("input map modified: %v", first)
+	}
+}
+
+func TestMergeKV(t *testing.T) {
+	tests := []struct {
+		name string
+		sets [][]attribute.KeyValue
+		want []attribute.KeyValue
+	}{
+		{name: "no sets", want: []attribute.KeyValue{}},
+		{name: "nil sets", sets: [][]attribute.KeyValue{nil, nil}, want: []attribute.KeyValue{}},
+		{
+			name: "nil among sets",
+			sets: [][]attribute.KeyValue{{attribute.String("user.id", "42")}, nil, {attribute.Int("order.id", 7)}},
+			want: []attribute.KeyValue{attribute.String("user.id", "42"), attribute.Int("order.id", 7)},
+		},
+		{
+			name: "last wins in first position",
+			sets: [][]attribute.KeyValue{
+				{attribute.String("tenant", "request"), attribute.String("route", "/orders")},
+				{attribute.String("tenant", "user")},
+				{attribute.Bool("retry", true), attribute.String("tenant", "derived")},
+			},
+			want: []attribute.KeyValue{
+				attribute.String("tenant", "derived"),
+				attribute.String("route", "/orders"),
+				attribute.Bool("retry", true),
+			},
+		},
+		{
+			name: "duplicate within a set",
+			sets: [][]attribute.KeyValue{{attribute.Int("attempt", 1), attribute.Int("attempt", 2)}},
+			want: []attribute.KeyValue{attribute.Int("attempt", 2)},
+		},
+		{
+			name: "conflict changes type",
+			sets: [][]attribute.KeyValue{{attribute.Int("code", 500)}, {attribute.String("code", "E500")}},
+			want: []attribute.KeyValue{attribute.String("code", "E500")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MergeKV(tt.sets...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeKV = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,11 +3,17 @@ package telemetry
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // HTTPMetrics provides common HTTP metrics
@@ -15,11 +21,140 @@ type HTTPMetrics struct {
 	RequestsTotal   metric.Int64Counter
 	RequestDuration metric.Float64Histogram
 	ErrorsTotal     metric.Int64Counter
+	ThrottledTotal  metric.Int64Counter
+	TimeToFirstByte metric.Float64Histogram
+	SLIGoodTotal    metric.Int64Counter
+	SLIBadTotal     metric.Int64Counter
+
+	sliClassifier SLIClassifier
+	p99           *percentileEstimator
+	splitOutcome  bool
+	labelGuard    *cardinalityGuard
+	dynamic       *dynamicAttrs
+	transform     AttributeTransform
+}
+
+// HTTPMetricsOption configures NewHTTPMetrics
+type HTTPMetricsOption func(*httpMetricsConfig)
+
+type httpMetricsConfig struct {
+	durationBuckets []float64
+	sliClassifier   SLIClassifier
+	p99Window       time.Duration
+	splitOutcome    bool
+	exponential     bool
+}
+
+// exponentialHistogramMeter is the meter of the http_request_duration_seconds instruments
+// created with WithExponentialHistogram. The SDK setup installs a view giving the instrument
+// base-2 exponential aggregation in this meter only, so other instances keep explicit buckets.
+const exponentialHistogramMeter = "github.com/mmacanmunhoz/otel-helpers/telemetry/exponential"
+
+// WithDurationBuckets sets the explicit bucket boundaries, in seconds, of
+// http_request_duration_seconds. They must be positive and strictly increasing, and cannot be
+// combined with WithExponentialHistogram.
+func WithDurationBuckets(boundaries ...float64) HTTPMetricsOption {
+	return func(cfg *httpMetricsConfig) {
+		cfg.durationBuckets = boundaries
+	}
+}
+
+// WithExponentialHistogram gives http_request_duration_seconds base-2 exponential (native)
+// aggregation, instead of explicit buckets, for this HTTPMetrics only. The instrument is created
+// in its own meter, which the SDK view selects, so its otel_scope_name differs from the other
+// instances. The backend must support exponential histograms.
+func WithExponentialHistogram() HTTPMetricsOption {
+	return func(cfg *httpMetricsConfig) {
+		cfg.exponential = true
+	}
+}
+
+// WithOutcomeSplit adds an outcome attribute ("success", or "error" for the status codes of
+// DefaultErrorStatus) to http_request_duration_seconds, so success and error latencies can
+// be analyzed separately. It doubles the duration series at most; http_requests_total is
+// unchanged.
+func WithOutcomeSplit() HTTPMetricsOption {
+	return func(cfg *httpMetricsConfig) {
+		cfg.splitOutcome = true
+	}
+}
+
+// SLIClassifier decides whether a request counts as good for the availability SLI
+type SLIClassifier func(statusCode int, duration time.Duration) bool
+
+// LatencySLIClassifier counts a request as good when it did not fail on the server side
+// (status < 500) and completed within the latency budget
+func LatencySLIClassifier(budget time.Duration) SLIClassifier {
+	return func(statusCode int, duration time.Duration) bool {
+		return statusCode < 500 && duration <= budget
+	}
+}
+
+// WithSLIClassifier makes HTTPMiddleware call RecordSLI for every request, classified by classifier
+func WithSLIClassifier(classifier SLIClassifier) HTTPMetricsOption {
+	return func(cfg *httpMetricsConfig) {
+		cfg.sliClassifier = classifier
+	}
+}
+
+// validateBuckets rejects boundaries the SDK would accept but aggregate wrongly
+func validateBuckets(boundaries []float64) error {
+	for i, boundary := range boundaries {
+		if boundary <= 0 {
+			return fmt.Errorf("invalid bucket boundary %v at index %d: must be positive", boundary, i)
+		}
+		if i > 0 && boundary <= boundaries[i-1] {
+			return fmt.Errorf("invalid bucket boundary %v at index %d: must be greater than %v", boundary, i, boundaries[i-1])
+		}
+	}
+	return nil
 }
 
 // NewHTTPMetrics creates standard HTTP metrics
-func (c *TelemetryClient) NewHTTPMetrics() (*HTTPMetrics, error) {
-	requestsTotal, err := c.Meter.Int64Counter(
+func (c *TelemetryClient) NewHTTPMetrics(opts ...HTTPMetricsOption) (*HTTPMetrics, error) {
+	cfg := &httpMetricsConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if err := validateBuckets(cfg.durationBuckets); err != nil {
+		return nil, fmt.Errorf("invalid duration buckets: %w", err)
+	}
+	durationMeter := c.Meter
+	if cfg.exponential {
+		if len(cfg.durationBuckets) > 0 {
+			return nil, fmt.Errorf("WithDurationBuckets cannot be combined with WithExponentialHistogram")
+		}
+		durationMeter = c.providers.meter.Meter(exponentialHistogramMeter)
+	}
+
+	metrics, err := newHTTPMetrics(c.Meter, durationMeter, cfg)
+	if err != nil {
+		return nil, err
+	}
+	metrics.labelGuard = c.labelGuard
+	metrics.dynamic = c.dynamicAttrs
+	metrics.sliClassifier = cfg.sliClassifier
+	metrics.transform = c.attrTransform
+	metrics.splitOutcome = cfg.splitOutcome
+	if cfg.p99Window > 0 {
+		metrics.p99 = newPercentileEstimator(cfg.p99Window)
+		if err := registerP99(c.Meter, metrics.p99); err != nil {
+			return nil, err
+		}
+	}
+	return metrics, nil
+}
+
+// attributes adds the context labels and the dynamic attributes to attrs, without overriding
+// them, then applies the attribute transform
+func (m *HTTPMetrics) attributes(ctx context.Context, attrs ...attribute.KeyValue) []attribute.KeyValue {
+	return m.transform.apply(m.labelGuard.dynamicAttributes(m.dynamic, m.labelGuard.metricAttributes(ctx, attrs...)))
+}
+
+// newHTTPMetrics creates the instruments in meter, except http_request_duration_seconds which
+// is created in durationMeter
+func newHTTPMetrics(meter, durationMeter metric.Meter, cfg *httpMetricsConfig) (*HTTPMetrics, error) {
+	requestsTotal, err := meter.Int64Counter(
 		"http_requests_total",
 		metric.WithDescription("Total number of HTTP requests"),
 		metric.WithUnit("1"),
@@ -28,16 +163,19 @@ func (c *TelemetryClient) NewHTTPMetrics() (*HTTPMetrics, error) {
 		return nil, fmt.Errorf("failed to create requests counter: %w", err)
 	}
 
-	requestDuration, err := c.Meter.Float64Histogram(
-		"http_request_duration_seconds",
+	durationOpts := []metric.Float64HistogramOption{
 		metric.WithDescription("Duration of HTTP requests in seconds"),
 		metric.WithUnit("s"),
-	)
+	}
+	if len(cfg.durationBuckets) > 0 {
+		durationOpts = append(durationOpts, metric.WithExplicitBucketBoundaries(cfg.durationBuckets...))
+	}
+	requestDuration, err := durationMeter.Float64Histogram("http_request_duration_seconds", durationOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create duration histogram: %w", err)
 	}
 
-	errorsTotal, err := c.Meter.Int64Counter(
+	errorsTotal, err := meter.Int64Counter(
 		"http_errors_total",
 		metric.WithDescription("Total number of HTTP errors"),
 		metric.WithUnit("1"),
@@ -46,31 +184,234 @@ func (c *TelemetryClient) NewHTTPMetrics() (*HTTPMetrics, error) {
 		return nil, fmt.Errorf("failed to create errors counter: %w", err)
 	}
 
+	throttledTotal, err := meter.Int64Counter(
+		"http_requests_throttled_total",
+		metric.WithDescription("Total number of HTTP requests rejected by rate limiting"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create throttled counter: %w", err)
+	}
+
+	timeToFirstByte, err := meter.Float64Histogram(
+		"http_response_ttfb_seconds",
+		metric.WithDescription("Time from request start until the first response body write, in seconds"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create time to first byte histogram: %w", err)
+	}
+
+	sliGoodTotal, err := meter.Int64Counter(
+		"sli_good_total",
+		metric.WithDescription("Total number of requests meeting the SLI"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SLI good counter: %w", err)
+	}
+
+	sliBadTotal, err := meter.Int64Counter(
+		"sli_bad_total",
+		metric.WithDescription("Total number of requests failing the SLI"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SLI bad counter: %w", err)
+	}
+
 	return &HTTPMetrics{
 		RequestsTotal:   requestsTotal,
 		RequestDuration: requestDuration,
 		ErrorsTotal:     errorsTotal,
+		ThrottledTotal:  throttledTotal,
+		TimeToFirstByte: timeToFirstByte,
+		SLIGoodTotal:    sliGoodTotal,
+		SLIBadTotal:     sliBadTotal,
 	}, nil
 }
 
 // RecordRequest records an HTTP request with standard attributes
 func (m *HTTPMetrics) RecordRequest(ctx context.Context, method, endpoint, statusCode string, duration time.Duration) {
-	attrs := metric.WithAttributes(
+	ctx = contextOrBackground(ctx)
+	attrs := metric.WithAttributes(m.attributes(ctx,
 		attribute.String("method", method),
 		attribute.String("endpoint", endpoint),
 		attribute.String("status_code", statusCode),
-	)
+	)...)
 
 	m.RequestsTotal.Add(ctx, 1, attrs)
+	if m.splitOutcome {
+		attrs = metric.WithAttributes(m.attributes(ctx,
+			attribute.String("method", method),
+			attribute.String("endpoint", endpoint),
+			attribute.String("status_code", statusCode),
+			attribute.String("outcome", requestOutcome(statusCode)),
+		)...)
+	}
 	m.RequestDuration.Record(ctx, duration.Seconds(), attrs)
+	if m.p99 != nil {
+		m.p99.record(endpoint, duration)
+	}
+}
+
+// requestOutcome classifies a status code like HTTPMiddleware does for http_errors_total
+func requestOutcome(statusCode string) string {
+	if code, err := strconv.Atoi(statusCode); err == nil && DefaultErrorStatus(code) {
+		return "error"
+	}
+	return "success"
+}
+
+// RecordTimeToFirstByte records how long the handler took to start writing the response body
+func (m *HTTPMetrics) RecordTimeToFirstByte(ctx context.Context, method, endpoint string, ttfb time.Duration) {
+	ctx = contextOrBackground(ctx)
+	m.TimeToFirstByte.Record(ctx, ttfb.Seconds(), metric.WithAttributes(m.attributes(ctx,
+		attribute.String("method", method),
+		attribute.String("endpoint", endpoint),
+	)...))
 }
 
 // RecordError records an HTTP error with standard attributes
 func (m *HTTPMetrics) RecordError(ctx context.Context, errorType, endpoint string) {
-	m.ErrorsTotal.Add(ctx, 1, metric.WithAttributes(
+	ctx = contextOrBackground(ctx)
+	m.ErrorsTotal.Add(ctx, 1, metric.WithAttributes(m.attributes(ctx,
 		attribute.String("error_type", errorType),
 		attribute.String("endpoint", endpoint),
-	))
+	)...))
+}
+
+// RecordSLI counts a request in sli_good_total or sli_bad_total. Every request lands in
+// exactly one of them, so availability is good / (good + bad).
+func (m *HTTPMetrics) RecordSLI(ctx context.Context, endpoint string, good bool) {
+	ctx = contextOrBackground(ctx)
+	counter := m.SLIBadTotal
+	if good {
+		counter = m.SLIGoodTotal
+	}
+	counter.Add(ctx, 1, metric.WithAttributes(m.attributes(ctx, attribute.String("endpoint", endpoint))...))
+}
+
+// RecordThrottle records a rate-limited (429) request and marks the active span as throttled
+func (m *HTTPMetrics) RecordThrottle(ctx context.Context, method, endpoint string) {
+	ctx = contextOrBackground(ctx)
+	m.ThrottledTotal.Add(ctx, 1, metric.WithAttributes(m.attributes(ctx,
+		attribute.String("method", method),
+		attribute.String("endpoint", endpoint),
+	)...))
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("http.throttled", true))
+}
+
+// RouteMetrics records HTTP metrics with the route label fixed at construction
+type RouteMetrics struct {
+	metrics *HTTPMetrics
+	route   string
+}
+
+// RouteMetrics returns metrics bound to a route. All routes share the same underlying instruments.
+func (c *TelemetryClient) RouteMetrics(route string) *RouteMetrics {
+	return &RouteMetrics{metrics: c.sharedHTTPMetrics(), route: route}
+}
+
+// sharedHTTPMetrics returns the HTTP metrics used by the client helpers, created on first use
+func (c *TelemetryClient) sharedHTTPMetrics() *HTTPMetrics {
+	c.routeMetricsOnce.Do(func() {
+		metrics, err := c.NewHTTPMetrics()
+		if err != nil {
+			c.Logger.Error("failed to create route metrics, falling back to no-op", "error", err)
+			meter := noop.NewMeterProvider().Meter("")
+			metrics, _ = newHTTPMetrics(meter, meter, &httpMetricsConfig{})
+		}
+		c.routeMetrics = metrics
+	})
+	return c.routeMetrics
+}
+
+// RecordRequest records an HTTP request for the bound route
+func (m *RouteMetrics) RecordRequest(ctx context.Context, method, statusCode string, duration time.Duration) {
+	m.metrics.RecordRequest(ctx, method, m.route, statusCode, duration)
+}
+
+// RecordError records an HTTP error for the bound route
+func (m *RouteMetrics) RecordError(ctx context.Context, errorType string) {
+	m.metrics.RecordError(ctx, errorType, m.route)
+}
+
+// countBuckets spread from single items to bulk results, so abnormally large counts (rows,
+// list sizes) stand out
+var countBuckets = []float64{0, 1, 5, 10, 50, 100, 500, 1000, 5000, 10000, 50000, 100000}
+
+// instrumentNamePattern is the OTel instrument name syntax
+var instrumentNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_./-]{0,254}$`)
+
+// NewCounter returns an Int64Counter named consistently: the _total suffix is appended when
+// missing and the unit is always "1". Names violating the OTel instrument name syntax (a
+// letter followed by up to 254 letters, digits, '_', '.', '-' or '/') return an error.
+// Counters are cached by normalized name, so "orders" and "orders_total" are the same counter.
+func (c *TelemetryClient) NewCounter(name, description string) (metric.Int64Counter, error) {
+	if !strings.HasSuffix(name, "_total") {
+		name += "_total"
+	}
+	if !instrumentNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid counter name %q: must start with a letter and contain only letters, digits, '_', '.', '-' or '/' (max 255 characters)", name)
+	}
+
+	if counter, ok := c.counters.Load(name); ok {
+		return counter.(metric.Int64Counter), nil
+	}
+
+	counter, err := c.Meter.Int64Counter(name, metric.WithDescription(description), metric.WithUnit("1"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create counter %q: %w", name, err)
+	}
+	actual, _ := c.counters.LoadOrStore(name, counter)
+	return actual.(metric.Int64Counter), nil
+}
+
+// RecordCount records n, e.g. the number of items a list endpoint returned, in the <name>_count
+// histogram, to see how the sizes are distributed rather than their sum (use NewCounter for
+// that). attrs are key-value pairs like slog arguments ("endpoint", "/users"), converted like
+// span attributes; keep them low cardinality. Context labels and dynamic attributes are added.
+// Histograms are created on first use and cached by name; invalid names are logged and dropped.
+func (c *TelemetryClient) RecordCount(ctx context.Context, name string, n int64, attrs ...any) {
+	ctx = contextOrBackground(ctx)
+	histogram, err := c.countHistogram(name)
+	if err != nil {
+		c.Logger.ErrorContext(ctx, "failed to record count", "error", err)
+		return
+	}
+
+	kvs := make([]attribute.KeyValue, 0, len(attrs)/2)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		kvs = append(kvs, attributeFromValue(fmt.Sprint(attrs[i]), attrs[i+1]))
+	}
+	kvs = c.attrTransform.apply(c.labelGuard.dynamicAttributes(c.dynamicAttrs, c.labelGuard.metricAttributes(ctx, kvs...)))
+	histogram.Record(ctx, n, metric.WithAttributes(kvs...))
+}
+
+// countHistogram returns the cached <name>_count histogram of RecordCount
+func (c *TelemetryClient) countHistogram(name string) (metric.Int64Histogram, error) {
+	if !strings.HasSuffix(name, "_count") {
+		name += "_count"
+	}
+	if !instrumentNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid count histogram name %q: must start with a letter and contain only letters, digits, '_', '.', '-' or '/' (max 255 characters)", name)
+	}
+
+	if histogram, ok := c.countHistograms.Load(name); ok {
+		return histogram.(metric.Int64Histogram), nil
+	}
+
+	histogram, err := c.Meter.Int64Histogram(name,
+		metric.WithDescription("Distribution of "+strings.TrimSuffix(name, "_count")+" counts"),
+		metric.WithUnit("1"),
+		metric.WithExplicitBucketBoundaries(countBuckets...),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create count histogram %q: %w", name, err)
+	}
+	actual, _ := c.countHistograms.LoadOrStore(name, histogram)
+	return actual.(metric.Int64Histogram), nil
 }
 
 // RegisterRuntimeMetrics provides Go runtime metrics
@@ -101,5 +442,91 @@ func (c *TelemetryClient) RegisterRuntimeMetrics() error {
 		return fmt.Errorf("failed to create heap gauge: %w", err)
 	}
 
+	return c.registerAllocationMetrics()
+}
+
+// registerAllocationMetrics exposes the allocation rate and the malloc/free counts, read from a
+// single ReadMemStats per collection
+func (c *TelemetryClient) registerAllocationMetrics() error {
+	allocRate, err := c.Meter.Float64ObservableGauge(
+		"go_memstats_alloc_rate_bytes",
+		metric.WithDescription("Heap bytes allocated per second since the previous collection"),
+		metric.WithUnit("By/s"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create allocation rate gauge: %w", err)
+	}
+
+	mallocs, err := c.Meter.Int64ObservableCounter(
+		"go_memstats_mallocs_total",
+		metric.WithDescription("Cumulative count of heap objects allocated"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create mallocs counter: %w", err)
+	}
+
+	frees, err := c.Meter.Int64ObservableCounter(
+		"go_memstats_frees_total",
+		metric.WithDescription("Cumulative count of heap objects freed"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create frees counter: %w", err)
+	}
+
+	var (
+		mu            sync.Mutex
+		previousAlloc uint64
+		previousTime  time.Time
+	)
+	_, err = c.Meter.RegisterCallback(func(_ context.Context, observer metric.Observer) error {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		now := time.Now()
+
+		observer.ObserveInt64(mallocs, int64(m.Mallocs))
+		observer.ObserveInt64(frees, int64(m.Frees))
+
+		mu.Lock()
+		defer mu.Unlock()
+		// The first collection has no previous sample, so there is no rate to report yet
+		if !previousTime.IsZero() {
+			if elapsed := now.Sub(previousTime).Seconds(); elapsed > 0 {
+				observer.ObserveFloat64(allocRate, float64(m.TotalAlloc-previousAlloc)/elapsed)
+			}
+		}
+		previousAlloc, previousTime = m.TotalAlloc, now
+		return nil
+	}, allocRate, mallocs, frees)
+	if err != nil {
+		return fmt.Errorf("failed to register allocation metrics callback: %w", err)
+	}
+
 	return nil
 }
+
+// RegisterBuildInfo registers the build_info gauge, always 1, labeled with version
+// (Config.ServiceVersion), go_version and commit (Config.CommitSHA) for filtering dashboards
+// by release. The gauge is registered once per client; later calls return the first result.
+func (c *TelemetryClient) RegisterBuildInfo() error {
+	c.buildInfoOnce.Do(func() {
+		attrs := metric.WithAttributes(
+			attribute.String("version", c.serviceVersion),
+			attribute.String("go_version", runtime.Version()),
+			attribute.String("commit", c.commitSHA),
+		)
+		_, err := c.Meter.Int64ObservableGauge(
+			"build_info",
+			metric.WithDescription("Build information of the running service, always 1"),
+			metric.WithInt64Callback(func(_ context.Context, observer metric.Int64Observer) error {
+				observer.Observe(1, attrs)
+				return nil
+			}),
+		)
+		if err != nil {
+			c.buildInfoErr = fmt.Errorf("failed to create build info gauge: %w", err)
+		}
+	})
+	return c.buildInfoErr
+}
@@ -0,0 +1,118 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestWithDurationBucketsValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		boundaries []float64
+		wantErr    string
+	}{
+		{name: "default", boundaries: nil},
+		{name: "increasing", boundaries: []float64{0.05, 0.1, 0.5, 1}},
+		{name: "unsorted", boundaries: []float64{0.1, 0.5, 0.25}, wantErr: "must be greater than 0.5"},
+		{name: "duplicate", boundaries: []float64{0.1, 0.1, 1}, wantErr: "must be greater than 0.1"},
+		{name: "negative", boundaries: []float64{-1, 0.1}, wantErr: "must be positive"},
+		{name: "zero", boundaries: []float64{0, 0.1}, wantErr: "must be positive"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTestTelemetry(t, Config{})
+			metrics, err := tt.client.NewHTTPMetrics(WithDurationBuckets(tc.boundaries...))
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("err = %v, want it to contain %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(tc.boundaries) == 0 {
+				return
+			}
+			metrics.RecordRequest(context.Background(), "GET", "/", "200", 300*time.Millisecond)
+			data := tt.metric(t, "http_request_duration_seconds").Data.(metricdata.Histogram[float64])
+			if got := data.DataPoints[0].Bounds; !slices.Equal(got, tc.boundaries) {
+				t.Errorf("bounds = %v, want %v", got, tc.boundaries)
+			}
+		})
+	}
+}
+
+func TestWithExponentialHistogram(t *testing.T) {
+	stop := captureStdout(t)
+	tt := newTestTelemetry(t, Config{ConfigPath: writeConfig(t, `file_format: "0.3"
+meter_provider:
+  readers:
+    - periodic:
+        interval: 3600000
+        exporter:
+          console: {}
+`)})
+	if _, err := tt.client.NewHTTPMetrics(WithExponentialHistogram(), WithDurationBuckets(0.1, 1)); err == nil {
+		t.Error("WithDurationBuckets combined with WithExponentialHistogram: want an error")
+	}
+	exponential, err := tt.client.NewHTTPMetrics(WithExponentialHistogram())
+	if err != nil {
+		t.Fatal(err)
+	}
+	explicit, err := tt.client.NewHTTPMetrics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exponential.RecordRequest(context.Background(), "GET", "/", "200", 300*time.Millisecond)
+	explicit.RecordRequest(context.Background(), "GET", "/", "200", 300*time.Millisecond)
+
+	// The instance without the option keeps explicit buckets
+	if _, ok := tt.metric(t, "http_request_duration_seconds").Data.(metricdata.Histogram[float64]); !ok {
+		t.Error("http_request_duration_seconds without the option is not an explicit bucket histogram")
+	}
+
+	// The exponential instance goes through the SDK, exported by the console reader on shutdown
+	if err := tt.client.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var scale bool
+	decoder := json.NewDecoder(&stop().buf)
+	for {
+		var export struct {
+			ScopeMetrics []struct {
+				Scope   struct{ Name string }
+				Metrics []struct {
+					Name string
+					Data struct{ DataPoints []map[string]any }
+				}
+			}
+		}
+		if err := decoder.Decode(&export); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatalf("decode console export: %v", err)
+		}
+		for _, sm := range export.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				if sm.Scope.Name != exponentialHistogramMeter || m.Name != "http_request_duration_seconds" {
+					continue
+				}
+				for _, dp := range m.Data.DataPoints {
+					_, scale = dp["Scale"]
+				}
+			}
+		}
+	}
+	if !scale {
+		t.Error("http_request_duration_seconds with WithExponentialHistogram was not exported as an exponential histogram")
+	}
+}
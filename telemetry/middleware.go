@@ -0,0 +1,515 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultRequestIDHeader is the header read and echoed by WithRequestID
+const DefaultRequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// requestErrorKey holds the *requestError of HTTPMiddleware in the request context
+type requestErrorKey struct{}
+
+// requestError lets a middleware running inside HTTPMiddleware (TimeoutMiddleware) name the
+// error of the request, so HTTPMiddleware records it once with its route and metrics. It is
+// written and read on the serving goroutine.
+type requestError struct {
+	errorType string
+}
+
+// markRequestError sets the error_type HTTPMiddleware records for the request; it reports false
+// when ctx does not come from HTTPMiddleware
+func markRequestError(ctx context.Context, errorType string) bool {
+	marked, ok := ctx.Value(requestErrorKey{}).(*requestError)
+	if ok {
+		marked.errorType = errorType
+	}
+	return ok
+}
+
+// MiddlewareOption configures HTTPMiddleware
+type MiddlewareOption func(*middlewareConfig)
+
+type middlewareConfig struct {
+	capturedHeaders  []string
+	goroutineDelta   bool
+	requestIDHeader  string
+	structuredErrors bool
+	route            string
+	timeout          time.Duration
+	httpMetrics      *HTTPMetrics
+	errorStatus      ErrorStatusClassifier
+}
+
+// WithCapturedHeaders records the given request/response headers as span attributes.
+// Only allowlisted headers are captured so credentials like Authorization never leak.
+func WithCapturedHeaders(headers ...string) MiddlewareOption {
+	return func(cfg *middlewareConfig) {
+		for _, header := range headers {
+			cfg.capturedHeaders = append(cfg.capturedHeaders, http.CanonicalHeaderKey(header))
+		}
+	}
+}
+
+// WithGoroutineDelta records how many goroutines a handler leaves behind in the
+// http_handler_goroutine_delta histogram. runtime.NumGoroutine is process-wide, so under
+// concurrent requests the delta also includes goroutines started or finished by other
+// handlers; treat it as an approximate signal for spotting leaks, not an exact count.
+func WithGoroutineDelta() MiddlewareOption {
+	return func(cfg *middlewareConfig) {
+		cfg.goroutineDelta = true
+	}
+}
+
+// WithRequestID reads the request ID from the given header (DefaultRequestIDHeader when empty),
+// generating a UUID if it is missing. The ID is stored in the context, echoed in the response
+// header and added as request_id to every correlated log.
+func WithRequestID(header string) MiddlewareOption {
+	return func(cfg *middlewareConfig) {
+		if header == "" {
+			header = DefaultRequestIDHeader
+		}
+		cfg.requestIDHeader = header
+	}
+}
+
+// WithRoute uses a fixed route (e.g. "/users/{id}") instead of the request path for the span
+// name, http.route and the endpoint metric label, keeping metric cardinality bounded. Logs
+// still carry the actual path.
+func WithRoute(route string) MiddlewareOption {
+	return func(cfg *middlewareConfig) {
+		cfg.route = route
+	}
+}
+
+// WithTimeout runs the handler under TimeoutMiddleware, inside the span so the timeout is
+// recorded on it
+func WithTimeout(d time.Duration) MiddlewareOption {
+	return func(cfg *middlewareConfig) {
+		cfg.timeout = d
+	}
+}
+
+// WithHTTPMetrics sets the metrics Instrument records to, instead of the client's shared ones.
+// HTTPMiddleware ignores it in favor of its own argument.
+func WithHTTPMetrics(httpMetrics *HTTPMetrics) MiddlewareOption {
+	return func(cfg *middlewareConfig) {
+		cfg.httpMetrics = httpMetrics
+	}
+}
+
+// WithStructuredErrors renders the 500 responses the middleware writes for recovered panics
+// with the trace ID: JSON {"error":"...","trace_id":"..."} when the Accept header asks for
+// JSON, plain text otherwise. Responses written by the handler itself are never touched.
+func WithStructuredErrors() MiddlewareOption {
+	return func(cfg *middlewareConfig) {
+		cfg.structuredErrors = true
+	}
+}
+
+// ErrorStatusClassifier decides whether a response status code marks the request as failed
+type ErrorStatusClassifier func(statusCode int) bool
+
+// DefaultErrorStatus counts client and server errors (status >= 400), the middleware default.
+// 429 is left out: throttled requests are counted in http_requests_throttled_total instead.
+func DefaultErrorStatus(statusCode int) bool {
+	return statusCode >= 400 && statusCode != http.StatusTooManyRequests
+}
+
+// ServerErrorStatus only counts server errors (status >= 500), for APIs where 4xx responses
+// such as validation failures or not found are expected
+func ServerErrorStatus(statusCode int) bool {
+	return statusCode >= 500
+}
+
+// WithErrorStatus sets which status codes set the error status on the span and are counted in
+// http_errors_total (DefaultErrorStatus when nil), e.g. WithErrorStatus(ServerErrorStatus) to
+// leave 4xx responses out. The outcome attribute of WithOutcomeSplit is not affected.
+func WithErrorStatus(classifier ErrorStatusClassifier) MiddlewareOption {
+	return func(cfg *middlewareConfig) {
+		cfg.errorStatus = classifier
+	}
+}
+
+// RequestIDFromContext returns the request ID stored by the middleware
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	requestID, ok := ctx.Value(requestIDKey{}).(string)
+	return requestID, ok
+}
+
+// responseWriter captures the status code and first write time of the wrapped handler
+type responseWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	firstWrite  time.Time
+	wroteHeader bool
+	contentType string
+}
+
+func (rw *responseWriter) WriteHeader(statusCode int) {
+	rw.statusCode = statusCode
+	rw.wroteHeader = true
+	if rw.contentType == "" {
+		rw.contentType = rw.Header().Get("Content-Type")
+	}
+	rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if rw.firstWrite.IsZero() {
+		rw.firstWrite = time.Now()
+		if rw.contentType == "" {
+			rw.contentType = responseContentType(rw.Header(), b)
+		}
+	}
+	rw.wroteHeader = true
+	return rw.ResponseWriter.Write(b)
+}
+
+// responseContentType returns the Content-Type net/http sends for a response starting with b:
+// the header if set, otherwise the type sniffed from the body
+func responseContentType(header http.Header, b []byte) string {
+	if values, ok := header["Content-Type"]; ok {
+		if len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	if len(b) == 0 || header.Get("Content-Encoding") != "" {
+		return ""
+	}
+	return http.DetectContentType(b)
+}
+
+// Flush keeps streaming (SSE) handlers working through the wrapper
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// HTTPMiddleware instruments an http.Handler with tracing, metrics and logs
+func (c *TelemetryClient) HTTPMiddleware(httpMetrics *HTTPMetrics, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	cfg := &middlewareConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	var goroutineDelta metric.Int64Histogram
+	if cfg.goroutineDelta {
+		var err error
+		goroutineDelta, err = c.Meter.Int64Histogram(
+			"http_handler_goroutine_delta",
+			metric.WithDescription("Goroutines alive after the handler returned minus goroutines before it started"),
+			metric.WithUnit("1"),
+		)
+		if err != nil {
+			c.Logger.Error("failed to create goroutine delta histogram", "error", err)
+		}
+	}
+
+	invalidTraceContext, err := c.Meter.Int64Counter(
+		"trace_context_invalid_total",
+		metric.WithDescription("Requests carrying a traceparent header that could not be extracted"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		c.Logger.Error("failed to create invalid trace context counter, falling back to no-op", "error", err)
+		invalidTraceContext, _ = noop.NewMeterProvider().Meter("").Int64Counter("trace_context_invalid_total")
+	}
+
+	return func(next http.Handler) http.Handler {
+		if cfg.timeout > 0 {
+			next = c.TimeoutMiddleware(cfg.timeout, opts...)(next)
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			startTime := time.Now()
+
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			if traceparent := r.Header.Get("traceparent"); traceparent != "" && !trace.SpanContextFromContext(ctx).IsRemote() {
+				// A malformed header silently starts a new root trace, make the broken client visible.
+				// Only the length is logged, the value is untrusted input.
+				invalidTraceContext.Add(ctx, 1)
+				c.Logger.DebugContext(ctx, "invalid inbound trace context, starting a new trace",
+					"header", "traceparent",
+					"header_length", len(traceparent),
+				)
+			}
+			forced := c.forceTrace.allowed(r)
+			if forced {
+				ctx = withForcedSampling(ctx)
+			}
+
+			route := r.URL.Path
+			if cfg.route != "" {
+				route = cfg.route
+			}
+
+			// Start attributes are visible to samplers (e.g. Config.RouteSampling)
+			ctx, span := c.Tracer.Start(ctx, r.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.method", r.Method),
+					attribute.String("http.route", route),
+				),
+			)
+			defer span.End()
+
+			cfg.captureHeaders(span, "http.request.header.", r.Header)
+			if forced {
+				span.SetAttributes(attribute.Bool("sampling.forced", true))
+			}
+
+			if c.coldStart.observe() {
+				// As a label, cold_start also reaches the HTTP metrics and the request logs
+				span.SetAttributes(attribute.Bool("cold_start", true))
+				ctx = mergeLabels(ctx, LabelSet{"cold_start": "true"})
+			}
+
+			if cfg.requestIDHeader != "" {
+				requestID := r.Header.Get(cfg.requestIDHeader)
+				if requestID == "" {
+					requestID = uuid.NewString()
+				}
+				ctx = context.WithValue(ctx, requestIDKey{}, requestID)
+				w.Header().Set(cfg.requestIDHeader, requestID)
+			}
+
+			marked := &requestError{}
+			ctx = context.WithValue(ctx, requestErrorKey{}, marked)
+
+			goroutinesBefore := runtime.NumGoroutine()
+
+			rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			c.serveRecovering(ctx, next, rw, r.WithContext(ctx), cfg)
+
+			if goroutineDelta != nil {
+				goroutineDelta.Record(ctx, int64(runtime.NumGoroutine()-goroutinesBefore), metric.WithAttributes(
+					attribute.String("method", r.Method),
+					attribute.String("endpoint", route),
+				))
+			}
+
+			cfg.captureHeaders(span, "http.response.header.", rw.Header())
+			span.SetAttributes(attribute.Int("http.status_code", rw.statusCode))
+			if rw.contentType != "" {
+				span.SetAttributes(attribute.String("http.response.content_type", rw.contentType))
+			}
+
+			duration := time.Since(startTime)
+			httpMetrics.RecordRequest(ctx, r.Method, route, strconv.Itoa(rw.statusCode), duration)
+			if !rw.firstWrite.IsZero() {
+				httpMetrics.RecordTimeToFirstByte(ctx, r.Method, route, rw.firstWrite.Sub(startTime))
+			}
+
+			if httpMetrics.sliClassifier != nil {
+				httpMetrics.RecordSLI(ctx, route, httpMetrics.sliClassifier(rw.statusCode, duration))
+			}
+
+			if rw.statusCode == http.StatusTooManyRequests {
+				httpMetrics.RecordThrottle(ctx, r.Method, route)
+			}
+
+			var errorType string
+			if cfg.isErrorStatus(rw.statusCode) {
+				errorType = "client_error"
+				if rw.statusCode >= 500 {
+					errorType = "server_error"
+				}
+				span.SetStatus(codes.Error, http.StatusText(rw.statusCode))
+			}
+			if marked.errorType != "" {
+				// Counted even when the response had started with a success status
+				errorType = marked.errorType
+			}
+			if errorType != "" {
+				httpMetrics.RecordError(ctx, errorType, route)
+			}
+
+			c.LogHTTPRequest(ctx, r.Method, r.URL.Path, rw.statusCode, duration)
+		})
+	}
+}
+
+// Instrument returns the complete server instrumentation as a single middleware, so the steps
+// cannot be chained in the wrong order. For each request, in order: the trace context is
+// extracted, the server span started, the cold start and request ID (WithRequestID) set up;
+// then panics are recovered around the timeout (WithTimeout) and the handler; finally the
+// span status, HTTP metrics (shared ones unless WithHTTPMetrics) and request log are recorded.
+// Every MiddlewareOption applies.
+func (c *TelemetryClient) Instrument(opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	cfg := &middlewareConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	httpMetrics := cfg.httpMetrics
+	if httpMetrics == nil {
+		httpMetrics = c.sharedHTTPMetrics()
+	}
+	return c.HTTPMiddleware(httpMetrics, opts...)
+}
+
+// Handle wraps fn with Instrument bound to route. Unless WithHTTPMetrics is given, it records
+// to the client's shared HTTP metrics (the ones behind RouteMetrics).
+func (c *TelemetryClient) Handle(route string, fn http.HandlerFunc, opts ...MiddlewareOption) http.Handler {
+	opts = append(opts[:len(opts):len(opts)], WithRoute(route))
+	return c.Instrument(opts...)(fn)
+}
+
+// HandleFunc registers an instrumented fn on mux. The route is the pattern without the method
+// and host, so "GET /users/{id}" is recorded as "/users/{id}".
+func (c *TelemetryClient) HandleFunc(mux *http.ServeMux, pattern string, fn http.HandlerFunc, opts ...MiddlewareOption) {
+	mux.Handle(pattern, c.Handle(patternRoute(pattern), fn, opts...))
+}
+
+// patternRoute extracts the path of a ServeMux pattern ("[METHOD ][HOST]/[PATH]")
+func patternRoute(pattern string) string {
+	if _, rest, ok := strings.Cut(pattern, " "); ok {
+		pattern = strings.TrimSpace(rest)
+	}
+	if i := strings.Index(pattern, "/"); i > 0 {
+		pattern = pattern[i:]
+	}
+	return pattern
+}
+
+// serveRecovering runs the handler, turning a panic into a 500 response (unless the handler
+// already started responding) recorded on the span, logged and counted with source=http. http.ErrAbortHandler is
+// re-panicked, net/http uses it to abort the response on purpose.
+func (c *TelemetryClient) serveRecovering(ctx context.Context, next http.Handler, rw *responseWriter, r *http.Request, cfg *middlewareConfig) {
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			return
+		}
+		if recovered == http.ErrAbortHandler {
+			panic(recovered)
+		}
+
+		c.recordPanic(ctx, recovered, "http", "panic recovered in HTTP handler")
+
+		if !rw.wroteHeader {
+			cfg.writeError(ctx, rw, r, http.StatusInternalServerError)
+		}
+	}()
+
+	next.ServeHTTP(rw, r)
+}
+
+// writeError writes an error response generated by the middleware itself
+func (cfg *middlewareConfig) writeError(ctx context.Context, w http.ResponseWriter, r *http.Request, statusCode int) {
+	message := http.StatusText(statusCode)
+	if !cfg.structuredErrors {
+		http.Error(w, message, statusCode)
+		return
+	}
+
+	traceID := ""
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		traceID = spanContext.TraceID().String()
+	}
+
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if acceptsJSON(r.Header.Get("Accept")) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		_ = json.NewEncoder(w).Encode(struct {
+			Error   string `json:"error"`
+			TraceID string `json:"trace_id,omitempty"`
+		}{message, traceID})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(statusCode)
+	if traceID != "" {
+		fmt.Fprintf(w, "%s (trace_id: %s)\n", message, traceID)
+	} else {
+		fmt.Fprintln(w, message)
+	}
+}
+
+// acceptsJSON reports whether the Accept header lists a JSON media type
+func acceptsJSON(accept string) bool {
+	for _, mediaType := range strings.Split(accept, ",") {
+		mediaType, _, _ = strings.Cut(mediaType, ";")
+		mediaType = strings.TrimSpace(mediaType)
+		if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+			return true
+		}
+	}
+	return false
+}
+
+// captureHeaders sets allowlisted headers as span attributes, joining multiple values with commas
+func (cfg *middlewareConfig) captureHeaders(span trace.Span, prefix string, header http.Header) {
+	for _, name := range cfg.capturedHeaders {
+		values := header.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		span.SetAttributes(attribute.String(prefix+strings.ToLower(name), strings.Join(values, ",")))
+	}
+}
+
+// isErrorStatus classifies the response status with WithErrorStatus, or DefaultErrorStatus
+func (cfg *middlewareConfig) isErrorStatus(statusCode int) bool {
+	if cfg.errorStatus == nil {
+		return DefaultErrorStatus(statusCode)
+	}
+	return cfg.errorStatus(statusCode)
+}
+
+// DefaultColdStartWindow is how long after NewClient requests are tagged cold_start by default
+const DefaultColdStartWindow = 10 * time.Second
+
+// coldStartTracker reports whether a request is on the cold path: the first request served,
+// or any request within the window after the client was created
+type coldStartTracker struct {
+	startedAt time.Time
+	window    time.Duration
+	served    atomic.Bool
+}
+
+func newColdStartTracker(window time.Duration) *coldStartTracker {
+	if window == 0 {
+		window = DefaultColdStartWindow
+	}
+	return &coldStartTracker{startedAt: time.Now(), window: window}
+}
+
+func (t *coldStartTracker) observe() bool {
+	if t == nil || t.window < 0 {
+		return false
+	}
+	first := !t.served.Swap(true)
+	return first || time.Since(t.startedAt) < t.window
+}
@@ -0,0 +1,297 @@
+package telemetry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// serveMiddleware runs req through HTTPMiddleware with opts and returns the response and the
+// server span
+func serveMiddleware(t *testing.T, tt *testTelemetry, handler http.HandlerFunc, req *http.Request, opts ...MiddlewareOption) (*httptest.ResponseRecorder, sdktrace.ReadOnlySpan) {
+	t.Helper()
+	metrics, err := tt.client.NewHTTPMetrics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	tt.client.HTTPMiddleware(metrics, opts...)(handler).ServeHTTP(rec, req)
+	spans := tt.spans.Ended()
+	if len(spans) == 0 {
+		t.Fatal("no span ended")
+	}
+	return rec, spans[len(spans)-1]
+}
+
+func TestWithCapturedHeaders(t *testing.T) {
+	tests := []struct {
+		name      string
+		captured  []string
+		reqHeader http.Header
+		resHeader http.Header
+		want      map[attribute.Key]string
+		absent    []attribute.Key
+	}{
+		{
+			name:      "allowlisted only",
+			captured:  []string{"X-Tenant", "content-type"},
+			reqHeader: http.Header{"X-Tenant": {"acme"}, "Authorization": {"Bearer secret"}},
+			resHeader: http.Header{"Content-Type": {"application/json"}, "Set-Cookie": {"session=1"}},
+			want: map[attribute.Key]string{
+				"http.request.header.x-tenant":      "acme",
+				"http.response.header.content-type": "application/json",
+			},
+			absent: []attribute.Key{
+				"http.request.header.authorization",
+				"http.response.header.set-cookie",
+				"http.request.header.content-type",
+			},
+		},
+		{
+			name:      "multi-value joined",
+			captured:  []string{"Accept"},
+			reqHeader: http.Header{"Accept": {"text/html", "application/json"}},
+			want:      map[attribute.Key]string{"http.request.header.accept": "text/html,application/json"},
+		},
+		{
+			name:      "nothing allowlisted",
+			reqHeader: http.Header{"Authorization": {"Bearer secret"}},
+			absent:    []attribute.Key{"http.request.header.authorization"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTestTelemetry(t, Config{})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header = tc.reqHeader
+			_, span := serveMiddleware(t, tt, func(w http.ResponseWriter, r *http.Request) {
+				for key, values := range tc.resHeader {
+					w.Header()[key] = values
+				}
+				w.WriteHeader(http.StatusOK)
+			}, req, WithCapturedHeaders(tc.captured...))
+
+			for key, want := range tc.want {
+				if got, ok := spanAttr(span, key); !ok || got.AsString() != want {
+					t.Errorf("%s = %q (present %v), want %q", key, got.AsString(), ok, want)
+				}
+			}
+			for _, key := range tc.absent {
+				if _, ok := spanAttr(span, key); ok {
+					t.Errorf("%s captured, want absent", key)
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPMiddlewareThrottle(t *testing.T) {
+	tests := []struct {
+		name          string
+		statusCode    int
+		opts          []MiddlewareOption
+		wantThrottled bool
+		wantError     bool
+	}{
+		{name: "429", statusCode: http.StatusTooManyRequests, wantThrottled: true},
+		{
+			name:       "429 counted as error",
+			statusCode: http.StatusTooManyRequests,
+			opts: []MiddlewareOption{WithErrorStatus(func(status int) bool {
+				return status >= 500 || status == http.StatusTooManyRequests
+			})},
+			wantThrottled: true,
+			wantError:     true,
+		},
+		{name: "200", statusCode: http.StatusOK},
+		{name: "503", statusCode: http.StatusServiceUnavailable, wantError: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTestTelemetry(t, Config{})
+			_, span := serveMiddleware(t, tt, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.statusCode)
+			}, httptest.NewRequest(http.MethodPost, "/orders", nil), append(tc.opts, WithRoute("/orders"))...)
+
+			throttled, ok := spanAttr(span, "http.throttled")
+			if ok != tc.wantThrottled || (ok && !throttled.AsBool()) {
+				t.Errorf("http.throttled = %v (present %v), want %v", throttled.AsBool(), ok, tc.wantThrottled)
+			}
+			want := 0.0
+			if tc.wantThrottled {
+				want = 1
+			}
+			if got := tt.sum(t, "http_requests_throttled_total", attribute.String("method", "POST"), attribute.String("endpoint", "/orders")); got != want {
+				t.Errorf("http_requests_throttled_total = %v, want %v", got, want)
+			}
+			// A throttled request is not also an error unless the classifier says so
+			if got := tt.sum(t, "http_errors_total"); (got > 0) != tc.wantError {
+				t.Errorf("http_errors_total = %v, want error %v", got, tc.wantError)
+			}
+			if got := span.Status().Code == codes.Error; got != tc.wantError {
+				t.Errorf("span error status = %v, want %v", got, tc.wantError)
+			}
+		})
+	}
+}
+
+func TestHTTPMiddlewareTimeToFirstByte(t *testing.T) {
+	const pause = 50 * time.Millisecond
+	tests := []struct {
+		name      string
+		handler   http.HandlerFunc
+		wantCount uint64
+	}{
+		{
+			name: "write sleep write",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("data: first\n\n"))
+				w.(http.Flusher).Flush()
+				time.Sleep(pause)
+				_, _ = w.Write([]byte("data: second\n\n"))
+			},
+			wantCount: 1,
+		},
+		{
+			name: "no write",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTestTelemetry(t, Config{})
+			serveMiddleware(t, tt, tc.handler, httptest.NewRequest(http.MethodGet, "/events", nil), WithRoute("/events"))
+
+			if tc.wantCount == 0 {
+				if tt.hasMetric(t, "http_response_ttfb_seconds") {
+					t.Error("http_response_ttfb_seconds recorded without a Write")
+				}
+				return
+			}
+			data := tt.metric(t, "http_response_ttfb_seconds").Data.(metricdata.Histogram[float64])
+			var count uint64
+			for _, dp := range data.DataPoints {
+				count += dp.Count
+				if dp.Count > 0 && dp.Sum >= pause.Seconds() {
+					t.Errorf("ttfb = %vs, want the first write before the %v pause", dp.Sum, pause)
+				}
+			}
+			if count != tc.wantCount {
+				t.Errorf("ttfb observations = %d, want %d", count, tc.wantCount)
+			}
+			// The total duration still covers the whole stream
+			duration := tt.metric(t, "http_request_duration_seconds").Data.(metricdata.Histogram[float64])
+			if duration.DataPoints[0].Sum < pause.Seconds() {
+				t.Errorf("duration = %vs, want at least the %v pause", duration.DataPoints[0].Sum, pause)
+			}
+		})
+	}
+}
+
+func TestHTTPMiddlewareResponseContentType(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string
+	}{
+		{
+			name: "json",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"ok":true}`))
+			},
+			want: "application/json",
+		},
+		{
+			name: "text set before WriteHeader",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusAccepted)
+				_, _ = w.Write([]byte("accepted"))
+			},
+			want: "text/plain; charset=utf-8",
+		},
+		{
+			name: "text sniffed from the body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("hello"))
+			},
+			want: "text/plain; charset=utf-8",
+		},
+		{
+			name: "unset",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTestTelemetry(t, Config{})
+			rec, span := serveMiddleware(t, tt, tc.handler, httptest.NewRequest(http.MethodGet, "/content", nil), WithRoute("/content"))
+
+			got, ok := spanAttr(span, "http.response.content_type")
+			if tc.want == "" {
+				if ok {
+					t.Errorf("http.response.content_type = %q, want unset", got.AsString())
+				}
+				return
+			}
+			if got.AsString() != tc.want {
+				t.Errorf("http.response.content_type = %q, want %q", got.AsString(), tc.want)
+			}
+			if sent := rec.Header().Get("Content-Type"); sent != tc.want {
+				t.Errorf("response Content-Type = %q, want %q", sent, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithErrorStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []MiddlewareOption
+		statusCode int
+		wantError  bool
+		wantType   string
+	}{
+		{name: "404 by default", statusCode: http.StatusNotFound, wantError: true, wantType: "client_error"},
+		{name: "404 with ServerErrorStatus", opts: []MiddlewareOption{WithErrorStatus(ServerErrorStatus)}, statusCode: http.StatusNotFound},
+		{name: "422 with ServerErrorStatus", opts: []MiddlewareOption{WithErrorStatus(ServerErrorStatus)}, statusCode: http.StatusUnprocessableEntity},
+		{name: "500 with ServerErrorStatus", opts: []MiddlewareOption{WithErrorStatus(ServerErrorStatus)}, statusCode: http.StatusInternalServerError, wantError: true, wantType: "server_error"},
+		{name: "200 by default", statusCode: http.StatusOK},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTestTelemetry(t, Config{})
+			opts := append([]MiddlewareOption{WithRoute("/items/{id}")}, tc.opts...)
+			_, span := serveMiddleware(t, tt, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.statusCode)
+			}, httptest.NewRequest(http.MethodGet, "/items/1", nil), opts...)
+
+			if gotError := span.Status().Code == codes.Error; gotError != tc.wantError {
+				t.Errorf("span status = %v, want error %v", span.Status(), tc.wantError)
+			}
+			if got := tt.sum(t, "http_errors_total"); (got > 0) != tc.wantError {
+				t.Errorf("http_errors_total = %v, want error %v", got, tc.wantError)
+			}
+			if tc.wantError {
+				if got := tt.sum(t, "http_errors_total", attribute.String("error_type", tc.wantType)); got != 1 {
+					t.Errorf("http_errors_total{error_type=%q} = %v, want 1", tc.wantType, got)
+				}
+			}
+			// The request itself is still counted
+			if got := tt.sum(t, "http_requests_total"); got != 1 {
+				t.Errorf("http_requests_total = %v, want 1", got)
+			}
+		})
+	}
+}
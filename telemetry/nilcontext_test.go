@@ -0,0 +1,70 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestHelpersAcceptNilContext(t *testing.T) {
+	tt := newTestTelemetry(t, Config{})
+	c := tt.client
+	metrics, err := c.NewHTTPMetrics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ctx context.Context // nil, as library code paths sometimes pass
+
+	tests := []struct {
+		name    string
+		call    func()
+		wantLog string
+	}{
+		{name: "LogError", call: func() { c.LogError(ctx, errors.New("boom"), "failed") }, wantLog: "failed"},
+		{name: "LogWithSpanAttributes", call: func() {
+			c.LogWithSpanAttributes(ctx, slog.LevelInfo, "attrs", map[string]any{"k": "v"})
+		}, wantLog: "attrs"},
+		{name: "LogHTTPRequest", call: func() { c.LogHTTPRequest(ctx, "GET", "/", 200, time.Millisecond) }, wantLog: "HTTP request completed"},
+		{name: "WithLabels", call: func() {
+			if got := c.WithLabels(ctx, LabelSet{"tenant": "acme"}); got == nil {
+				t.Error("WithLabels returned a nil context")
+			}
+		}},
+		{name: "WithProfilingLabels", call: func() {
+			if got := c.WithProfilingLabels(ctx); got == nil {
+				t.Error("WithProfilingLabels returned a nil context")
+			}
+		}},
+		{name: "RecordRequest", call: func() { metrics.RecordRequest(ctx, "GET", "/", "200", time.Millisecond) }},
+		{name: "RecordTimeToFirstByte", call: func() { metrics.RecordTimeToFirstByte(ctx, "GET", "/", time.Millisecond) }},
+		{name: "RecordError", call: func() { metrics.RecordError(ctx, "server_error", "/") }},
+		{name: "RecordSLI", call: func() { metrics.RecordSLI(ctx, "/", true) }},
+		{name: "RecordThrottle", call: func() { metrics.RecordThrottle(ctx, "GET", "/") }},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			before := len(tt.logs.lines(t))
+			func() {
+				defer func() {
+					if recovered := recover(); recovered != nil {
+						t.Fatalf("panicked with a nil context: %v", recovered)
+					}
+				}()
+				tc.call()
+			}()
+			if tc.wantLog == "" {
+				return
+			}
+			lines := tt.logs.lines(t)
+			if len(lines) != before+1 || lines[len(lines)-1]["msg"] != tc.wantLog {
+				t.Errorf("want one %q log line, got %v", tc.wantLog, lines[before:])
+			}
+		})
+	}
+
+	if got := tt.sum(t, "http_requests_total"); got != 1 {
+		t.Errorf("http_requests_total = %v, want 1", got)
+	}
+}
@@ -0,0 +1,171 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// correlationKeys are carried natively by OTLP log records (via the context), so the
+// attributes added by CorrelatedHandler are not duplicated
+var correlationKeys = map[string]bool{
+	"trace_id":      true,
+	"span_id":       true,
+	"trace_sampled": true,
+}
+
+// OTLPHandler is a slog.Handler that emits records through an OpenTelemetry LoggerProvider.
+// Groups are flattened into dotted keys following the JSON handler nesting, so
+// {"request":{"id":1}} in JSON is request.id in OTLP.
+type OTLPHandler struct {
+	logger      log.Logger
+	attrs       []log.KeyValue
+	prefix      string // open groups joined with dots, with a trailing dot
+	sampledOnly bool
+	// severityKeys are the WithSeverityAttrs keys of the client logger, redundant with the
+	// native OTLP severity
+	severityKeys map[string]bool
+}
+
+// OTLPHandlerOption configures an OTLPHandler
+type OTLPHandlerOption func(*OTLPHandler)
+
+// WithSampledTracesOnly skips records below error level logged within a trace that was not
+// sampled, so OTLP log retention follows trace sampling. Records without a span context are
+// still emitted. The other handlers of a MultiHandler are unaffected.
+func WithSampledTracesOnly() OTLPHandlerOption {
+	return func(h *OTLPHandler) {
+		h.sampledOnly = true
+	}
+}
+
+// NewOTLPHandler creates a handler emitting to the named logger of the provider
+func NewOTLPHandler(provider log.LoggerProvider, name string, opts ...OTLPHandlerOption) *OTLPHandler {
+	h := &OTLPHandler{logger: provider.Logger(name)}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func (h *OTLPHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if h.sampledOnly && level < slog.LevelError {
+		if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() && !spanContext.IsSampled() {
+			return false
+		}
+	}
+	return h.logger.Enabled(ctx, log.EnabledParameters{Severity: SeverityFromLevel(level)})
+}
+
+func (h *OTLPHandler) Handle(ctx context.Context, record slog.Record) error {
+	var r log.Record
+	r.SetTimestamp(record.Time)
+	r.SetBody(log.StringValue(record.Message))
+	r.SetSeverity(SeverityFromLevel(record.Level))
+	r.SetSeverityText(record.Level.String())
+
+	attrs := make([]log.KeyValue, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		if !correlationKeys[attr.Key] && !h.severityKeys[attr.Key] {
+			attrs = appendLogAttr(attrs, h.prefix, attr)
+		}
+		return true
+	})
+
+	r.AddAttributes(h.attrs...)
+	r.AddAttributes(attrs...)
+
+	h.logger.Emit(ctx, r)
+	return nil
+}
+
+func (h *OTLPHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append([]log.KeyValue{}, h.attrs...)
+	for _, attr := range attrs {
+		clone.attrs = appendLogAttr(clone.attrs, h.prefix, attr)
+	}
+	return &clone
+}
+
+func (h *OTLPHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.prefix = h.prefix + name + "."
+	return &clone
+}
+
+// SeverityFromLevel maps a slog level to an OTel severity number. slog levels are spaced by
+// 4 (Debug=-4, Info=0, Warn=4, Error=8) just like the OTel ranges (DEBUG=5, INFO=9, WARN=13,
+// ERROR=17), so custom levels in between land on the matching sub-severity (e.g. Info+2 ->
+// INFO3). Values outside the OTel range are clamped to TRACE and FATAL4.
+func SeverityFromLevel(level slog.Level) log.Severity {
+	severity := int(level) + int(log.SeverityInfo)
+	switch {
+	case severity < int(log.SeverityTrace1):
+		return log.SeverityTrace1
+	case severity > int(log.SeverityFatal4):
+		return log.SeverityFatal4
+	default:
+		return log.Severity(severity)
+	}
+}
+
+// appendLogAttr converts a slog attribute under the key prefix, flattening group values into
+// dotted keys and inlining groups with empty keys like slog does
+func appendLogAttr(attrs []log.KeyValue, prefix string, attr slog.Attr) []log.KeyValue {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return attrs
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, member := range attr.Value.Group() {
+			attrs = appendLogAttr(attrs, prefix, member)
+		}
+		return attrs
+	}
+	return append(attrs, log.KeyValue{Key: prefix + attr.Key, Value: logValue(attr.Value)})
+}
+
+// logValue converts a resolved slog value into an OTel log value
+func logValue(value slog.Value) log.Value {
+	switch value.Kind() {
+	case slog.KindString:
+		return log.StringValue(value.String())
+	case slog.KindInt64:
+		return log.Int64Value(value.Int64())
+	case slog.KindUint64:
+		if v := value.Uint64(); v <= math.MaxInt64 {
+			return log.Int64Value(int64(v))
+		}
+		return log.StringValue(value.String())
+	case slog.KindFloat64:
+		return log.Float64Value(value.Float64())
+	case slog.KindBool:
+		return log.BoolValue(value.Bool())
+	case slog.KindDuration:
+		return log.Int64Value(value.Duration().Nanoseconds())
+	case slog.KindTime:
+		return log.Int64Value(value.Time().UnixNano())
+	default:
+		switch v := value.Any().(type) {
+		case error:
+			return log.StringValue(v.Error())
+		case []byte:
+			return log.BytesValue(v)
+		case fmt.Stringer:
+			return log.StringValue(v.String())
+		default:
+			return log.StringValue(fmt.Sprintf("%+v", v))
+		}
+	}
+}
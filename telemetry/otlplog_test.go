@@ -0,0 +1,124 @@
+package telemetry
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// recordingProcessor keeps the emitted OTLP log records
+type recordingProcessor struct {
+	mu      sync.Mutex
+	records []sdklog.Record
+}
+
+func (p *recordingProcessor) OnEmit(_ context.Context, record *sdklog.Record) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.records = append(p.records, record.Clone())
+	return nil
+}
+
+func (p *recordingProcessor) Shutdown(context.Context) error   { return nil }
+func (p *recordingProcessor) ForceFlush(context.Context) error { return nil }
+
+// flattenJSON returns the dotted paths of the leaves of a decoded JSON record, without the
+// built-in time, level and msg keys
+func flattenJSON(prefix string, record map[string]any, keys []string) []string {
+	for key, value := range record {
+		if prefix == "" && (key == slog.TimeKey || key == slog.LevelKey || key == slog.MessageKey) {
+			continue
+		}
+		if group, ok := value.(map[string]any); ok {
+			keys = flattenJSON(prefix+key+".", group, keys)
+			continue
+		}
+		keys = append(keys, prefix+key)
+	}
+	return keys
+}
+
+func TestSeverityFromLevel(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  log.Severity
+	}{
+		{slog.LevelDebug, log.SeverityDebug},
+		{slog.LevelInfo, log.SeverityInfo},
+		{slog.LevelWarn, log.SeverityWarn},
+		{slog.LevelError, log.SeverityError},
+		{slog.LevelDebug - 4, log.SeverityTrace},
+		{slog.LevelDebug + 1, log.SeverityDebug2},
+		{slog.LevelInfo + 2, log.SeverityInfo3},
+		{slog.LevelWarn - 1, log.SeverityInfo4},
+		{slog.LevelError + 3, log.SeverityError4},
+		{slog.LevelError + 4, log.SeverityFatal},
+		{slog.LevelError + 7, log.SeverityFatal4},
+		{slog.LevelError + 100, log.SeverityFatal4},
+		{slog.LevelDebug - 100, log.SeverityTrace1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.level.String(), func(t *testing.T) {
+			if got := SeverityFromLevel(tc.level); got != tc.want {
+				t.Errorf("SeverityFromLevel(%v) = %v, want %v", tc.level, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestOTLPHandlerGroupsMatchJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(logger *slog.Logger)
+	}{
+		{name: "no group", log: func(logger *slog.Logger) {
+			logger.Info("msg", "id", 1, "name", "bob")
+		}},
+		{name: "WithGroup", log: func(logger *slog.Logger) {
+			logger.With("service", "orders").WithGroup("request").With("id", 7).Info("msg", "path", "/")
+		}},
+		{name: "nested groups", log: func(logger *slog.Logger) {
+			logger.WithGroup("request").WithGroup("user").Info("msg", "id", 7, slog.Group("meta", "role", "admin"))
+		}},
+		{name: "inline group", log: func(logger *slog.Logger) {
+			logger.WithGroup("request").Info("msg", slog.Group("", "a", 1), "b", 2)
+		}},
+		{name: "empty group dropped", log: func(logger *slog.Logger) {
+			logger.Info("msg", slog.Group("empty"), "b", 2)
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			processor := &recordingProcessor{}
+			provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(processor))
+			jsonOut := &syncBuffer{}
+			tc.log(slog.New(NewMultiHandler(slog.NewJSONHandler(jsonOut, nil), NewOTLPHandler(provider, "test"))))
+
+			lines := jsonOut.lines(t)
+			if len(lines) != 1 || len(processor.records) != 1 {
+				t.Fatalf("got %d JSON lines and %d OTLP records, want 1 each", len(lines), len(processor.records))
+			}
+			jsonKeys := flattenJSON("", lines[0], nil)
+			var otlpKeys []string
+			processor.records[0].WalkAttributes(func(kv log.KeyValue) bool {
+				otlpKeys = append(otlpKeys, kv.Key)
+				return true
+			})
+			sort.Strings(jsonKeys)
+			sort.Strings(otlpKeys)
+			if len(jsonKeys) != len(otlpKeys) {
+				t.Fatalf("JSON keys %v, OTLP keys %v", jsonKeys, otlpKeys)
+			}
+			for i := range jsonKeys {
+				if jsonKeys[i] != otlpKeys[i] {
+					t.Fatalf("JSON keys %v, OTLP keys %v", jsonKeys, otlpKeys)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// DefaultPercentileWindow is the sliding window used by WithDurationP99 when none is given
+const DefaultPercentileWindow = time.Minute
+
+// percentileMaxSamples bounds the samples kept per endpoint. Under higher rates only the most
+// recent samples are kept, so the effective window shrinks.
+const percentileMaxSamples = 2048
+
+// WithDurationP99 additionally keeps the request durations of the last window (<= 0 uses
+// DefaultPercentileWindow) in memory and exposes their 99th percentile per endpoint as the
+// http_request_duration_p99_seconds gauge, for tools that cannot compute percentiles from
+// histograms. The estimate is exact over the retained samples but covers at most
+// percentileMaxSamples per endpoint, and, like any client-side percentile, cannot be
+// aggregated across instances or endpoints.
+func WithDurationP99(window time.Duration) HTTPMetricsOption {
+	return func(cfg *httpMetricsConfig) {
+		if window <= 0 {
+			window = DefaultPercentileWindow
+		}
+		cfg.p99Window = window
+	}
+}
+
+type durationSample struct {
+	at       time.Time
+	duration time.Duration
+}
+
+// durationWindow keeps the most recent samples of one endpoint in a ring buffer
+type durationWindow struct {
+	samples []durationSample
+	next    int
+}
+
+func (w *durationWindow) add(sample durationSample) {
+	if len(w.samples) < percentileMaxSamples {
+		w.samples = append(w.samples, sample)
+		return
+	}
+	w.samples[w.next] = sample
+	w.next = (w.next + 1) % percentileMaxSamples
+}
+
+// percentileEstimator computes per-endpoint duration percentiles over a sliding window
+type percentileEstimator struct {
+	window time.Duration
+
+	mu        sync.Mutex
+	endpoints map[string]*durationWindow
+}
+
+func newPercentileEstimator(window time.Duration) *percentileEstimator {
+	return &percentileEstimator{window: window, endpoints: map[string]*durationWindow{}}
+}
+
+func (e *percentileEstimator) record(endpoint string, duration time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	w, ok := e.endpoints[endpoint]
+	if !ok {
+		if len(e.endpoints) >= maxLabelValues {
+			endpoint = overflowLabelValue
+			w = e.endpoints[endpoint]
+		}
+		if w == nil {
+			w = &durationWindow{}
+			e.endpoints[endpoint] = w
+		}
+	}
+	w.add(durationSample{at: time.Now(), duration: duration})
+}
+
+// percentiles returns the q quantile of the samples in the window for each endpoint, and drops
+// the endpoints without recent samples
+func (e *percentileEstimator) percentiles(q float64) map[string]time.Duration {
+	cutoff := time.Now().Add(-e.window)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	result := make(map[string]time.Duration, len(e.endpoints))
+	for endpoint, w := range e.endpoints {
+		durations := make([]time.Duration, 0, len(w.samples))
+		for _, sample := range w.samples {
+			if sample.at.After(cutoff) {
+				durations = append(durations, sample.duration)
+			}
+		}
+		if len(durations) == 0 {
+			delete(e.endpoints, endpoint)
+			continue
+		}
+		slices.Sort(durations)
+		// Nearest-rank percentile
+		rank := int(math.Ceil(q*float64(len(durations)))) - 1
+		result[endpoint] = durations[max(rank, 0)]
+	}
+	return result
+}
+
+// registerP99 creates the http_request_duration_p99_seconds gauge fed by the estimator. The SDK
+// keeps only the first callback passed at creation for a name, so the callback is registered
+// separately.
+func registerP99(meter metric.Meter, estimator *percentileEstimator) error {
+	p99, err := meter.Float64ObservableGauge(
+		"http_request_duration_p99_seconds",
+		metric.WithDescription("99th percentile of HTTP request durations over a sliding window, in seconds"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create p99 duration gauge: %w", err)
+	}
+	_, err = meter.RegisterCallback(func(_ context.Context, observer metric.Observer) error {
+		for endpoint, duration := range estimator.percentiles(0.99) {
+			observer.ObserveFloat64(p99, duration.Seconds(), metric.WithAttributes(attribute.String("endpoint", endpoint)))
+		}
+		return nil
+	}, p99)
+	if err != nil {
+		return fmt.Errorf("failed to register p99 duration callback: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,860 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	otelconf "go.opentelemetry.io/contrib/otelconf/v0.3.0"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutlog"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/log"
+	lognoop "go.opentelemetry.io/otel/log/noop"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/credentials"
+)
+
+// yamlPipelines are the export pipelines of the YAML file: the span processors, the logger
+// provider and the meter provider. otelconf offers no hook around the exporters it creates,
+// so buildSDK takes them out of the parsed config and builds them here instead, the same way
+// otelconf v0.3.0 does, with every exporter running under Config.MaxConcurrentExports. The
+// rest (sampler, span limits, propagators) is still built by otelconf.
+type yamlPipelines struct {
+	resource       *otelconf.Resource
+	spanProcessors []otelconf.SpanProcessor
+	logs           *otelconf.LoggerProvider
+	metrics        *otelconf.MeterProvider
+}
+
+// takePipelines removes the export pipelines from conf, so otelconf does not build them
+func takePipelines(conf *otelconf.OpenTelemetryConfiguration) yamlPipelines {
+	if conf.Disabled != nil && *conf.Disabled {
+		return yamlPipelines{}
+	}
+	p := yamlPipelines{resource: conf.Resource, logs: conf.LoggerProvider, metrics: conf.MeterProvider}
+	if conf.TracerProvider != nil {
+		p.spanProcessors = conf.TracerProvider.Processors
+		conf.TracerProvider.Processors = nil
+	}
+	conf.LoggerProvider, conf.MeterProvider = nil, nil
+	return p
+}
+
+// pipelines are the built yamlPipelines. The span processors are shut down with the tracer
+// provider they are registered on; shutdown covers the logger and meter providers.
+type pipelines struct {
+	spanProcessors []sdktrace.SpanProcessor
+	logger         log.LoggerProvider
+	meter          metric.MeterProvider
+	shutdown       func(context.Context) error
+}
+
+func (p yamlPipelines) build(ctx context.Context, limit exportLimiter) (*pipelines, error) {
+	built := &pipelines{logger: lognoop.NewLoggerProvider(), meter: metricnoop.NewMeterProvider()}
+	// cleanup shuts down what was built when a later part fails
+	var cleanup []func(context.Context) error
+	fail := func(err error) (*pipelines, error) {
+		for _, shutdown := range cleanup {
+			err = errors.Join(err, shutdown(ctx))
+		}
+		return nil, err
+	}
+	res := yamlResource(p.resource)
+
+	for _, processor := range p.spanProcessors {
+		spanProcessor, err := yamlSpanProcessor(ctx, processor, limit)
+		if err != nil {
+			return fail(err)
+		}
+		built.spanProcessors = append(built.spanProcessors, spanProcessor)
+		cleanup = append(cleanup, spanProcessor.Shutdown)
+	}
+
+	var shutdowns []func(context.Context) error
+	if p.logs != nil {
+		opts := []sdklog.LoggerProviderOption{sdklog.WithResource(res)}
+		for _, processor := range p.logs.Processors {
+			logProcessor, err := yamlLogProcessor(ctx, processor, limit)
+			if err != nil {
+				return fail(err)
+			}
+			opts = append(opts, sdklog.WithProcessor(logProcessor))
+			cleanup = append(cleanup, logProcessor.Shutdown)
+		}
+		provider := sdklog.NewLoggerProvider(opts...)
+		built.logger = provider
+		shutdowns = append(shutdowns, provider.Shutdown)
+	}
+
+	if p.metrics != nil {
+		opts := []sdkmetric.Option{sdkmetric.WithResource(res)}
+		for _, reader := range p.metrics.Readers {
+			metricReader, err := yamlMetricReader(ctx, reader, limit)
+			if err != nil {
+				return fail(err)
+			}
+			opts = append(opts, sdkmetric.WithReader(metricReader))
+			cleanup = append(cleanup, metricReader.Shutdown)
+		}
+		for _, view := range p.metrics.Views {
+			metricView, err := yamlView(view)
+			if err != nil {
+				return fail(err)
+			}
+			opts = append(opts, sdkmetric.WithView(metricView))
+		}
+		provider := sdkmetric.NewMeterProvider(opts...)
+		built.meter = provider
+		shutdowns = append(shutdowns, provider.Shutdown)
+	}
+
+	built.shutdown = func(ctx context.Context) error {
+		var errs []error
+		for _, shutdown := range shutdowns {
+			errs = append(errs, shutdown(ctx))
+		}
+		return errors.Join(errs...)
+	}
+	return built, nil
+}
+
+// yamlResource builds the resource like otelconf, so the three signals share it
+func yamlResource(res *otelconf.Resource) *resource.Resource {
+	if res == nil {
+		return resource.Default()
+	}
+	var attrs []attribute.KeyValue
+	for _, attr := range res.Attributes {
+		attrs = append(attrs, resourceAttribute(attr.Name, attr.Value))
+	}
+	if res.SchemaUrl == nil {
+		return resource.NewSchemaless(attrs...)
+	}
+	return resource.NewWithAttributes(*res.SchemaUrl, attrs...)
+}
+
+func resourceAttribute(key string, value any) attribute.KeyValue {
+	switch v := value.(type) {
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int8:
+		return attribute.Int64(key, int64(v))
+	case int16:
+		return attribute.Int64(key, int64(v))
+	case int32:
+		return attribute.Int64(key, int64(v))
+	case int64:
+		return attribute.Int64(key, v)
+	case uint:
+		return attribute.String(key, strconv.FormatUint(uint64(v), 10))
+	case uint8:
+		return attribute.Int64(key, int64(v))
+	case uint16:
+		return attribute.Int64(key, int64(v))
+	case uint32:
+		return attribute.Int64(key, int64(v))
+	case uint64:
+		return attribute.String(key, strconv.FormatUint(v, 10))
+	case float32:
+		return attribute.Float64(key, float64(v))
+	case float64:
+		return attribute.Float64(key, v)
+	case string:
+		return attribute.String(key, v)
+	default:
+		return attribute.String(key, fmt.Sprint(v))
+	}
+}
+
+func yamlSpanProcessor(ctx context.Context, processor otelconf.SpanProcessor, limit exportLimiter) (sdktrace.SpanProcessor, error) {
+	switch {
+	case processor.Batch != nil && processor.Simple != nil:
+		return nil, errors.New("must not specify multiple span processor type")
+	case processor.Batch != nil:
+		opts, err := batchSpanOptions(processor.Batch)
+		if err != nil {
+			return nil, err
+		}
+		exporter, err := yamlSpanExporter(ctx, processor.Batch.Exporter)
+		if err != nil {
+			return nil, err
+		}
+		return sdktrace.NewBatchSpanProcessor(limitedSpanExporter{SpanExporter: exporter, limit: limit}, opts...), nil
+	case processor.Simple != nil:
+		exporter, err := yamlSpanExporter(ctx, processor.Simple.Exporter)
+		if err != nil {
+			return nil, err
+		}
+		return sdktrace.NewSimpleSpanProcessor(limitedSpanExporter{SpanExporter: exporter, limit: limit}), nil
+	}
+	return nil, errors.New("unsupported span processor type, must be one of simple or batch")
+}
+
+func batchSpanOptions(batch *otelconf.BatchSpanProcessor) ([]sdktrace.BatchSpanProcessorOption, error) {
+	var opts []sdktrace.BatchSpanProcessorOption
+	if batch.ExportTimeout != nil {
+		if *batch.ExportTimeout < 0 {
+			return nil, fmt.Errorf("invalid export timeout %d", *batch.ExportTimeout)
+		}
+		opts = append(opts, sdktrace.WithExportTimeout(milliseconds(*batch.ExportTimeout)))
+	}
+	if batch.MaxExportBatchSize != nil {
+		if *batch.MaxExportBatchSize < 0 {
+			return nil, fmt.Errorf("invalid batch size %d", *batch.MaxExportBatchSize)
+		}
+		opts = append(opts, sdktrace.WithMaxExportBatchSize(*batch.MaxExportBatchSize))
+	}
+	if batch.MaxQueueSize != nil {
+		if *batch.MaxQueueSize < 0 {
+			return nil, fmt.Errorf("invalid queue size %d", *batch.MaxQueueSize)
+		}
+		opts = append(opts, sdktrace.WithMaxQueueSize(*batch.MaxQueueSize))
+	}
+	if batch.ScheduleDelay != nil {
+		if *batch.ScheduleDelay < 0 {
+			return nil, fmt.Errorf("invalid schedule delay %d", *batch.ScheduleDelay)
+		}
+		opts = append(opts, sdktrace.WithBatchTimeout(milliseconds(*batch.ScheduleDelay)))
+	}
+	return opts, nil
+}
+
+func yamlSpanExporter(ctx context.Context, exporter otelconf.SpanExporter) (sdktrace.SpanExporter, error) {
+	if exporter.Console != nil && exporter.OTLP != nil {
+		return nil, errors.New("must not specify multiple exporters")
+	}
+	if exporter.Console != nil {
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	}
+	if exporter.OTLP == nil || exporter.OTLP.Protocol == nil {
+		return nil, errors.New("no valid span exporter")
+	}
+	otlp, err := newOTLPSettings(exporter.OTLP.Endpoint, exporter.OTLP.Insecure, exporter.OTLP.Compression, exporter.OTLP.Timeout,
+		exporter.OTLP.Headers, exporter.OTLP.HeadersList, exporter.OTLP.Certificate, exporter.OTLP.ClientCertificate, exporter.OTLP.ClientKey)
+	if err != nil {
+		return nil, err
+	}
+
+	switch *exporter.OTLP.Protocol {
+	case otlpProtocolHTTP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithTLSClientConfig(otlp.tls)}
+		if otlp.endpoint != nil {
+			opts = append(opts, otlptracehttp.WithEndpoint(otlp.endpoint.Host))
+			if otlp.endpoint.Scheme == "http" {
+				opts = append(opts, otlptracehttp.WithInsecure())
+			}
+			if otlp.endpoint.Path != "" {
+				opts = append(opts, otlptracehttp.WithURLPath(otlp.endpoint.Path))
+			}
+		}
+		if otlp.compression != "" {
+			compression := otlptracehttp.NoCompression
+			if otlp.compression == otlpCompressionGzip {
+				compression = otlptracehttp.GzipCompression
+			}
+			opts = append(opts, otlptracehttp.WithCompression(compression))
+		}
+		if otlp.timeout > 0 {
+			opts = append(opts, otlptracehttp.WithTimeout(otlp.timeout))
+		}
+		if len(otlp.headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(otlp.headers))
+		}
+		return otlptracehttp.New(ctx, opts...)
+	case otlpProtocolGRPC:
+		var opts []otlptracegrpc.Option
+		if otlp.endpoint != nil {
+			opts = append(opts, otlptracegrpc.WithEndpoint(otlp.grpcEndpoint))
+			if otlp.grpcInsecure {
+				opts = append(opts, otlptracegrpc.WithInsecure())
+			}
+		}
+		if otlp.compression == otlpCompressionGzip {
+			opts = append(opts, otlptracegrpc.WithCompressor(otlp.compression))
+		}
+		if otlp.timeout > 0 {
+			opts = append(opts, otlptracegrpc.WithTimeout(otlp.timeout))
+		}
+		if len(otlp.headers) > 0 {
+			opts = append(opts, otlptracegrpc.WithHeaders(otlp.headers))
+		}
+		if otlp.grpcTLS {
+			opts = append(opts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(otlp.tls)))
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	}
+	return nil, fmt.Errorf("unsupported protocol %q", *exporter.OTLP.Protocol)
+}
+
+func yamlLogProcessor(ctx context.Context, processor otelconf.LogRecordProcessor, limit exportLimiter) (sdklog.Processor, error) {
+	switch {
+	case processor.Batch != nil && processor.Simple != nil:
+		return nil, errors.New("must not specify multiple log processor type")
+	case processor.Batch != nil:
+		opts, err := batchLogOptions(processor.Batch)
+		if err != nil {
+			return nil, err
+		}
+		exporter, err := yamlLogExporter(ctx, processor.Batch.Exporter)
+		if err != nil {
+			return nil, err
+		}
+		return sdklog.NewBatchProcessor(limitedLogExporter{Exporter: exporter, limit: limit}, opts...), nil
+	case processor.Simple != nil:
+		exporter, err := yamlLogExporter(ctx, processor.Simple.Exporter)
+		if err != nil {
+			return nil, err
+		}
+		return sdklog.NewSimpleProcessor(limitedLogExporter{Exporter: exporter, limit: limit}), nil
+	}
+	return nil, errors.New("unsupported log processor type, must be one of simple or batch")
+}
+
+func batchLogOptions(batch *otelconf.BatchLogRecordProcessor) ([]sdklog.BatchProcessorOption, error) {
+	var opts []sdklog.BatchProcessorOption
+	if batch.ExportTimeout != nil {
+		if *batch.ExportTimeout < 0 {
+			return nil, fmt.Errorf("invalid export timeout %d", *batch.ExportTimeout)
+		}
+		opts = append(opts, sdklog.WithExportTimeout(milliseconds(*batch.ExportTimeout)))
+	}
+	if batch.MaxExportBatchSize != nil {
+		if *batch.MaxExportBatchSize < 0 {
+			return nil, fmt.Errorf("invalid batch size %d", *batch.MaxExportBatchSize)
+		}
+		opts = append(opts, sdklog.WithExportMaxBatchSize(*batch.MaxExportBatchSize))
+	}
+	if batch.MaxQueueSize != nil {
+		if *batch.MaxQueueSize < 0 {
+			return nil, fmt.Errorf("invalid queue size %d", *batch.MaxQueueSize)
+		}
+		opts = append(opts, sdklog.WithMaxQueueSize(*batch.MaxQueueSize))
+	}
+	if batch.ScheduleDelay != nil {
+		if *batch.ScheduleDelay < 0 {
+			return nil, fmt.Errorf("invalid schedule delay %d", *batch.ScheduleDelay)
+		}
+		opts = append(opts, sdklog.WithExportInterval(milliseconds(*batch.ScheduleDelay)))
+	}
+	return opts, nil
+}
+
+func yamlLogExporter(ctx context.Context, exporter otelconf.LogRecordExporter) (sdklog.Exporter, error) {
+	if exporter.Console != nil && exporter.OTLP != nil {
+		return nil, errors.New("must not specify multiple exporters")
+	}
+	if exporter.Console != nil {
+		return stdoutlog.New(stdoutlog.WithPrettyPrint())
+	}
+	if exporter.OTLP == nil || exporter.OTLP.Protocol == nil {
+		return nil, errors.New("no valid log exporter")
+	}
+	otlp, err := newOTLPSettings(exporter.OTLP.Endpoint, exporter.OTLP.Insecure, exporter.OTLP.Compression, exporter.OTLP.Timeout,
+		exporter.OTLP.Headers, exporter.OTLP.HeadersList, exporter.OTLP.Certificate, exporter.OTLP.ClientCertificate, exporter.OTLP.ClientKey)
+	if err != nil {
+		return nil, err
+	}
+
+	switch *exporter.OTLP.Protocol {
+	case otlpProtocolHTTP:
+		opts := []otlploghttp.Option{otlploghttp.WithTLSClientConfig(otlp.tls)}
+		if otlp.endpoint != nil {
+			opts = append(opts, otlploghttp.WithEndpoint(otlp.endpoint.Host))
+			if otlp.endpoint.Scheme == "http" {
+				opts = append(opts, otlploghttp.WithInsecure())
+			}
+			if otlp.endpoint.Path != "" {
+				opts = append(opts, otlploghttp.WithURLPath(otlp.endpoint.Path))
+			}
+		}
+		if otlp.compression != "" {
+			compression := otlploghttp.NoCompression
+			if otlp.compression == otlpCompressionGzip {
+				compression = otlploghttp.GzipCompression
+			}
+			opts = append(opts, otlploghttp.WithCompression(compression))
+		}
+		if otlp.timeout > 0 {
+			opts = append(opts, otlploghttp.WithTimeout(otlp.timeout))
+		}
+		if len(otlp.headers) > 0 {
+			opts = append(opts, otlploghttp.WithHeaders(otlp.headers))
+		}
+		return otlploghttp.New(ctx, opts...)
+	case otlpProtocolGRPC:
+		var opts []otlploggrpc.Option
+		if otlp.endpoint != nil {
+			opts = append(opts, otlploggrpc.WithEndpoint(otlp.grpcEndpoint))
+			if otlp.grpcInsecure {
+				opts = append(opts, otlploggrpc.WithInsecure())
+			}
+		}
+		if otlp.compression == otlpCompressionGzip {
+			opts = append(opts, otlploggrpc.WithCompressor(otlp.compression))
+		}
+		if otlp.timeout > 0 {
+			opts = append(opts, otlploggrpc.WithTimeout(otlp.timeout))
+		}
+		if len(otlp.headers) > 0 {
+			opts = append(opts, otlploggrpc.WithHeaders(otlp.headers))
+		}
+		if otlp.grpcTLS {
+			opts = append(opts, otlploggrpc.WithTLSCredentials(credentials.NewTLS(otlp.tls)))
+		}
+		return otlploggrpc.New(ctx, opts...)
+	}
+	return nil, fmt.Errorf("unsupported protocol %q", *exporter.OTLP.Protocol)
+}
+
+func yamlMetricReader(ctx context.Context, reader otelconf.MetricReader, limit exportLimiter) (sdkmetric.Reader, error) {
+	switch {
+	case reader.Periodic != nil && reader.Pull != nil:
+		return nil, errors.New("must not specify multiple metric reader type")
+	case reader.Periodic != nil:
+		var opts []sdkmetric.PeriodicReaderOption
+		if reader.Periodic.Interval != nil {
+			opts = append(opts, sdkmetric.WithInterval(milliseconds(*reader.Periodic.Interval)))
+		}
+		if reader.Periodic.Timeout != nil {
+			opts = append(opts, sdkmetric.WithTimeout(milliseconds(*reader.Periodic.Timeout)))
+		}
+		exporter, err := yamlMetricExporter(ctx, reader.Periodic.Exporter)
+		if err != nil {
+			return nil, err
+		}
+		return sdkmetric.NewPeriodicReader(limitedMetricExporter{Exporter: exporter, limit: limit}, opts...), nil
+	case reader.Pull != nil:
+		if reader.Pull.Exporter.Prometheus == nil {
+			return nil, errors.New("no valid metric exporter")
+		}
+		return prometheusReader(ctx, reader.Pull.Exporter.Prometheus)
+	}
+	return nil, errors.New("no valid metric reader")
+}
+
+func yamlMetricExporter(ctx context.Context, exporter otelconf.PushMetricExporter) (sdkmetric.Exporter, error) {
+	if exporter.Console != nil && exporter.OTLP != nil {
+		return nil, errors.New("must not specify multiple exporters")
+	}
+	if exporter.Console != nil {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return stdoutmetric.New(stdoutmetric.WithEncoder(encoder))
+	}
+	if exporter.OTLP == nil || exporter.OTLP.Protocol == nil {
+		return nil, errors.New("no valid metric exporter")
+	}
+	otlp, err := newOTLPSettings(exporter.OTLP.Endpoint, exporter.OTLP.Insecure, exporter.OTLP.Compression, exporter.OTLP.Timeout,
+		exporter.OTLP.Headers, exporter.OTLP.HeadersList, exporter.OTLP.Certificate, exporter.OTLP.ClientCertificate, exporter.OTLP.ClientKey)
+	if err != nil {
+		return nil, err
+	}
+	var temporality sdkmetric.TemporalitySelector
+	if exporter.OTLP.TemporalityPreference != nil {
+		switch *exporter.OTLP.TemporalityPreference {
+		case "delta":
+			temporality = deltaTemporality
+		case "cumulative":
+			temporality = sdkmetric.DefaultTemporalitySelector
+		case "lowmemory":
+			temporality = lowMemoryTemporality
+		default:
+			return nil, fmt.Errorf("unsupported temporality preference %q", *exporter.OTLP.TemporalityPreference)
+		}
+	}
+
+	switch *exporter.OTLP.Protocol {
+	case otlpProtocolHTTP:
+		opts := []otlpmetrichttp.Option{otlpmetrichttp.WithTLSClientConfig(otlp.tls)}
+		if otlp.endpoint != nil {
+			opts = append(opts, otlpmetrichttp.WithEndpoint(otlp.endpoint.Host))
+			if otlp.endpoint.Scheme == "http" {
+				opts = append(opts, otlpmetrichttp.WithInsecure())
+			}
+			if otlp.endpoint.Path != "" {
+				opts = append(opts, otlpmetrichttp.WithURLPath(otlp.endpoint.Path))
+			}
+		}
+		if otlp.compression != "" {
+			compression := otlpmetrichttp.NoCompression
+			if otlp.compression == otlpCompressionGzip {
+				compression = otlpmetrichttp.GzipCompression
+			}
+			opts = append(opts, otlpmetrichttp.WithCompression(compression))
+		}
+		if otlp.timeout > 0 {
+			opts = append(opts, otlpmetrichttp.WithTimeout(otlp.timeout))
+		}
+		if len(otlp.headers) > 0 {
+			opts = append(opts, otlpmetrichttp.WithHeaders(otlp.headers))
+		}
+		if temporality != nil {
+			opts = append(opts, otlpmetrichttp.WithTemporalitySelector(temporality))
+		}
+		return otlpmetrichttp.New(ctx, opts...)
+	case otlpProtocolGRPC:
+		var opts []otlpmetricgrpc.Option
+		if otlp.endpoint != nil {
+			opts = append(opts, otlpmetricgrpc.WithEndpoint(otlp.grpcEndpoint))
+			if otlp.grpcInsecure {
+				opts = append(opts, otlpmetricgrpc.WithInsecure())
+			}
+		}
+		if otlp.compression == otlpCompressionGzip {
+			opts = append(opts, otlpmetricgrpc.WithCompressor(otlp.compression))
+		}
+		if otlp.timeout > 0 {
+			opts = append(opts, otlpmetricgrpc.WithTimeout(otlp.timeout))
+		}
+		if len(otlp.headers) > 0 {
+			opts = append(opts, otlpmetricgrpc.WithHeaders(otlp.headers))
+		}
+		if temporality != nil {
+			opts = append(opts, otlpmetricgrpc.WithTemporalitySelector(temporality))
+		}
+		if otlp.grpcTLS {
+			opts = append(opts, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(otlp.tls)))
+		}
+		return otlpmetricgrpc.New(ctx, opts...)
+	}
+	return nil, fmt.Errorf("unsupported protocol %q", *exporter.OTLP.Protocol)
+}
+
+// deltaTemporality is the "delta" temporality_preference
+func deltaTemporality(kind sdkmetric.InstrumentKind) metricdata.Temporality {
+	switch kind {
+	case sdkmetric.InstrumentKindCounter, sdkmetric.InstrumentKindHistogram, sdkmetric.InstrumentKindObservableCounter:
+		return metricdata.DeltaTemporality
+	default:
+		return metricdata.CumulativeTemporality
+	}
+}
+
+// lowMemoryTemporality is the "lowmemory" temporality_preference
+func lowMemoryTemporality(kind sdkmetric.InstrumentKind) metricdata.Temporality {
+	switch kind {
+	case sdkmetric.InstrumentKindCounter, sdkmetric.InstrumentKindHistogram:
+		return metricdata.DeltaTemporality
+	default:
+		return metricdata.CumulativeTemporality
+	}
+}
+
+// prometheusReader serves the metrics on host:port/metrics until the reader is shut down
+func prometheusReader(ctx context.Context, config *otelconf.Prometheus) (sdkmetric.Reader, error) {
+	if config.Host == nil {
+		return nil, errors.New("host must be specified")
+	}
+	if config.Port == nil {
+		return nil, errors.New("port must be specified")
+	}
+
+	registry := prometheus.NewRegistry()
+	opts := []otelprometheus.Option{otelprometheus.WithRegisterer(registry)}
+	if config.WithoutScopeInfo != nil && *config.WithoutScopeInfo {
+		opts = append(opts, otelprometheus.WithoutScopeInfo())
+	}
+	if config.WithoutTypeSuffix != nil && *config.WithoutTypeSuffix {
+		opts = append(opts, otelprometheus.WithoutCounterSuffixes())
+	}
+	if config.WithoutUnits != nil && *config.WithoutUnits {
+		opts = append(opts, otelprometheus.WithoutUnits())
+	}
+	if config.WithResourceConstantLabels != nil {
+		filter, err := includeExcludeFilter(config.WithResourceConstantLabels)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, otelprometheus.WithResourceAsConstantLabels(filter))
+	}
+	reader, err := otelprometheus.New(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating otel prometheus exporter: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry}))
+	server := &http.Server{
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		IdleTimeout:  120 * time.Second,
+		Handler:      mux,
+	}
+	// The host may be written "[::1]" or "::1"
+	host := *config.Host
+	if len(host) > 2 && host[0] == '[' && host[len(host)-1] == ']' {
+		host = host[1 : len(host)-1]
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(*config.Port))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("binding address %s for Prometheus exporter: %w", addr, err), reader.Shutdown(ctx))
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			otel.Handle(fmt.Errorf("the Prometheus HTTP server exited unexpectedly: %w", err))
+		}
+	}()
+	return readerWithServer{Reader: reader, server: server}, nil
+}
+
+type readerWithServer struct {
+	sdkmetric.Reader
+	server *http.Server
+}
+
+func (r readerWithServer) Shutdown(ctx context.Context) error {
+	return errors.Join(r.Reader.Shutdown(ctx), r.server.Shutdown(ctx))
+}
+
+func yamlView(view otelconf.View) (sdkmetric.View, error) {
+	if view.Selector == nil {
+		return nil, errors.New("view: no selector provided")
+	}
+	kind, err := instrumentKind(view.Selector.InstrumentType)
+	if err != nil {
+		return nil, fmt.Errorf("view_selector: %w", err)
+	}
+	instrument := sdkmetric.Instrument{
+		Name: stringOrEmpty(view.Selector.InstrumentName),
+		Unit: stringOrEmpty(view.Selector.Unit),
+		Kind: kind,
+		Scope: instrumentation.Scope{
+			Name:      stringOrEmpty(view.Selector.MeterName),
+			Version:   stringOrEmpty(view.Selector.MeterVersion),
+			SchemaURL: stringOrEmpty(view.Selector.MeterSchemaUrl),
+		},
+	}
+	if instrument.Name == "" && instrument.Unit == "" && instrument.Kind == 0 && instrument.Scope == (instrumentation.Scope{}) {
+		return nil, errors.New("view_selector: empty selector not supporter")
+	}
+
+	var stream sdkmetric.Stream
+	if view.Stream != nil {
+		filter, err := includeExcludeFilter(view.Stream.AttributeKeys)
+		if err != nil {
+			return nil, err
+		}
+		stream = sdkmetric.Stream{
+			Name:            stringOrEmpty(view.Stream.Name),
+			Description:     stringOrEmpty(view.Stream.Description),
+			Aggregation:     viewAggregation(view.Stream.Aggregation),
+			AttributeFilter: filter,
+		}
+	}
+	return sdkmetric.NewView(instrument, stream), nil
+}
+
+func instrumentKind(instrumentType *otelconf.ViewSelectorInstrumentType) (sdkmetric.InstrumentKind, error) {
+	if instrumentType == nil {
+		return 0, nil
+	}
+	switch *instrumentType {
+	case otelconf.ViewSelectorInstrumentTypeCounter:
+		return sdkmetric.InstrumentKindCounter, nil
+	case otelconf.ViewSelectorInstrumentTypeUpDownCounter:
+		return sdkmetric.InstrumentKindUpDownCounter, nil
+	case otelconf.ViewSelectorInstrumentTypeHistogram:
+		return sdkmetric.InstrumentKindHistogram, nil
+	case otelconf.ViewSelectorInstrumentTypeObservableCounter:
+		return sdkmetric.InstrumentKindObservableCounter, nil
+	case otelconf.ViewSelectorInstrumentTypeObservableUpDownCounter:
+		return sdkmetric.InstrumentKindObservableUpDownCounter, nil
+	case otelconf.ViewSelectorInstrumentTypeObservableGauge:
+		return sdkmetric.InstrumentKindObservableGauge, nil
+	}
+	return 0, errors.New("instrument_type: invalid value")
+}
+
+func viewAggregation(aggregation *otelconf.ViewStreamAggregation) sdkmetric.Aggregation {
+	switch {
+	case aggregation == nil, aggregation.Default != nil:
+		return nil
+	case aggregation.Base2ExponentialBucketHistogram != nil:
+		return sdkmetric.AggregationBase2ExponentialHistogram{
+			MaxSize:  int32OrZero(aggregation.Base2ExponentialBucketHistogram.MaxSize),
+			MaxScale: int32OrZero(aggregation.Base2ExponentialBucketHistogram.MaxScale),
+			NoMinMax: !isSet(aggregation.Base2ExponentialBucketHistogram.RecordMinMax),
+		}
+	case aggregation.Drop != nil:
+		return sdkmetric.AggregationDrop{}
+	case aggregation.ExplicitBucketHistogram != nil:
+		return sdkmetric.AggregationExplicitBucketHistogram{
+			Boundaries: aggregation.ExplicitBucketHistogram.Boundaries,
+			NoMinMax:   !isSet(aggregation.ExplicitBucketHistogram.RecordMinMax),
+		}
+	case aggregation.LastValue != nil:
+		return sdkmetric.AggregationLastValue{}
+	case aggregation.Sum != nil:
+		return sdkmetric.AggregationSum{}
+	}
+	return nil
+}
+
+// includeExcludeFilter keeps the attributes of the included list (all when it is empty)
+// except those of the excluded list
+func includeExcludeFilter(lists *otelconf.IncludeExclude) (attribute.Filter, error) {
+	if lists == nil {
+		return func(attribute.KeyValue) bool { return true }, nil
+	}
+	included := make(map[attribute.Key]bool, len(lists.Included))
+	for _, key := range lists.Included {
+		included[attribute.Key(key)] = true
+	}
+	excluded := make(map[attribute.Key]bool, len(lists.Excluded))
+	for _, key := range lists.Excluded {
+		if included[attribute.Key(key)] {
+			return nil, fmt.Errorf("attribute cannot be in both include and exclude list: %s", key)
+		}
+		excluded[attribute.Key(key)] = true
+	}
+	return func(kv attribute.KeyValue) bool {
+		if excluded[kv.Key] {
+			return false
+		}
+		return len(included) == 0 || included[kv.Key]
+	}, nil
+}
+
+const (
+	otlpProtocolHTTP    = "http/protobuf"
+	otlpProtocolGRPC    = "grpc"
+	otlpCompressionGzip = "gzip"
+	otlpCompressionNone = "none"
+)
+
+// otlpSettings are the OTLP exporter fields shared by the three signals, parsed once
+type otlpSettings struct {
+	endpoint     *url.URL // nil when the YAML sets none
+	grpcEndpoint string   // host:port, or the endpoint as written when it has no scheme
+	grpcInsecure bool
+	grpcTLS      bool // a certificate is set; the HTTP exporters always get tls
+	tls          *tls.Config
+	compression  string // empty when unset
+	timeout      time.Duration
+	headers      map[string]string
+}
+
+func newOTLPSettings(endpoint *string, insecure *bool, compression *string, timeout *int,
+	headers []otelconf.NameStringValuePair, headersList *string, caFile, certFile, keyFile *string) (*otlpSettings, error) {
+	settings := &otlpSettings{grpcTLS: caFile != nil || certFile != nil || keyFile != nil}
+	if endpoint != nil {
+		parsed, err := url.ParseRequestURI(*endpoint)
+		if err != nil {
+			return nil, err
+		}
+		settings.endpoint = parsed
+		// Without a scheme (e.g. localhost:4317) the host is empty and the endpoint is used as is
+		settings.grpcEndpoint = parsed.Host
+		if parsed.Host == "" {
+			settings.grpcEndpoint = *endpoint
+		}
+		settings.grpcInsecure = parsed.Scheme == "http" || (parsed.Scheme != "https" && isSet(insecure))
+	}
+	if compression != nil {
+		switch *compression {
+		case otlpCompressionGzip, otlpCompressionNone:
+			settings.compression = *compression
+		default:
+			return nil, fmt.Errorf("unsupported compression %q", *compression)
+		}
+	}
+	if timeout != nil && *timeout > 0 {
+		settings.timeout = milliseconds(*timeout)
+	}
+
+	// Headers take precedence over HeadersList
+	settings.headers = map[string]string{}
+	if headersList != nil {
+		members, err := baggage.Parse(*headersList)
+		if err != nil {
+			return nil, fmt.Errorf("invalid headers list: %w", err)
+		}
+		for _, member := range members.Members() {
+			settings.headers[member.Key()] = member.Value()
+		}
+	}
+	for _, header := range headers {
+		if header.Value != nil {
+			settings.headers[header.Name] = *header.Value
+		}
+	}
+
+	settings.tls = &tls.Config{}
+	if caFile != nil {
+		ca, err := os.ReadFile(*caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("could not create certificate authority chain from certificate")
+		}
+		settings.tls.RootCAs = pool
+	}
+	if certFile != nil {
+		if keyFile == nil {
+			return nil, errors.New("client certificate was provided but no client key was provided")
+		}
+		cert, err := tls.LoadX509KeyPair(*certFile, *keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not use client certificate: %w", err)
+		}
+		settings.tls.Certificates = []tls.Certificate{cert}
+	}
+	return settings, nil
+}
+
+func milliseconds(ms int) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
+
+func isSet(b *bool) bool {
+	return b != nil && *b
+}
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func int32OrZero(i *int) int32 {
+	if i == nil {
+		return 0
+	}
+	return int32(max(min(*i, math.MaxInt32), math.MinInt32))
+}
@@ -0,0 +1,123 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+)
+
+// processStats is a snapshot of the process resources. Each group is only reported when the
+// platform exposes it.
+type processStats struct {
+	cpuSeconds float64
+	hasCPU     bool
+
+	residentBytes int64
+	virtualBytes  int64
+	hasMemory     bool
+
+	openFDs int64
+	maxFDs  int64
+	hasFDs  bool
+}
+
+// processStart approximates the process start time where it cannot be read from the OS: the
+// package is initialized right after the process starts
+var processStart = time.Now()
+
+// RegisterProcessMetrics provides process metrics named like the Prometheus process collector:
+// process_cpu_seconds_total, process_resident_memory_bytes, process_virtual_memory_bytes,
+// process_open_fds, process_max_fds and process_start_time_seconds. They are read from /proc
+// on Linux; elsewhere only process_start_time_seconds is reported (approximated by the package
+// initialization time) and the other metrics are omitted without an error. Complements
+// RegisterRuntimeMetrics. The metrics are registered once per client; later calls return the
+// first result.
+func (c *TelemetryClient) RegisterProcessMetrics() error {
+	c.processMetricsOnce.Do(func() {
+		c.processMetricsErr = c.registerProcessMetrics()
+	})
+	return c.processMetricsErr
+}
+
+func (c *TelemetryClient) registerProcessMetrics() error {
+	cpu, err := c.Meter.Float64ObservableCounter(
+		"process_cpu_seconds_total",
+		metric.WithDescription("Total user and system CPU time spent in seconds"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create process cpu counter: %w", err)
+	}
+
+	resident, err := c.Meter.Int64ObservableGauge(
+		"process_resident_memory_bytes",
+		metric.WithDescription("Resident memory size in bytes"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create resident memory gauge: %w", err)
+	}
+
+	virtual, err := c.Meter.Int64ObservableGauge(
+		"process_virtual_memory_bytes",
+		metric.WithDescription("Virtual memory size in bytes"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create virtual memory gauge: %w", err)
+	}
+
+	openFDs, err := c.Meter.Int64ObservableGauge(
+		"process_open_fds",
+		metric.WithDescription("Number of open file descriptors"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create open fds gauge: %w", err)
+	}
+
+	maxFDs, err := c.Meter.Int64ObservableGauge(
+		"process_max_fds",
+		metric.WithDescription("Maximum number of open file descriptors"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create max fds gauge: %w", err)
+	}
+
+	startTime, err := c.Meter.Float64ObservableGauge(
+		"process_start_time_seconds",
+		metric.WithDescription("Start time of the process since unix epoch in seconds"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create process start time gauge: %w", err)
+	}
+
+	// The start time does not change, read it once
+	start := processStartTime()
+	_, err = c.Meter.RegisterCallback(func(_ context.Context, observer metric.Observer) error {
+		observer.ObserveFloat64(startTime, float64(start.UnixNano())/1e9)
+
+		stats := readProcessStats()
+		if stats.hasCPU {
+			observer.ObserveFloat64(cpu, stats.cpuSeconds)
+		}
+		if stats.hasMemory {
+			observer.ObserveInt64(resident, stats.residentBytes)
+			observer.ObserveInt64(virtual, stats.virtualBytes)
+		}
+		if stats.hasFDs {
+			observer.ObserveInt64(openFDs, stats.openFDs)
+			observer.ObserveInt64(maxFDs, stats.maxFDs)
+		}
+		return nil
+	}, cpu, resident, virtual, openFDs, maxFDs, startTime)
+	if err != nil {
+		return fmt.Errorf("failed to register process metrics callback: %w", err)
+	}
+
+	return nil
+}
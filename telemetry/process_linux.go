@@ -0,0 +1,89 @@
+package telemetry
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// userHZ is the clock tick of the /proc times; Linux fixes it at 100 for userspace
+const userHZ = 100
+
+// readProcessStats reads /proc/self. A file that cannot be read leaves its group unreported.
+func readProcessStats() processStats {
+	var stats processStats
+
+	if fields := procSelfStat(); len(fields) > 21 {
+		utime, errU := strconv.ParseUint(fields[11], 10, 64)
+		stime, errS := strconv.ParseUint(fields[12], 10, 64)
+		if errU == nil && errS == nil {
+			stats.cpuSeconds = float64(utime+stime) / userHZ
+			stats.hasCPU = true
+		}
+		vsize, errV := strconv.ParseInt(fields[20], 10, 64)
+		rss, errR := strconv.ParseInt(fields[21], 10, 64)
+		if errV == nil && errR == nil {
+			stats.virtualBytes = vsize
+			stats.residentBytes = rss * int64(os.Getpagesize())
+			stats.hasMemory = true
+		}
+	}
+
+	if fds, err := os.ReadDir("/proc/self/fd"); err == nil {
+		var limit syscall.Rlimit
+		if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err == nil {
+			stats.openFDs, stats.maxFDs = int64(len(fds)), int64(limit.Cur)
+			stats.hasFDs = true
+		}
+	}
+
+	return stats
+}
+
+// procSelfStat returns the fields of /proc/self/stat after the command name, which may contain
+// spaces: fields[0] is the state (field 3 in proc(5))
+func procSelfStat() []string {
+	data, err := os.ReadFile("/proc/self/stat")
+	if err != nil {
+		return nil
+	}
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return nil
+	}
+	return strings.Fields(string(data[end+1:]))
+}
+
+// processStartTime adds the start time in /proc/self/stat (clock ticks since boot) to the boot
+// time in /proc/stat, falling back to the package initialization time
+func processStartTime() time.Time {
+	fields := procSelfStat()
+	if len(fields) <= 19 {
+		return processStart
+	}
+	ticks, err := strconv.ParseUint(fields[19], 10, 64)
+	if err != nil {
+		return processStart
+	}
+
+	file, err := os.Open("/proc/stat")
+	if err != nil {
+		return processStart
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "btime "); ok {
+			bootTime, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				break
+			}
+			return time.Unix(bootTime, 0).Add(time.Duration(ticks) * time.Second / userHZ)
+		}
+	}
+	return processStart
+}
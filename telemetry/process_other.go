@@ -0,0 +1,14 @@
+//go:build !linux
+
+package telemetry
+
+import "time"
+
+// readProcessStats reports nothing outside Linux, the process metrics are omitted
+func readProcessStats() processStats {
+	return processStats{}
+}
+
+func processStartTime() time.Time {
+	return processStart
+}
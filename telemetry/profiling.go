@@ -0,0 +1,24 @@
+package telemetry
+
+import (
+	"context"
+	"runtime/pprof"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithProfilingLabels attaches the active trace/span IDs as pprof labels to the context.
+// The labels only apply to CPU samples once set on the goroutine, so use the returned
+// context with pprof.Do (or pprof.SetGoroutineLabels) right after starting the span.
+func (c *TelemetryClient) WithProfilingLabels(ctx context.Context) context.Context {
+	ctx = contextOrBackground(ctx)
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return ctx
+	}
+
+	return pprof.WithLabels(ctx, pprof.Labels(
+		"trace_id", spanContext.TraceID().String(),
+		"span_id", spanContext.SpanID().String(),
+	))
+}
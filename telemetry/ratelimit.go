@@ -0,0 +1,159 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// rateLimitHeaders are the remaining/limit header pairs read by RecordRateLimitHeaders, in order
+// of preference: the de facto X-RateLimit-* headers (GitHub, Twitter...) and the IETF RateLimit
+// fields draft
+var rateLimitHeaders = [][2]string{
+	{"X-RateLimit-Remaining", "X-RateLimit-Limit"},
+	{"RateLimit-Remaining", "RateLimit-Limit"},
+}
+
+type rateLimitMetrics struct {
+	mu        sync.Mutex
+	remaining map[string]int64 // last value per provider, read by the gauge callback
+	limit     map[string]int64
+}
+
+// RecordRateLimit records the rate limit budget reported by a third-party API: remaining calls
+// in the external_rate_limit_remaining gauge and the window limit in external_rate_limit_total,
+// both labeled with provider (e.g. "github"), and as rate_limit.* attributes of the active span.
+// A limit <= 0 (unknown) only records remaining. Alert on remaining/total to act before the
+// provider starts rejecting calls. Past 100 distinct providers the metrics record "other".
+func (c *TelemetryClient) RecordRateLimit(ctx context.Context, provider string, remaining, limit int64) {
+	ctx = contextOrBackground(ctx)
+	attrs := []attribute.KeyValue{
+		attribute.String("rate_limit.provider", provider),
+		attribute.Int64("rate_limit.remaining", remaining),
+	}
+	if limit > 0 {
+		attrs = append(attrs, attribute.Int64("rate_limit.limit", limit))
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attrs...)
+
+	provider = c.labelGuard.value("rate_limit.provider", provider)
+	metrics := c.rateLimitInstruments()
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	metrics.remaining[provider] = remaining
+	if limit > 0 {
+		metrics.limit[provider] = limit
+	}
+}
+
+// RecordRateLimitHeaders calls RecordRateLimit with the values of the X-RateLimit-Remaining and
+// X-RateLimit-Limit headers, or of the standard RateLimit-Remaining and RateLimit-Limit, and
+// reports whether a remaining header was found. The limit header is optional.
+func (c *TelemetryClient) RecordRateLimitHeaders(ctx context.Context, provider string, header http.Header) bool {
+	for _, names := range rateLimitHeaders {
+		remaining, ok := parseRateLimitHeader(header.Get(names[0]))
+		if !ok {
+			continue
+		}
+		limit, _ := parseRateLimitHeader(header.Get(names[1]))
+		c.RecordRateLimit(ctx, provider, remaining, limit)
+		return true
+	}
+	return false
+}
+
+// parseRateLimitHeader reads the leading integer of a header value, skipping the parameters of
+// the IETF draft (e.g. "100, 100;w=60")
+func parseRateLimitHeader(value string) (int64, bool) {
+	if end := strings.IndexAny(value, ",;"); end >= 0 {
+		value = value[:end]
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	return n, err == nil
+}
+
+// RateLimitTransport wraps base (http.DefaultTransport when nil) so every response of provider
+// carrying rate limit headers is recorded with RecordRateLimitHeaders, annotating the span of
+// the request context. Responses without the headers are left alone.
+//
+//	githubClient := &http.Client{Transport: client.RateLimitTransport("github", nil)}
+func (c *TelemetryClient) RateLimitTransport(provider string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &rateLimitTransport{client: c, provider: provider, base: base}
+}
+
+type rateLimitTransport struct {
+	client   *TelemetryClient
+	provider string
+	base     http.RoundTripper
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		t.client.RecordRateLimitHeaders(req.Context(), t.provider, resp.Header)
+	}
+	return resp, err
+}
+
+// rateLimitInstruments creates the rate limit gauges on first use
+func (c *TelemetryClient) rateLimitInstruments() *rateLimitMetrics {
+	c.rateLimitMetricsOnce.Do(func() {
+		metrics, err := newRateLimitMetrics(c.Meter)
+		if err != nil {
+			c.Logger.Error("failed to create rate limit metrics, falling back to no-op", "error", err)
+			metrics, _ = newRateLimitMetrics(noop.NewMeterProvider().Meter(""))
+		}
+		c.rateLimitMetrics = metrics
+	})
+	return c.rateLimitMetrics
+}
+
+func newRateLimitMetrics(meter metric.Meter) (*rateLimitMetrics, error) {
+	m := &rateLimitMetrics{remaining: map[string]int64{}, limit: map[string]int64{}}
+
+	remaining, err := meter.Int64ObservableGauge(
+		"external_rate_limit_remaining",
+		metric.WithDescription("Calls left in the current rate limit window of an external API"),
+		metric.WithUnit("{call}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rate limit remaining gauge: %w", err)
+	}
+
+	total, err := meter.Int64ObservableGauge(
+		"external_rate_limit_total",
+		metric.WithDescription("Calls allowed per rate limit window of an external API"),
+		metric.WithUnit("{call}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rate limit total gauge: %w", err)
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, observer metric.Observer) error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for provider, value := range m.remaining {
+			observer.ObserveInt64(remaining, value, metric.WithAttributes(attribute.String("provider", provider)))
+		}
+		for provider, value := range m.limit {
+			observer.ObserveInt64(total, value, metric.WithAttributes(attribute.String("provider", provider)))
+		}
+		return nil
+	}, remaining, total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register rate limit callback: %w", err)
+	}
+
+	return m, nil
+}
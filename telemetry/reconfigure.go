@@ -0,0 +1,643 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
+	logembedded "go.opentelemetry.io/otel/log/embedded"
+	"go.opentelemetry.io/otel/log/global"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/embedded"
+	"go.opentelemetry.io/otel/trace"
+	traceembedded "go.opentelemetry.io/otel/trace/embedded"
+)
+
+// Reconfigure rebuilds the SDK, and so the exporters, from config and shuts down the previous
+// one, keeping the client, its Tracer, Meter and Logger and every instrument created from them.
+// Use it to rotate the collector endpoint or credentials without a restart.
+//
+// Only these fields are applied: ConfigPath (the YAML file is read again), MetricReaderFiles,
+// OTLPTLS, TracesEnabled, MetricsEnabled, ParentSampling, RouteSampling and
+// RouteSamplingDefault. The other fields keep their NewClient values: they are baked into the
+// logger, the middleware or the resource and need a restart.
+//
+// The new SDK is built before the old one is touched, so on a setup error the client keeps
+// exporting as before. Cumulative metrics restart from zero with the new SDK.
+func (c *TelemetryClient) Reconfigure(ctx context.Context, config Config) error {
+	c.sdkMu.Lock()
+	defer c.sdkMu.Unlock()
+
+	next := c.config
+	next.ConfigPath = config.ConfigPath
+	next.MetricReaderFiles = config.MetricReaderFiles
+	next.OTLPTLS = config.OTLPTLS
+	next.TracesEnabled = config.TracesEnabled
+	next.MetricsEnabled = config.MetricsEnabled
+	next.ParentSampling = config.ParentSampling
+	next.RouteSampling = config.RouteSampling
+	next.RouteSamplingDefault = config.RouteSamplingDefault
+
+	if err := next.Validate(); err != nil {
+		return err
+	}
+	state, err := buildSDK(ctx, next)
+	if err != nil {
+		// buildSDK replaced the globals before failing
+		c.providers.install()
+		return fmt.Errorf("failed to reconfigure telemetry: %w", err)
+	}
+	// Config.TracerProvider is kept, and already has the processor
+	if state.tracerProvider != nil && state.tracerProvider != c.sdk.tracerProvider {
+		state.tracerProvider.RegisterSpanProcessor(dynamicAttrsProcessor{dynamic: c.dynamicAttrs, keys: c.keys})
+	}
+
+	swapErr := c.providers.swap(state)
+	c.providers.install()
+
+	previous := c.sdk
+	c.sdk, c.config = state, next
+	if err := previous.shutdown(ctx); err != nil {
+		return errors.Join(swapErr, fmt.Errorf("failed to shut down previous SDK: %w", err))
+	}
+	return swapErr
+}
+
+// swapProviders forward to the providers of the current SDK, so the tracers, meters, loggers
+// and instruments handed out before Reconfigure keep working with the new exporters
+type swapProviders struct {
+	tracer *swapTracerProvider
+	meter  *swapMeterProvider
+	logger *swapLoggerProvider
+}
+
+func newSwapProviders(state *sdkState) *swapProviders {
+	return &swapProviders{
+		tracer: &swapTracerProvider{current: state.tracer, tracers: map[scopeKey]*swapTracer{}},
+		meter:  &swapMeterProvider{current: state.meter, meters: map[scopeKey]*swapMeter{}},
+		logger: &swapLoggerProvider{current: state.logger, loggers: map[scopeKey]*swapLogger{}},
+	}
+}
+
+// install sets the swap providers as the global providers
+func (p *swapProviders) install() {
+	otel.SetTracerProvider(p.tracer)
+	otel.SetMeterProvider(p.meter)
+	global.SetLoggerProvider(p.logger)
+}
+
+func (p *swapProviders) swap(state *sdkState) error {
+	p.tracer.swap(state.tracer)
+	p.logger.swap(state.logger)
+	return p.meter.swap(state.meter)
+}
+
+// scopeKey identifies the tracers, meters and loggers a provider hands out
+type scopeKey struct {
+	name      string
+	version   string
+	schemaURL string
+	attrs     attribute.Distinct
+}
+
+func newScopeKey(name, version, schemaURL string, attrs attribute.Set) scopeKey {
+	return scopeKey{name: name, version: version, schemaURL: schemaURL, attrs: attrs.Equivalent()}
+}
+
+type swapTracerProvider struct {
+	traceembedded.TracerProvider
+
+	mu      sync.Mutex
+	current trace.TracerProvider
+	tracers map[scopeKey]*swapTracer
+}
+
+func (p *swapTracerProvider) Tracer(name string, opts ...trace.TracerOption) trace.Tracer {
+	cfg := trace.NewTracerConfig(opts...)
+	key := newScopeKey(name, cfg.InstrumentationVersion(), cfg.SchemaURL(), cfg.InstrumentationAttributes())
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if t, ok := p.tracers[key]; ok {
+		return t
+	}
+	t := &swapTracer{name: name, opts: opts}
+	t.store(p.current)
+	p.tracers[key] = t
+	return t
+}
+
+func (p *swapTracerProvider) swap(provider trace.TracerProvider) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = provider
+	for _, t := range p.tracers {
+		t.store(provider)
+	}
+}
+
+type swapTracer struct {
+	traceembedded.Tracer
+	name    string
+	opts    []trace.TracerOption
+	current atomic.Pointer[trace.Tracer]
+}
+
+func (t *swapTracer) store(provider trace.TracerProvider) {
+	tracer := provider.Tracer(t.name, t.opts...)
+	t.current.Store(&tracer)
+}
+
+func (t *swapTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return (*t.current.Load()).Start(ctx, name, opts...)
+}
+
+type swapLoggerProvider struct {
+	logembedded.LoggerProvider
+
+	mu      sync.Mutex
+	current log.LoggerProvider
+	loggers map[scopeKey]*swapLogger
+}
+
+func (p *swapLoggerProvider) Logger(name string, opts ...log.LoggerOption) log.Logger {
+	cfg := log.NewLoggerConfig(opts...)
+	key := newScopeKey(name, cfg.InstrumentationVersion(), cfg.SchemaURL(), cfg.InstrumentationAttributes())
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if l, ok := p.loggers[key]; ok {
+		return l
+	}
+	l := &swapLogger{name: name, opts: opts}
+	l.store(p.current)
+	p.loggers[key] = l
+	return l
+}
+
+func (p *swapLoggerProvider) swap(provider log.LoggerProvider) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = provider
+	for _, l := range p.loggers {
+		l.store(provider)
+	}
+}
+
+type swapLogger struct {
+	logembedded.Logger
+	name    string
+	opts    []log.LoggerOption
+	current atomic.Pointer[log.Logger]
+}
+
+func (l *swapLogger) store(provider log.LoggerProvider) {
+	logger := provider.Logger(l.name, l.opts...)
+	l.current.Store(&logger)
+}
+
+func (l *swapLogger) Emit(ctx context.Context, record log.Record) {
+	(*l.current.Load()).Emit(ctx, record)
+}
+
+func (l *swapLogger) Enabled(ctx context.Context, param log.EnabledParameters) bool {
+	return (*l.current.Load()).Enabled(ctx, param)
+}
+
+// swapMeterProvider keeps how every instrument and callback was created, to create them again
+// on the meters of the next provider
+type swapMeterProvider struct {
+	embedded.MeterProvider
+
+	mu      sync.Mutex // guards current, meters and the instruments and callbacks of each meter
+	current metric.MeterProvider
+	meters  map[scopeKey]*swapMeter
+}
+
+func (p *swapMeterProvider) Meter(name string, opts ...metric.MeterOption) metric.Meter {
+	cfg := metric.NewMeterConfig(opts...)
+	key := newScopeKey(name, cfg.InstrumentationVersion(), cfg.SchemaURL(), cfg.InstrumentationAttributes())
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if m, ok := p.meters[key]; ok {
+		return m
+	}
+	m := &swapMeter{provider: p, name: name, opts: opts}
+	m.current = p.current.Meter(name, opts...)
+	p.meters[key] = m
+	return m
+}
+
+// swap moves every meter to provider: instruments first, then the callbacks observing them
+func (p *swapMeterProvider) swap(provider metric.MeterProvider) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = provider
+
+	var errs []error
+	for _, m := range p.meters {
+		m.current = provider.Meter(m.name, m.opts...)
+		for _, instrument := range m.instruments {
+			if err := instrument.swap(m.current); err != nil {
+				errs = append(errs, fmt.Errorf("failed to recreate instrument of meter %q: %w", m.name, err))
+			}
+		}
+		for _, registration := range m.callbacks {
+			if err := registration.register(m.current); err != nil {
+				errs = append(errs, fmt.Errorf("failed to register callback of meter %q: %w", m.name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type swapMeter struct {
+	embedded.Meter
+	provider *swapMeterProvider
+	name     string
+	opts     []metric.MeterOption
+
+	current     metric.Meter
+	instruments []swappable
+	callbacks   []*swapRegistration
+}
+
+type swappable interface {
+	swap(meter metric.Meter) error
+}
+
+// swapped holds the instrument created by create on the current meter
+type swapped[T any] struct {
+	create  func(metric.Meter) (T, error)
+	current atomic.Pointer[T]
+}
+
+func (s *swapped[T]) swap(meter metric.Meter) error {
+	instrument, err := s.create(meter)
+	if err != nil {
+		return err
+	}
+	s.current.Store(&instrument)
+	return nil
+}
+
+func (s *swapped[T]) load() T {
+	return *s.current.Load()
+}
+
+// addSwapped creates an instrument on the current meter and keeps create for the next swap
+func addSwapped[T, I any](m *swapMeter, create func(metric.Meter) (T, error), wrap func(T, *swapped[T]) I) (I, error) {
+	m.provider.mu.Lock()
+	defer m.provider.mu.Unlock()
+
+	instrument, err := create(m.current)
+	if err != nil {
+		var zero I
+		return zero, err
+	}
+	s := &swapped[T]{create: create}
+	s.current.Store(&instrument)
+	m.instruments = append(m.instruments, s)
+	return wrap(instrument, s), nil
+}
+
+func (m *swapMeter) Int64Counter(name string, options ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	return addSwapped(m,
+		func(meter metric.Meter) (metric.Int64Counter, error) { return meter.Int64Counter(name, options...) },
+		func(_ metric.Int64Counter, s *swapped[metric.Int64Counter]) metric.Int64Counter {
+			return &swapInt64Counter{swapped: s}
+		},
+	)
+}
+
+func (m *swapMeter) Int64UpDownCounter(name string, options ...metric.Int64UpDownCounterOption) (metric.Int64UpDownCounter, error) {
+	return addSwapped(m,
+		func(meter metric.Meter) (metric.Int64UpDownCounter, error) {
+			return meter.Int64UpDownCounter(name, options...)
+		},
+		func(_ metric.Int64UpDownCounter, s *swapped[metric.Int64UpDownCounter]) metric.Int64UpDownCounter {
+			return &swapInt64UpDownCounter{swapped: s}
+		},
+	)
+}
+
+func (m *swapMeter) Int64Histogram(name string, options ...metric.Int64HistogramOption) (metric.Int64Histogram, error) {
+	return addSwapped(m,
+		func(meter metric.Meter) (metric.Int64Histogram, error) { return meter.Int64Histogram(name, options...) },
+		func(_ metric.Int64Histogram, s *swapped[metric.Int64Histogram]) metric.Int64Histogram {
+			return &swapInt64Histogram{swapped: s}
+		},
+	)
+}
+
+func (m *swapMeter) Int64Gauge(name string, options ...metric.Int64GaugeOption) (metric.Int64Gauge, error) {
+	return addSwapped(m,
+		func(meter metric.Meter) (metric.Int64Gauge, error) { return meter.Int64Gauge(name, options...) },
+		func(_ metric.Int64Gauge, s *swapped[metric.Int64Gauge]) metric.Int64Gauge {
+			return &swapInt64Gauge{swapped: s}
+		},
+	)
+}
+
+func (m *swapMeter) Float64Counter(name string, options ...metric.Float64CounterOption) (metric.Float64Counter, error) {
+	return addSwapped(m,
+		func(meter metric.Meter) (metric.Float64Counter, error) { return meter.Float64Counter(name, options...) },
+		func(_ metric.Float64Counter, s *swapped[metric.Float64Counter]) metric.Float64Counter {
+			return &swapFloat64Counter{swapped: s}
+		},
+	)
+}
+
+func (m *swapMeter) Float64UpDownCounter(name string, options ...metric.Float64UpDownCounterOption) (metric.Float64UpDownCounter, error) {
+	return addSwapped(m,
+		func(meter metric.Meter) (metric.Float64UpDownCounter, error) {
+			return meter.Float64UpDownCounter(name, options...)
+		},
+		func(_ metric.Float64UpDownCounter, s *swapped[metric.Float64UpDownCounter]) metric.Float64UpDownCounter {
+			return &swapFloat64UpDownCounter{swapped: s}
+		},
+	)
+}
+
+func (m *swapMeter) Float64Histogram(name string, options ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	return addSwapped(m,
+		func(meter metric.Meter) (metric.Float64Histogram, error) {
+			return meter.Float64Histogram(name, options...)
+		},
+		func(_ metric.Float64Histogram, s *swapped[metric.Float64Histogram]) metric.Float64Histogram {
+			return &swapFloat64Histogram{swapped: s}
+		},
+	)
+}
+
+func (m *swapMeter) Float64Gauge(name string, options ...metric.Float64GaugeOption) (metric.Float64Gauge, error) {
+	return addSwapped(m,
+		func(meter metric.Meter) (metric.Float64Gauge, error) { return meter.Float64Gauge(name, options...) },
+		func(_ metric.Float64Gauge, s *swapped[metric.Float64Gauge]) metric.Float64Gauge {
+			return &swapFloat64Gauge{swapped: s}
+		},
+	)
+}
+
+// Callbacks passed as options are passed again when the instrument is recreated
+func (m *swapMeter) Int64ObservableCounter(name string, options ...metric.Int64ObservableCounterOption) (metric.Int64ObservableCounter, error) {
+	return addSwapped(m,
+		func(meter metric.Meter) (metric.Int64ObservableCounter, error) {
+			return meter.Int64ObservableCounter(name, options...)
+		},
+		func(first metric.Int64ObservableCounter, s *swapped[metric.Int64ObservableCounter]) metric.Int64ObservableCounter {
+			return &swapInt64ObservableCounter{Int64ObservableCounter: first, swapped: s}
+		},
+	)
+}
+
+func (m *swapMeter) Int64ObservableUpDownCounter(name string, options ...metric.Int64ObservableUpDownCounterOption) (metric.Int64ObservableUpDownCounter, error) {
+	return addSwapped(m,
+		func(meter metric.Meter) (metric.Int64ObservableUpDownCounter, error) {
+			return meter.Int64ObservableUpDownCounter(name, options...)
+		},
+		func(first metric.Int64ObservableUpDownCounter, s *swapped[metric.Int64ObservableUpDownCounter]) metric.Int64ObservableUpDownCounter {
+			return &swapInt64ObservableUpDownCounter{Int64ObservableUpDownCounter: first, swapped: s}
+		},
+	)
+}
+
+func (m *swapMeter) Int64ObservableGauge(name string, options ...metric.Int64ObservableGaugeOption) (metric.Int64ObservableGauge, error) {
+	return addSwapped(m,
+		func(meter metric.Meter) (metric.Int64ObservableGauge, error) {
+			return meter.Int64ObservableGauge(name, options...)
+		},
+		func(first metric.Int64ObservableGauge, s *swapped[metric.Int64ObservableGauge]) metric.Int64ObservableGauge {
+			return &swapInt64ObservableGauge{Int64ObservableGauge: first, swapped: s}
+		},
+	)
+}
+
+func (m *swapMeter) Float64ObservableCounter(name string, options ...metric.Float64ObservableCounterOption) (metric.Float64ObservableCounter, error) {
+	return addSwapped(m,
+		func(meter metric.Meter) (metric.Float64ObservableCounter, error) {
+			return meter.Float64ObservableCounter(name, options...)
+		},
+		func(first metric.Float64ObservableCounter, s *swapped[metric.Float64ObservableCounter]) metric.Float64ObservableCounter {
+			return &swapFloat64ObservableCounter{Float64ObservableCounter: first, swapped: s}
+		},
+	)
+}
+
+func (m *swapMeter) Float64ObservableUpDownCounter(name string, options ...metric.Float64ObservableUpDownCounterOption) (metric.Float64ObservableUpDownCounter, error) {
+	return addSwapped(m,
+		func(meter metric.Meter) (metric.Float64ObservableUpDownCounter, error) {
+			return meter.Float64ObservableUpDownCounter(name, options...)
+		},
+		func(first metric.Float64ObservableUpDownCounter, s *swapped[metric.Float64ObservableUpDownCounter]) metric.Float64ObservableUpDownCounter {
+			return &swapFloat64ObservableUpDownCounter{Float64ObservableUpDownCounter: first, swapped: s}
+		},
+	)
+}
+
+func (m *swapMeter) Float64ObservableGauge(name string, options ...metric.Float64ObservableGaugeOption) (metric.Float64ObservableGauge, error) {
+	return addSwapped(m,
+		func(meter metric.Meter) (metric.Float64ObservableGauge, error) {
+			return meter.Float64ObservableGauge(name, options...)
+		},
+		func(first metric.Float64ObservableGauge, s *swapped[metric.Float64ObservableGauge]) metric.Float64ObservableGauge {
+			return &swapFloat64ObservableGauge{Float64ObservableGauge: first, swapped: s}
+		},
+	)
+}
+
+// RegisterCallback registers f on the current meter and again on every swap
+func (m *swapMeter) RegisterCallback(f metric.Callback, instruments ...metric.Observable) (metric.Registration, error) {
+	m.provider.mu.Lock()
+	defer m.provider.mu.Unlock()
+
+	registration := &swapRegistration{meter: m, callback: f, instruments: instruments}
+	if err := registration.register(m.current); err != nil {
+		return nil, err
+	}
+	m.callbacks = append(m.callbacks, registration)
+	return registration, nil
+}
+
+type swapRegistration struct {
+	embedded.Registration
+	meter       *swapMeter
+	callback    metric.Callback
+	instruments []metric.Observable
+	current     metric.Registration
+}
+
+// register registers the callback on meter, observing the instruments created on it
+func (r *swapRegistration) register(meter metric.Meter) error {
+	instruments := make([]metric.Observable, len(r.instruments))
+	for i, instrument := range r.instruments {
+		if s, ok := instrument.(swapObservable); ok {
+			instrument = s.currentObservable()
+		}
+		instruments[i] = instrument
+	}
+
+	registration, err := meter.RegisterCallback(func(ctx context.Context, observer metric.Observer) error {
+		return r.callback(ctx, swapObserver{observer: observer})
+	}, instruments...)
+	if err != nil {
+		return err
+	}
+	r.current = registration
+	return nil
+}
+
+func (r *swapRegistration) Unregister() error {
+	r.meter.provider.mu.Lock()
+	defer r.meter.provider.mu.Unlock()
+
+	r.meter.callbacks = slices.DeleteFunc(r.meter.callbacks, func(other *swapRegistration) bool { return other == r })
+	return r.current.Unregister()
+}
+
+// swapObserver hands the SDK the instruments created on its own meter, it rejects the others
+type swapObserver struct {
+	embedded.Observer
+	observer metric.Observer
+}
+
+func (o swapObserver) ObserveInt64(instrument metric.Int64Observable, value int64, opts ...metric.ObserveOption) {
+	if s, ok := instrument.(swapObservable); ok {
+		instrument, _ = s.currentObservable().(metric.Int64Observable)
+	}
+	o.observer.ObserveInt64(instrument, value, opts...)
+}
+
+func (o swapObserver) ObserveFloat64(instrument metric.Float64Observable, value float64, opts ...metric.ObserveOption) {
+	if s, ok := instrument.(swapObservable); ok {
+		instrument, _ = s.currentObservable().(metric.Float64Observable)
+	}
+	o.observer.ObserveFloat64(instrument, value, opts...)
+}
+
+// swapObservable is implemented by the observable swap instruments, which embed the first
+// instrument created to satisfy the API interfaces
+type swapObservable interface {
+	currentObservable() metric.Observable
+}
+
+type swapInt64ObservableCounter struct {
+	metric.Int64ObservableCounter
+	*swapped[metric.Int64ObservableCounter]
+}
+
+func (i *swapInt64ObservableCounter) currentObservable() metric.Observable { return i.load() }
+
+type swapInt64ObservableUpDownCounter struct {
+	metric.Int64ObservableUpDownCounter
+	*swapped[metric.Int64ObservableUpDownCounter]
+}
+
+func (i *swapInt64ObservableUpDownCounter) currentObservable() metric.Observable { return i.load() }
+
+type swapInt64ObservableGauge struct {
+	metric.Int64ObservableGauge
+	*swapped[metric.Int64ObservableGauge]
+}
+
+func (i *swapInt64ObservableGauge) currentObservable() metric.Observable { return i.load() }
+
+type swapFloat64ObservableCounter struct {
+	metric.Float64ObservableCounter
+	*swapped[metric.Float64ObservableCounter]
+}
+
+func (i *swapFloat64ObservableCounter) currentObservable() metric.Observable { return i.load() }
+
+type swapFloat64ObservableUpDownCounter struct {
+	metric.Float64ObservableUpDownCounter
+	*swapped[metric.Float64ObservableUpDownCounter]
+}
+
+func (i *swapFloat64ObservableUpDownCounter) currentObservable() metric.Observable { return i.load() }
+
+type swapFloat64ObservableGauge struct {
+	metric.Float64ObservableGauge
+	*swapped[metric.Float64ObservableGauge]
+}
+
+func (i *swapFloat64ObservableGauge) currentObservable() metric.Observable { return i.load() }
+
+type swapInt64Counter struct {
+	embedded.Int64Counter
+	*swapped[metric.Int64Counter]
+}
+
+func (i *swapInt64Counter) Add(ctx context.Context, incr int64, options ...metric.AddOption) {
+	i.load().Add(ctx, incr, options...)
+}
+
+type swapInt64UpDownCounter struct {
+	embedded.Int64UpDownCounter
+	*swapped[metric.Int64UpDownCounter]
+}
+
+func (i *swapInt64UpDownCounter) Add(ctx context.Context, incr int64, options ...metric.AddOption) {
+	i.load().Add(ctx, incr, options...)
+}
+
+type swapInt64Histogram struct {
+	embedded.Int64Histogram
+	*swapped[metric.Int64Histogram]
+}
+
+func (i *swapInt64Histogram) Record(ctx context.Context, value int64, options ...metric.RecordOption) {
+	i.load().Record(ctx, value, options...)
+}
+
+type swapInt64Gauge struct {
+	embedded.Int64Gauge
+	*swapped[metric.Int64Gauge]
+}
+
+func (i *swapInt64Gauge) Record(ctx context.Context, value int64, options ...metric.RecordOption) {
+	i.load().Record(ctx, value, options...)
+}
+
+type swapFloat64Counter struct {
+	embedded.Float64Counter
+	*swapped[metric.Float64Counter]
+}
+
+func (i *swapFloat64Counter) Add(ctx context.Context, incr float64, options ...metric.AddOption) {
+	i.load().Add(ctx, incr, options...)
+}
+
+type swapFloat64UpDownCounter struct {
+	embedded.Float64UpDownCounter
+	*swapped[metric.Float64UpDownCounter]
+}
+
+func (i *swapFloat64UpDownCounter) Add(ctx context.Context, incr float64, options ...metric.AddOption) {
+	i.load().Add(ctx, incr, options...)
+}
+
+type swapFloat64Histogram struct {
+	embedded.Float64Histogram
+	*swapped[metric.Float64Histogram]
+}
+
+func (i *swapFloat64Histogram) Record(ctx context.Context, value float64, options ...metric.RecordOption) {
+	i.load().Record(ctx, value, options...)
+}
+
+type swapFloat64Gauge struct {
+	embedded.Float64Gauge
+	*swapped[metric.Float64Gauge]
+}
+
+func (i *swapFloat64Gauge) Record(ctx context.Context, value float64, options ...metric.RecordOption) {
+	i.load().Record(ctx, value, options...)
+}
@@ -0,0 +1,82 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultPanicSource is the source label of the panics recovered by RecoverAndRecord without
+// WithPanicSource
+const DefaultPanicSource = "goroutine"
+
+// RecoverOption configures RecoverAndRecord
+type RecoverOption func(*recoverConfig)
+
+type recoverConfig struct {
+	source  string
+	repanic bool
+}
+
+// WithPanicSource sets the source label of panics_total (e.g. "consumer", "cron"), to tell
+// where panics come from. Keep it low cardinality.
+func WithPanicSource(source string) RecoverOption {
+	return func(cfg *recoverConfig) {
+		cfg.source = source
+	}
+}
+
+// WithRepanic panics again with the recovered value once it is recorded, for code that must
+// still crash (or be recovered further up) but should not lose the telemetry
+func WithRepanic() RecoverOption {
+	return func(cfg *recoverConfig) {
+		cfg.repanic = true
+	}
+}
+
+// RecoverAndRecord recovers a panic and records it: the error, with the stack trace in the
+// exception event, and an error status on the active span of ctx, an error log with the stack,
+// and panics_total labeled with source. The panic is swallowed unless WithRepanic is given.
+// It must be deferred directly, since recover only works in the deferred function itself:
+//
+//	go func() {
+//		defer client.RecoverAndRecord(ctx, telemetry.WithPanicSource("consumer"))
+//		consume(ctx)
+//	}()
+func (c *TelemetryClient) RecoverAndRecord(ctx context.Context, opts ...RecoverOption) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+
+	cfg := recoverConfig{source: DefaultPanicSource}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	c.recordPanic(contextOrBackground(ctx), recovered, cfg.source, "panic recovered")
+	if cfg.repanic {
+		panic(recovered)
+	}
+}
+
+// recordPanic records a recovered panic on the span of ctx, in the logs under message and in
+// panics_total, shared by RecoverAndRecord and HTTPMiddleware
+func (c *TelemetryClient) recordPanic(ctx context.Context, recovered any, source, message string) {
+	err := fmt.Errorf("panic: %v", recovered)
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err, trace.WithStackTrace(true))
+	span.SetStatus(codes.Error, err.Error())
+	c.Logger.ErrorContext(ctx, message, "error", err, "panic.source", source, "stack", string(debug.Stack()))
+
+	counter, counterErr := c.NewCounter("panics", "Total number of recovered panics, by source")
+	if counterErr != nil {
+		c.Logger.ErrorContext(ctx, "failed to create panics counter", "error", counterErr)
+		return
+	}
+	counter.Add(ctx, 1, metric.WithAttributes(attribute.String("source", c.labelGuard.value("panic.source", source))))
+}
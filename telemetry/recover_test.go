@@ -0,0 +1,95 @@
+package telemetry
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// panicWorker panics under a span, recovering with RecoverAndRecord, and returns the value
+// that escaped it, if any
+func panicWorker(tt *testTelemetry, opts ...RecoverOption) (escaped any) {
+	defer func() { escaped = recover() }()
+	ctx, span := tt.client.StartSpan(context.Background(), "worker")
+	defer span.End()
+	defer tt.client.RecoverAndRecord(ctx, opts...)
+	panic("worker exploded")
+}
+
+func TestRecoverAndRecord(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []RecoverOption
+		wantSource string
+		wantEscape bool
+	}{
+		{name: "swallow", wantSource: DefaultPanicSource},
+		{name: "swallow with source", opts: []RecoverOption{WithPanicSource("consumer")}, wantSource: "consumer"},
+		{name: "repanic", opts: []RecoverOption{WithRepanic()}, wantSource: DefaultPanicSource, wantEscape: true},
+		{name: "repanic with source", opts: []RecoverOption{WithRepanic(), WithPanicSource("cron")}, wantSource: "cron", wantEscape: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTestTelemetry(t, Config{})
+			escaped := panicWorker(tt, tc.opts...)
+
+			if tc.wantEscape && escaped != "worker exploded" {
+				t.Errorf("escaped panic = %v, want the recovered value", escaped)
+			}
+			if !tc.wantEscape && escaped != nil {
+				t.Errorf("panic escaped: %v", escaped)
+			}
+
+			spans := tt.spans.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("got %d spans, want 1", len(spans))
+			}
+			span := spans[0]
+			if span.Status().Code != codes.Error {
+				t.Errorf("span status = %v, want Error", span.Status())
+			}
+			var stack string
+			for _, event := range span.Events() {
+				if event.Name != semconv.ExceptionEventName {
+					continue
+				}
+				for _, attr := range event.Attributes {
+					if attr.Key == semconv.ExceptionStacktraceKey {
+						stack = attr.Value.AsString()
+					}
+				}
+			}
+			if !strings.Contains(stack, "panicWorker") {
+				t.Errorf("exception stack trace does not name the panicking function:\n%s", stack)
+			}
+
+			if got := tt.sum(t, "panics_total", attribute.String("source", tc.wantSource)); got != 1 {
+				t.Errorf("panics_total{source=%q} = %v, want 1", tc.wantSource, got)
+			}
+
+			var logged bool
+			for _, line := range tt.logs.lines(t) {
+				if line["msg"] != "panic recovered" {
+					continue
+				}
+				logged = true
+				if line["panic.source"] != tc.wantSource {
+					t.Errorf("log panic.source = %v, want %s", line["panic.source"], tc.wantSource)
+				}
+				if source, ok := line["source"]; ok {
+					t.Errorf("log source = %v, want it left to slog AddSource", source)
+				}
+				if stack, _ := line["stack"].(string); !strings.Contains(stack, "panicWorker") {
+					t.Errorf("log stack does not name the panicking function:\n%s", stack)
+				}
+			}
+			if !logged {
+				t.Error("panic not logged")
+			}
+		})
+	}
+}
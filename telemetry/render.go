@@ -0,0 +1,105 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type renderMetrics struct {
+	duration metric.Float64Histogram
+	size     metric.Int64Histogram
+}
+
+// renderWriter counts the bytes written and keeps the first write error, which templates
+// may wrap or, for some writers, never return
+type renderWriter struct {
+	w     io.Writer
+	bytes int64
+	err   error
+}
+
+func (rw *renderWriter) Write(p []byte) (int, error) {
+	n, err := rw.w.Write(p)
+	rw.bytes += int64(n)
+	if err != nil && rw.err == nil {
+		rw.err = err
+	}
+	return n, err
+}
+
+// TraceRender measures a template rendering (e.g. name "orders/list.html") in a render.<name>
+// span and in the render_duration_seconds and render_bytes histograms, labeled with template.
+// fn writes to the io.Writer it receives, which forwards to w and counts the bytes. Errors
+// writing to w are recorded on the span even when fn does not return them; the error returned
+// by fn is returned unchanged.
+func (c *TelemetryClient) TraceRender(ctx context.Context, name string, fn func(io.Writer) error, w io.Writer) error {
+	ctx, span := c.StartSpan(contextOrBackground(ctx), "render."+name, trace.WithAttributes(attribute.String("render.template", name)))
+	defer span.End()
+
+	rw := &renderWriter{w: w}
+	start := time.Now()
+	err := fn(rw)
+	duration := time.Since(start)
+
+	metrics := c.renderInstruments()
+	metrics.duration.Record(ctx, duration.Seconds(), metric.WithAttributes(
+		attribute.String("template", name),
+		attribute.Bool("error", err != nil || rw.err != nil),
+	))
+	metrics.size.Record(ctx, rw.bytes, metric.WithAttributes(attribute.String("template", name)))
+	span.SetAttributes(attribute.Int64("render.bytes", rw.bytes))
+
+	if rw.err != nil {
+		span.RecordError(rw.err, trace.WithAttributes(attribute.Bool("render.write_error", true)))
+		span.SetStatus(codes.Error, rw.err.Error())
+	}
+	if err != nil && (rw.err == nil || !errors.Is(err, rw.err)) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// renderInstruments creates the TraceRender histograms on first use
+func (c *TelemetryClient) renderInstruments() *renderMetrics {
+	c.renderMetricsOnce.Do(func() {
+		metrics, err := newRenderMetrics(c.Meter)
+		if err != nil {
+			c.Logger.Error("failed to create render metrics, falling back to no-op", "error", err)
+			metrics, _ = newRenderMetrics(noop.NewMeterProvider().Meter(""))
+		}
+		c.renderMetrics = metrics
+	})
+	return c.renderMetrics
+}
+
+func newRenderMetrics(meter metric.Meter) (*renderMetrics, error) {
+	duration, err := meter.Float64Histogram(
+		"render_duration_seconds",
+		metric.WithDescription("Duration of template rendering in seconds"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create render duration histogram: %w", err)
+	}
+
+	size, err := meter.Int64Histogram(
+		"render_bytes",
+		metric.WithDescription("Size of rendered output in bytes"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create render size histogram: %w", err)
+	}
+
+	return &renderMetrics{duration: duration, size: size}, nil
+}
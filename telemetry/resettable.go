@@ -0,0 +1,80 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/metric"
+)
+
+// ResettableCounter exports a cumulative value read from an external source (a device, a
+// /proc file, another process) that may restart from zero
+type ResettableCounter struct {
+	name   string
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	last   int64 // last value read from the source
+	offset int64 // sum of the values reached before each reset
+}
+
+// NewResettableCounter registers an observable counter fed by ResettableCounter.Set, named like
+// NewCounter (_total appended, unit "1").
+//
+// The exported value must never decrease, so resets are detected with a simple heuristic: any
+// reading lower than the previous one means the source restarted from zero. The value reached
+// before the reset is kept as an offset and added to the following readings, and a warning is
+// logged. Increments between the last reading and the reset are lost, and a source that
+// restarts and overtakes its previous value before the next reading goes unnoticed.
+func (c *TelemetryClient) NewResettableCounter(name string) (*ResettableCounter, error) {
+	if !strings.HasSuffix(name, "_total") {
+		name += "_total"
+	}
+	if !instrumentNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid counter name %q: must start with a letter and contain only letters, digits, '_', '.', '-' or '/' (max 255 characters)", name)
+	}
+
+	counter := &ResettableCounter{name: name, logger: c.Logger}
+	_, err := c.Meter.Int64ObservableCounter(
+		name,
+		metric.WithUnit("1"),
+		metric.WithInt64Callback(func(_ context.Context, observer metric.Int64Observer) error {
+			observer.Observe(counter.value())
+			return nil
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create counter %q: %w", name, err)
+	}
+	return counter, nil
+}
+
+// Set records the current cumulative value read from the source. Negative values are ignored.
+func (r *ResettableCounter) Set(value int64) {
+	if value < 0 {
+		r.logger.Warn("ignoring negative counter value", "counter", r.name, "value", value)
+		return
+	}
+
+	r.mu.Lock()
+	previous := r.last
+	reset := value < previous
+	if reset {
+		r.offset += previous
+	}
+	r.last = value
+	r.mu.Unlock()
+
+	if reset {
+		r.logger.Warn("counter reset detected", "counter", r.name, "previous", previous, "current", value)
+	}
+}
+
+func (r *ResettableCounter) value() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.offset + r.last
+}
@@ -0,0 +1,106 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Default backoff of WithRetrySpan: the delay doubles after each failed attempt, starting at
+// DefaultRetryInitialBackoff and capped at DefaultRetryMaxBackoff
+const (
+	DefaultRetryInitialBackoff = 100 * time.Millisecond
+	DefaultRetryMaxBackoff     = 10 * time.Second
+)
+
+// RetryOption configures WithRetrySpan
+type RetryOption func(*retryConfig)
+
+type retryConfig struct {
+	initial time.Duration
+	max     time.Duration
+}
+
+// WithRetryBackoff sets the delay before the second attempt (initial) and the cap of the
+// doubling delays (maxDelay). A zero initial retries without waiting.
+func WithRetryBackoff(initial, maxDelay time.Duration) RetryOption {
+	return func(cfg *retryConfig) {
+		cfg.initial, cfg.max = initial, maxDelay
+	}
+}
+
+func (cfg retryConfig) backoff(attempt int) time.Duration {
+	delay := cfg.initial
+	for i := 1; i < attempt && delay < cfg.max; i++ {
+		delay *= 2
+	}
+	return min(delay, cfg.max)
+}
+
+// WithRetrySpan calls fn up to maxAttempts times (at least once) until it returns nil, in a
+// name span with a name.attempt child span per attempt carrying retry.attempt (starting at 1).
+// Between attempts it waits with exponential backoff (see WithRetryBackoff), recorded as
+// retry.backoff events on the parent span. The parent ends with retry.attempts and, when every
+// attempt failed, an error status. It returns the last error of fn, joined with the context
+// error when ctx is done while waiting.
+func (c *TelemetryClient) WithRetrySpan(ctx context.Context, name string, fn func(ctx context.Context, attempt int) error, maxAttempts int, opts ...RetryOption) error {
+	cfg := retryConfig{initial: DefaultRetryInitialBackoff, max: DefaultRetryMaxBackoff}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	maxAttempts = max(maxAttempts, 1)
+
+	ctx, span := c.StartSpan(ctx, name, trace.WithAttributes(attribute.Int("retry.max_attempts", maxAttempts)))
+	defer span.End()
+
+	var err error
+	attempt := 1
+	for ; ; attempt++ {
+		attemptCtx, attemptSpan := c.StartSpan(ctx, name+".attempt", trace.WithAttributes(attribute.Int("retry.attempt", attempt)))
+		err = fn(attemptCtx, attempt)
+		if err != nil {
+			attemptSpan.RecordError(err)
+			attemptSpan.SetStatus(codes.Error, err.Error())
+		}
+		attemptSpan.End()
+		if err == nil || attempt == maxAttempts {
+			break
+		}
+
+		delay := cfg.backoff(attempt)
+		span.AddEvent("retry.backoff", trace.WithAttributes(
+			attribute.Int("retry.attempt", attempt),
+			attribute.Int64("retry.backoff_ms", delay.Milliseconds()),
+		))
+		if waitErr := sleepContext(ctx, delay); waitErr != nil {
+			err = errors.Join(err, waitErr)
+			break
+		}
+	}
+
+	span.SetAttributes(attribute.Int("retry.attempts", attempt))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// sleepContext waits for d, or returns the context error if ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
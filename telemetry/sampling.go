@@ -0,0 +1,242 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+
+	otelconf "go.opentelemetry.io/contrib/otelconf/v0.3.0"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+)
+
+// routeSampler samples root spans with a per-route trace ID ratio
+type routeSampler struct {
+	routes   map[string]sdktrace.Sampler
+	fallback sdktrace.Sampler
+}
+
+// NewRouteSampler returns a sampler applying ratios[route] to spans of that route and
+// defaultRatio to every other span. The route is the http.route start attribute, or else
+// the path part of a "METHOD /path" span name, or else the span name itself.
+func NewRouteSampler(ratios map[string]float64, defaultRatio float64) sdktrace.Sampler {
+	routes := make(map[string]sdktrace.Sampler, len(ratios))
+	for route, ratio := range ratios {
+		routes[route] = sdktrace.TraceIDRatioBased(ratio)
+	}
+	return &routeSampler{routes: routes, fallback: sdktrace.TraceIDRatioBased(defaultRatio)}
+}
+
+func (s *routeSampler) ShouldSample(parameters sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	if sampler, ok := s.routes[samplingRoute(parameters)]; ok {
+		return sampler.ShouldSample(parameters)
+	}
+	return s.fallback.ShouldSample(parameters)
+}
+
+func (s *routeSampler) Description() string {
+	return fmt.Sprintf("RouteSampler{routes:%d,default:%s}", len(s.routes), s.fallback.Description())
+}
+
+// samplingRoute determines the route of a span that has not started yet
+func samplingRoute(parameters sdktrace.SamplingParameters) string {
+	for _, attr := range parameters.Attributes {
+		if attr.Key == "http.route" && attr.Value.Type() == attribute.STRING {
+			return attr.Value.AsString()
+		}
+	}
+	if _, path, ok := strings.Cut(parameters.Name, " "); ok && strings.HasPrefix(path, "/") {
+		return path
+	}
+	return parameters.Name
+}
+
+// rootSamplingTracerProvider applies a sampler to root spans before the SDK sees them.
+// otelconf only builds samplers from the YAML file, so custom samplers cannot be set on its
+// provider; spans kept here still go through the YAML sampler, with the same trace ID.
+type rootSamplingTracerProvider struct {
+	embedded.TracerProvider
+	provider trace.TracerProvider
+	sampler  sdktrace.Sampler
+}
+
+func (p *rootSamplingTracerProvider) Tracer(name string, opts ...trace.TracerOption) trace.Tracer {
+	return &rootSamplingTracer{tracer: p.provider.Tracer(name, opts...), sampler: p.sampler}
+}
+
+type rootSamplingTracer struct {
+	embedded.Tracer
+	tracer  trace.Tracer
+	sampler sdktrace.Sampler
+}
+
+func (t *rootSamplingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	startConfig := trace.NewSpanStartConfig(opts...)
+	if trace.SpanContextFromContext(ctx).IsValid() && !startConfig.NewRoot() || isSamplingForced(ctx) {
+		// Children follow the parent decision in the SDK, forced requests skip the route ratios
+		return t.tracer.Start(ctx, name, opts...)
+	}
+	return startSampled(ctx, t.tracer, t.sampler, name, opts)
+}
+
+// startSampled runs sampler on a span about to start and starts it through tracer unless it
+// is dropped. The sampler must see the trace ID the span gets, but the SDK only generates it
+// once the span starts, so for a root span the ID is chosen here and pinned in the context as a
+// span context with a trace ID and no span ID: the SDK takes the trace ID of such a context,
+// which is not a valid parent, so the span stays a root.
+func startSampled(ctx context.Context, tracer trace.Tracer, sampler sdktrace.Sampler, name string, opts []trace.SpanStartOption) (context.Context, trace.Span) {
+	startConfig := trace.NewSpanStartConfig(opts...)
+	parent := trace.SpanContextFromContext(ctx)
+	if startConfig.NewRoot() {
+		parent = trace.SpanContext{}
+	}
+	traceID := parent.TraceID()
+	if !parent.IsValid() {
+		// An outer wrapper may have pinned the ID already
+		if !traceID.IsValid() {
+			traceID = randomTraceID()
+		}
+		ctx = trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID}))
+		// WithNewRoot would make the SDK ignore the pinned ID; the pinned context replaces the parent
+		opts = rootStartOptions(&startConfig)
+	}
+
+	result := sampler.ShouldSample(sdktrace.SamplingParameters{
+		ParentContext: ctx,
+		TraceID:       traceID,
+		Name:          name,
+		Kind:          startConfig.SpanKind(),
+		Attributes:    startConfig.Attributes(),
+		Links:         startConfig.Links(),
+	})
+	if result.Decision != sdktrace.Drop {
+		return tracer.Start(ctx, name, opts...)
+	}
+
+	// Same outcome as an SDK drop: a non-recording span whose unsampled context propagates
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     randomSpanID(),
+		TraceState: result.Tracestate,
+	})
+	ctx = trace.ContextWithSpanContext(ctx, spanContext)
+	return ctx, trace.SpanFromContext(ctx)
+}
+
+// rootStartOptions rebuilds the start options of startConfig without WithNewRoot
+func rootStartOptions(startConfig *trace.SpanConfig) []trace.SpanStartOption {
+	opts := []trace.SpanStartOption{
+		trace.WithSpanKind(startConfig.SpanKind()),
+		trace.WithAttributes(startConfig.Attributes()...),
+		trace.WithLinks(startConfig.Links()...),
+	}
+	if timestamp := startConfig.Timestamp(); !timestamp.IsZero() {
+		opts = append(opts, trace.WithTimestamp(timestamp))
+	}
+	return opts
+}
+
+func randomTraceID() trace.TraceID {
+	var id trace.TraceID
+	binary.BigEndian.PutUint64(id[:8], rand.Uint64())
+	binary.BigEndian.PutUint64(id[8:], rand.Uint64())
+	return id
+}
+
+func randomSpanID() trace.SpanID {
+	var id trace.SpanID
+	binary.BigEndian.PutUint64(id[:], rand.Uint64())
+	return id
+}
+
+// ParentSamplingConfig holds the trace ID ratios of a parent-based sampler. By default sampled
+// parents are followed and unsampled ones are not, like the OTel ParentBased sampler; set
+// RemoteParentSampled below 1 so external clients cannot force every trace to be sampled by
+// sending the sampled flag.
+type ParentSamplingConfig struct {
+	Root                   *float64 // Spans without a parent (nil keeps the YAML sampler, or 1)
+	RemoteParentSampled    *float64 // Remote parent sampled (nil = 1)
+	RemoteParentNotSampled *float64 // Remote parent not sampled (nil = 0)
+	LocalParentSampled     *float64 // Local parent sampled (nil = 1)
+	LocalParentNotSampled  *float64 // Local parent not sampled (nil = 0)
+}
+
+// applyParentSampling sets a parent_based sampler on the YAML configuration
+func applyParentSampling(conf *otelconf.OpenTelemetryConfiguration, parentSampling ParentSamplingConfig) {
+	if conf.TracerProvider == nil {
+		return
+	}
+
+	ratio := func(value *float64, fallback float64) *otelconf.Sampler {
+		if value != nil {
+			fallback = *value
+		}
+		return &otelconf.Sampler{TraceIDRatioBased: &otelconf.SamplerTraceIDRatioBased{Ratio: &fallback}}
+	}
+
+	root := conf.TracerProvider.Sampler
+	if root != nil && root.ParentBased != nil {
+		root = root.ParentBased.Root
+	}
+	if parentSampling.Root != nil || root == nil {
+		root = ratio(parentSampling.Root, 1)
+	}
+
+	conf.TracerProvider.Sampler = &otelconf.Sampler{ParentBased: &otelconf.SamplerParentBased{
+		Root:                   root,
+		RemoteParentSampled:    ratio(parentSampling.RemoteParentSampled, 1),
+		RemoteParentNotSampled: ratio(parentSampling.RemoteParentNotSampled, 0),
+		LocalParentSampled:     ratio(parentSampling.LocalParentSampled, 1),
+		LocalParentNotSampled:  ratio(parentSampling.LocalParentNotSampled, 0),
+	}}
+}
+
+// applyEnvSampler sets the sampler from OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG, which
+// otelconf ignores, so ops can change sampling without editing the YAML. The ratio samplers
+// default to 1 when the arg is unset. Like the OTel SDKs, an unknown sampler or an invalid
+// arg does not stop the setup: it logs a warning (through slog.Default, which
+// Config.StartupLogBuffer captures) and falls back to parentbased_always_on or a ratio of 1.
+func applyEnvSampler(conf *otelconf.OpenTelemetryConfiguration) {
+	name := strings.TrimSpace(os.Getenv("OTEL_TRACES_SAMPLER"))
+	if name == "" || conf.TracerProvider == nil {
+		return
+	}
+
+	ratio := 1.0
+	if arg := strings.TrimSpace(os.Getenv("OTEL_TRACES_SAMPLER_ARG")); arg != "" && strings.HasSuffix(name, "traceidratio") {
+		parsed, err := strconv.ParseFloat(arg, 64)
+		if err != nil || parsed < 0 || parsed > 1 {
+			slog.Warn("invalid OTEL_TRACES_SAMPLER_ARG, must be a ratio between 0 and 1; using 1",
+				"component", "telemetry", "value", arg)
+		} else {
+			ratio = parsed
+		}
+	}
+
+	var root *otelconf.Sampler
+	switch strings.TrimPrefix(name, "parentbased_") {
+	case "always_on":
+		root = &otelconf.Sampler{AlwaysOn: otelconf.SamplerAlwaysOn{}}
+	case "always_off":
+		root = &otelconf.Sampler{AlwaysOff: otelconf.SamplerAlwaysOff{}}
+	case "traceidratio":
+		root = &otelconf.Sampler{TraceIDRatioBased: &otelconf.SamplerTraceIDRatioBased{Ratio: &ratio}}
+	default:
+		slog.Warn("unsupported OTEL_TRACES_SAMPLER, using parentbased_always_on",
+			"component", "telemetry", "value", name)
+		name = "parentbased_always_on"
+		root = &otelconf.Sampler{AlwaysOn: otelconf.SamplerAlwaysOn{}}
+	}
+
+	if strings.HasPrefix(name, "parentbased_") {
+		root = &otelconf.Sampler{ParentBased: &otelconf.SamplerParentBased{Root: root}}
+	}
+	conf.TracerProvider.Sampler = root
+}
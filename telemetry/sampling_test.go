@@ -0,0 +1,202 @@
+package telemetry
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	otelconf "go.opentelemetry.io/contrib/otelconf/v0.3.0"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRouteSamplingUsesSpanTraceID(t *testing.T) {
+	const ratio = 0.3
+	ratioSampler := sdktrace.TraceIDRatioBased(ratio)
+	sampledByRatio := func(traceID trace.TraceID) bool {
+		return ratioSampler.ShouldSample(sdktrace.SamplingParameters{TraceID: traceID}).Decision == sdktrace.RecordAndSample
+	}
+
+	tests := []struct {
+		name  string
+		start func(client *TelemetryClient, parent context.Context) (context.Context, trace.Span)
+	}{
+		{
+			name: "root",
+			start: func(client *TelemetryClient, _ context.Context) (context.Context, trace.Span) {
+				return client.StartSpan(context.Background(), "GET /search")
+			},
+		},
+		{
+			name: "new root under a parent",
+			start: func(client *TelemetryClient, parent context.Context) (context.Context, trace.Span) {
+				return client.Tracer.Start(parent, "GET /search", trace.WithNewRoot())
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTestTelemetry(t, Config{RouteSampling: map[string]float64{"/search": ratio}})
+			parent, parentSpan := tt.client.StartSpan(context.Background(), "GET /other")
+			parentSpan.End()
+
+			sampled := 0
+			const spans = 300
+			for range spans {
+				ctx, span := tc.start(tt.client, parent)
+				traceID := span.SpanContext().TraceID()
+				if !trace.SpanContextFromContext(ctx).Equal(span.SpanContext()) {
+					t.Fatal("returned context does not hold the span")
+				}
+				if traceID == parentSpan.SpanContext().TraceID() {
+					t.Fatal("root span joined the parent trace")
+				}
+				if got, want := span.SpanContext().IsSampled(), sampledByRatio(traceID); got != want {
+					t.Fatalf("trace %s sampled = %v, want %v like TraceIDRatioBased(%v) on the span trace ID", traceID, got, want, ratio)
+				}
+				if span.SpanContext().IsSampled() {
+					sampled++
+				}
+				span.End()
+			}
+			if sampled == 0 || sampled == spans {
+				t.Fatalf("sampled %d of %d spans, want about %v", sampled, spans, ratio)
+			}
+
+			for _, span := range tt.spans.Ended() {
+				if span.Name() == "GET /other" {
+					continue
+				}
+				if span.Parent().IsValid() {
+					t.Errorf("root span has parent %v", span.Parent())
+				}
+				if !sampledByRatio(span.SpanContext().TraceID()) {
+					t.Errorf("exported trace %s is outside the ratio", span.SpanContext().TraceID())
+				}
+			}
+		})
+	}
+}
+
+func TestForcedSamplingUsesSpanTraceID(t *testing.T) {
+	const ratio = 0.3
+	ratioSampler := sdktrace.TraceIDRatioBased(ratio)
+	client, err := NewClient(context.Background(), Config{
+		ConfigPath:       writeConfig(t, "file_format: \"0.3\"\ntracer_provider:\n  sampler:\n    trace_id_ratio_based:\n      ratio: 0.3\n"),
+		PrioritySampling: true,
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Shutdown(context.Background()) })
+
+	sampled := 0
+	for range 300 {
+		_, span := client.StartSpan(context.Background(), "job")
+		traceID := span.SpanContext().TraceID()
+		want := ratioSampler.ShouldSample(sdktrace.SamplingParameters{TraceID: traceID}).Decision == sdktrace.RecordAndSample
+		if span.SpanContext().IsSampled() != want {
+			t.Fatalf("trace %s sampled = %v, want %v", traceID, span.SpanContext().IsSampled(), want)
+		}
+		if want {
+			sampled++
+		}
+		span.End()
+	}
+	if sampled == 0 {
+		t.Fatal("no span sampled")
+	}
+}
+
+func TestApplyEnvSampler(t *testing.T) {
+	parentBased := func(root sdktrace.Sampler) sdktrace.Sampler { return sdktrace.ParentBased(root) }
+	yamlSampler := &otelconf.Sampler{AlwaysOff: otelconf.SamplerAlwaysOff{}}
+
+	tests := []struct {
+		name     string
+		sampler  string
+		arg      string
+		want     sdktrace.Sampler
+		wantWarn string
+	}{
+		{name: "unset keeps the YAML", want: sdktrace.NeverSample()},
+		{name: "always_on", sampler: "always_on", want: sdktrace.AlwaysSample()},
+		{name: "always_off", sampler: "always_off", want: sdktrace.NeverSample()},
+		{name: "traceidratio", sampler: "traceidratio", arg: "0.25", want: sdktrace.TraceIDRatioBased(0.25)},
+		{name: "traceidratio without arg", sampler: "traceidratio", want: sdktrace.TraceIDRatioBased(1)},
+		{name: "parentbased_always_on", sampler: "parentbased_always_on", want: parentBased(sdktrace.AlwaysSample())},
+		{name: "parentbased_always_off", sampler: "parentbased_always_off", want: parentBased(sdktrace.NeverSample())},
+		{name: "parentbased_traceidratio", sampler: "parentbased_traceidratio", arg: "0.1", want: parentBased(sdktrace.TraceIDRatioBased(0.1))},
+		{name: "arg ignored by always_on", sampler: "always_on", arg: "0.1", want: sdktrace.AlwaysSample()},
+		{
+			name:     "invalid arg",
+			sampler:  "traceidratio",
+			arg:      "ten percent",
+			want:     sdktrace.TraceIDRatioBased(1),
+			wantWarn: "invalid OTEL_TRACES_SAMPLER_ARG",
+		},
+		{
+			name:     "arg out of range",
+			sampler:  "parentbased_traceidratio",
+			arg:      "1.5",
+			want:     parentBased(sdktrace.TraceIDRatioBased(1)),
+			wantWarn: "invalid OTEL_TRACES_SAMPLER_ARG",
+		},
+		{
+			name:     "unknown sampler",
+			sampler:  "jaeger_remote",
+			want:     parentBased(sdktrace.AlwaysSample()),
+			wantWarn: "unsupported OTEL_TRACES_SAMPLER",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OTEL_TRACES_SAMPLER", tt.sampler)
+			t.Setenv("OTEL_TRACES_SAMPLER_ARG", tt.arg)
+			logs := &syncBuffer{}
+			previous := slog.Default()
+			slog.SetDefault(slog.New(slog.NewJSONHandler(logs, nil)))
+			defer slog.SetDefault(previous)
+
+			conf := &otelconf.OpenTelemetryConfiguration{TracerProvider: &otelconf.TracerProvider{Sampler: yamlSampler}}
+			applyEnvSampler(conf)
+
+			got, err := samplerFromConfig(conf.TracerProvider.Sampler)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Description() != tt.want.Description() {
+				t.Errorf("sampler = %s, want %s", got.Description(), tt.want.Description())
+			}
+
+			lines := logs.lines(t)
+			if tt.wantWarn == "" {
+				if len(lines) > 0 {
+					t.Errorf("unexpected logs: %v", lines)
+				}
+				return
+			}
+			if len(lines) != 1 || lines[0]["level"] != "WARN" || !strings.HasPrefix(lines[0]["msg"].(string), tt.wantWarn) {
+				t.Errorf("logs = %v, want one warning %q", lines, tt.wantWarn)
+			}
+		})
+	}
+}
+
+func TestUnknownEnvSamplerDoesNotAbortSetup(t *testing.T) {
+	t.Setenv("OTEL_TRACES_SAMPLER", "unknown")
+	client, err := NewClient(context.Background(), Config{
+		ConfigPath:  writeConfig(t, "file_format: \"0.3\"\ntracer_provider: {}\n"),
+		LogHandlers: []slog.Handler{slog.NewJSONHandler(&syncBuffer{}, nil)},
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer func() { _ = client.Shutdown(context.Background()) }()
+	_, span := client.StartSpan(context.Background(), "op")
+	defer span.End()
+	if !span.SpanContext().IsSampled() {
+		t.Error("root span not sampled, want the parentbased_always_on fallback")
+	}
+}
@@ -0,0 +1,249 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// testTelemetry is a client whose spans, metrics and logs are kept in memory
+type testTelemetry struct {
+	client  *TelemetryClient
+	spans   *tracetest.SpanRecorder
+	metrics *sdkmetric.ManualReader
+	logs    *syncBuffer
+}
+
+// syncBuffer is a bytes.Buffer safe for the concurrent writes of the log handlers
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// lines decodes the JSON log lines written so far
+func (b *syncBuffer) lines(t testing.TB) []map[string]any {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var lines []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(b.buf.Bytes()), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		lines = append(lines, record)
+	}
+	return lines
+}
+
+// newTestTelemetry builds a client from an exporter-less YAML file, recording the spans with a
+// SpanRecorder, the metrics with a ManualReader and the logs as JSON lines. config may set any
+// other field; ConfigPath, TracerProvider and LogHandlers are filled in when nil. Pass a
+// non-nil empty LogHandlers to keep the default stdout handler (see captureStdout).
+func newTestTelemetry(t testing.TB, config Config) *testTelemetry {
+	t.Helper()
+	tt := &testTelemetry{
+		spans:   tracetest.NewSpanRecorder(),
+		metrics: sdkmetric.NewManualReader(),
+		logs:    &syncBuffer{},
+	}
+	if config.ConfigPath == "" {
+		config.ConfigPath = writeConfig(t, "file_format: \"0.3\"\n")
+	}
+	if config.TracerProvider == nil && isEnabled(config.TracesEnabled) {
+		config.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(tt.spans))
+	}
+	if config.LogHandlers == nil && config.LogFormat == "" {
+		config.LogHandlers = []slog.Handler{slog.NewJSONHandler(tt.logs, &slog.HandlerOptions{Level: slog.LevelDebug})}
+	}
+
+	client, err := NewClient(context.Background(), config)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Shutdown(context.Background()) })
+	client.Meter = sdkmetric.NewMeterProvider(sdkmetric.WithReader(tt.metrics)).Meter("test")
+	tt.client = client
+	return tt
+}
+
+// writeConfig writes yaml to a temporary otel.yaml and returns its path
+func writeConfig(t testing.TB, yaml string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "otel.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// collect reads the metrics recorded so far
+func (tt *testTelemetry) collect(t testing.TB) []metricdata.Metrics {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := tt.metrics.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("collect metrics: %v", err)
+	}
+	var metrics []metricdata.Metrics
+	for _, sm := range rm.ScopeMetrics {
+		metrics = append(metrics, sm.Metrics...)
+	}
+	return metrics
+}
+
+// metric returns the collected metric named name, failing the test when it is missing
+func (tt *testTelemetry) metric(t testing.TB, name string) metricdata.Metrics {
+	t.Helper()
+	for _, m := range tt.collect(t) {
+		if m.Name == name {
+			return m
+		}
+	}
+	t.Fatalf("metric %q not recorded", name)
+	return metricdata.Metrics{}
+}
+
+// hasMetric reports whether a metric named name was recorded
+func (tt *testTelemetry) hasMetric(t testing.TB, name string) bool {
+	t.Helper()
+	for _, m := range tt.collect(t) {
+		if m.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sum returns the value of the counter or gauge data points of name having every attr of
+// attrs (other attributes are ignored), summed over the matching points; 0 when name was never
+// recorded
+func (tt *testTelemetry) sum(t testing.TB, name string, attrs ...attribute.KeyValue) float64 {
+	t.Helper()
+	if !tt.hasMetric(t, name) {
+		return 0
+	}
+	var total float64
+	switch data := tt.metric(t, name).Data.(type) {
+	case metricdata.Sum[int64]:
+		for _, dp := range data.DataPoints {
+			if hasAttrs(dp.Attributes, attrs) {
+				total += float64(dp.Value)
+			}
+		}
+	case metricdata.Sum[float64]:
+		for _, dp := range data.DataPoints {
+			if hasAttrs(dp.Attributes, attrs) {
+				total += dp.Value
+			}
+		}
+	case metricdata.Gauge[int64]:
+		for _, dp := range data.DataPoints {
+			if hasAttrs(dp.Attributes, attrs) {
+				total += float64(dp.Value)
+			}
+		}
+	case metricdata.Gauge[float64]:
+		for _, dp := range data.DataPoints {
+			if hasAttrs(dp.Attributes, attrs) {
+				total += dp.Value
+			}
+		}
+	default:
+		t.Fatalf("metric %q has unexpected data %T", name, data)
+	}
+	return total
+}
+
+// count returns the number of observations of the histogram name with attrs
+func (tt *testTelemetry) count(t testing.TB, name string, attrs ...attribute.KeyValue) uint64 {
+	t.Helper()
+	var total uint64
+	switch data := tt.metric(t, name).Data.(type) {
+	case metricdata.Histogram[float64]:
+		for _, dp := range data.DataPoints {
+			if hasAttrs(dp.Attributes, attrs) {
+				total += dp.Count
+			}
+		}
+	case metricdata.Histogram[int64]:
+		for _, dp := range data.DataPoints {
+			if hasAttrs(dp.Attributes, attrs) {
+				total += dp.Count
+			}
+		}
+	default:
+		t.Fatalf("metric %q is not a histogram: %T", name, data)
+	}
+	return total
+}
+
+func hasAttrs(set attribute.Set, attrs []attribute.KeyValue) bool {
+	for _, attr := range attrs {
+		if value, ok := set.Value(attr.Key); !ok || value != attr.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// spanAttr returns the attribute key of span
+func spanAttr(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+// captureStdout redirects os.Stdout, which the default handlers capture in NewClient, to a
+// pipe. stop restores it and returns what was written.
+func captureStdout(t testing.TB) (stop func() *syncBuffer) {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	buf := &syncBuffer{}
+	copied := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(buf, reader)
+		close(copied)
+	}()
+
+	var once sync.Once
+	stop = func() *syncBuffer {
+		once.Do(func() {
+			os.Stdout = stdout
+			_ = writer.Close()
+			<-copied
+			_ = reader.Close()
+		})
+		return buf
+	}
+	t.Cleanup(func() { stop() })
+	return stop
+}
@@ -0,0 +1,102 @@
+package telemetry
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// shutdownEvents records, in order, what reached the log sink and which providers shut down
+type shutdownEvents struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (e *shutdownEvents) add(event string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.events = append(e.events, event)
+}
+
+func (e *shutdownEvents) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimSpace(string(p)), "\n") {
+		e.add("log: " + line)
+	}
+	return len(p), nil
+}
+
+func (e *shutdownEvents) list() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.events...)
+}
+
+// bufferedHandler is a JSON handler over a bufio.Writer, flushed by Shutdown
+type bufferedHandler struct {
+	slog.Handler
+	w *bufio.Writer
+}
+
+func (h *bufferedHandler) Flush() error { return h.w.Flush() }
+
+// shutdownProcessor records when the tracer provider shuts it down
+type shutdownProcessor struct {
+	sdktrace.SpanProcessor
+	events *shutdownEvents
+}
+
+func (p shutdownProcessor) Shutdown(context.Context) error {
+	p.events.add("traces shutdown")
+	return nil
+}
+
+func TestShutdownFlushesLogsBeforeProviders(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []string
+	}{
+		{name: "no late logs"},
+		{name: "one late log", messages: []string{"draining"}},
+		{name: "several late logs", messages: []string{"draining", "drained", "bye"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := &shutdownEvents{}
+			w := bufio.NewWriterSize(events, 64<<10)
+			handler := &bufferedHandler{Handler: slog.NewJSONHandler(w, nil), w: w}
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(shutdownProcessor{
+				SpanProcessor: sdktrace.NewSimpleSpanProcessor(nil),
+				events:        events,
+			}))
+			tel := newTestTelemetry(t, Config{TracerProvider: provider, LogHandlers: []slog.Handler{handler}})
+
+			for _, msg := range tt.messages {
+				tel.client.Logger.Info(msg)
+			}
+			if got := events.list(); len(got) != 0 {
+				t.Fatalf("logs reached the sink before Shutdown: %v", got)
+			}
+			if err := tel.client.Shutdown(context.Background()); err != nil {
+				t.Fatalf("Shutdown: %v", err)
+			}
+
+			got := events.list()
+			if len(got) != len(tt.messages)+1 {
+				t.Fatalf("events = %v, want %d logs then the provider shutdown", got, len(tt.messages))
+			}
+			for i, msg := range tt.messages {
+				if !strings.HasPrefix(got[i], "log: ") || !strings.Contains(got[i], `"msg":"`+msg+`"`) {
+					t.Errorf("event %d = %q, want log %q", i, got[i], msg)
+				}
+			}
+			if last := got[len(got)-1]; last != "traces shutdown" {
+				t.Errorf("last event = %q, want traces shutdown", last)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+type stateMetrics struct {
+	mu       sync.Mutex
+	machines map[string]*machineStates
+}
+
+// machineStates tracks how many entities of a machine are in each state, and for how long
+type machineStates struct {
+	allowed map[string]bool // nil accepts any state up to the cardinality limit
+	current map[string]int64
+	seconds map[string]float64 // entity-seconds spent in each state
+	updated time.Time
+}
+
+// advance adds the time since the last update to every occupied state
+func (m *machineStates) advance(now time.Time) {
+	if !m.updated.IsZero() {
+		elapsed := now.Sub(m.updated).Seconds()
+		for state, count := range m.current {
+			m.seconds[state] += float64(count) * elapsed
+		}
+	}
+	m.updated = now
+}
+
+// RegisterStates declares the states of machine, bounding the state labels of RecordTransition:
+// any other state is recorded as "other". Without it the states of a machine are capped at 100
+// distinct values like the other labels. Call it before recording transitions; a second call
+// replaces the states.
+func (c *TelemetryClient) RegisterStates(machine string, states ...string) {
+	allowed := make(map[string]bool, len(states))
+	for _, state := range states {
+		allowed[state] = true
+	}
+
+	metrics := c.stateInstruments()
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	metrics.machine(c.labelGuard.value("state.machine", machine)).allowed = allowed
+}
+
+// RecordTransition records an entity of machine (e.g. "order") moving from one state to another
+// in state_transitions_total, labeled with machine, from and to. It also feeds two observables
+// labeled with machine and state: state_current, the number of entities in each state, and
+// state_seconds_total, the time entities spent in each state summed over them (its rate is the
+// average number of entities in the state; divided by the transitions out of the state it is
+// the mean time in it). An empty from records an entity entering the machine and an empty to
+// one leaving it. Counts start at zero with the process, so entities created before it are
+// only counted once they transition again; state_current never goes below zero.
+func (c *TelemetryClient) RecordTransition(ctx context.Context, machine, from, to string) {
+	ctx = contextOrBackground(ctx)
+	machine = c.labelGuard.value("state.machine", machine)
+
+	metrics := c.stateInstruments()
+	metrics.mu.Lock()
+	states := metrics.machine(machine)
+	from, to = c.stateLabel(states, machine, from), c.stateLabel(states, machine, to)
+	states.advance(time.Now())
+	if from != "" && states.current[from] > 0 {
+		states.current[from]--
+	}
+	if to != "" {
+		states.current[to]++
+	}
+	metrics.mu.Unlock()
+
+	counter, err := c.NewCounter("state_transitions", "Total number of state machine transitions")
+	if err != nil {
+		c.Logger.ErrorContext(ctx, "failed to create state transitions counter", "error", err)
+		return
+	}
+	counter.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("machine", machine),
+		attribute.String("from", from),
+		attribute.String("to", to),
+	))
+}
+
+// stateLabel bounds a state by the RegisterStates allowlist, or by the cardinality guard
+func (c *TelemetryClient) stateLabel(states *machineStates, machine, state string) string {
+	switch {
+	case state == "":
+		return ""
+	case states.allowed != nil:
+		if states.allowed[state] {
+			return state
+		}
+		return overflowLabelValue
+	default:
+		return c.labelGuard.value("state."+machine, state)
+	}
+}
+
+func (m *stateMetrics) machine(name string) *machineStates {
+	states, ok := m.machines[name]
+	if !ok {
+		states = &machineStates{current: map[string]int64{}, seconds: map[string]float64{}}
+		m.machines[name] = states
+	}
+	return states
+}
+
+// stateInstruments creates the state observables on first use
+func (c *TelemetryClient) stateInstruments() *stateMetrics {
+	c.stateMetricsOnce.Do(func() {
+		metrics, err := newStateMetrics(c.Meter)
+		if err != nil {
+			c.Logger.Error("failed to create state metrics, falling back to no-op", "error", err)
+			metrics, _ = newStateMetrics(noop.NewMeterProvider().Meter(""))
+		}
+		c.stateMetrics = metrics
+	})
+	return c.stateMetrics
+}
+
+func newStateMetrics(meter metric.Meter) (*stateMetrics, error) {
+	m := &stateMetrics{machines: map[string]*machineStates{}}
+
+	current, err := meter.Int64ObservableGauge(
+		"state_current",
+		metric.WithDescription("Number of state machine entities currently in each state"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create state current gauge: %w", err)
+	}
+
+	seconds, err := meter.Float64ObservableCounter(
+		"state_seconds_total",
+		metric.WithDescription("Total time state machine entities spent in each state, summed over entities, in seconds"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create state seconds counter: %w", err)
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, observer metric.Observer) error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		now := time.Now()
+		for machine, states := range m.machines {
+			states.advance(now)
+			for state, count := range states.current {
+				observer.ObserveInt64(current, count, metric.WithAttributes(
+					attribute.String("machine", machine),
+					attribute.String("state", state),
+				))
+			}
+			for state, total := range states.seconds {
+				observer.ObserveFloat64(seconds, total, metric.WithAttributes(
+					attribute.String("machine", machine),
+					attribute.String("state", state),
+				))
+			}
+		}
+		return nil
+	}, current, seconds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register state callback: %w", err)
+	}
+
+	return m, nil
+}
@@ -2,31 +2,283 @@ package telemetry
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"net/url"
 	"os"
+	"sync"
+	"time"
 
 	otelconf "go.opentelemetry.io/contrib/otelconf/v0.3.0"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/global"
 	"go.opentelemetry.io/otel/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace"
 )
 
 // Config holds telemetry configuration options
 type Config struct {
-	ConfigPath     string            // Path to YAML config file
-	ServiceName    string            // Service name override
-	ServiceVersion string            // Service version
-	Environment    string            // Environment (dev, staging, prod)
-	Attributes     map[string]string // Additional resource attributes
+	ConfigPath       string            // Path to YAML config file
+	ServiceName      string            // Service name override
+	ServiceVersion   string            // Service version
+	CommitSHA        string            // Commit of the running build, reported by RegisterBuildInfo
+	ServiceNamespace string            // Service namespace (service.namespace), omitted when empty
+	Environment      string            // Environment (dev, staging, prod)
+	Attributes       map[string]string // Additional resource attributes
+	SchemaURL        string            // Semconv schema URL of the resource, overriding the YAML schema_url
+
+	// Signal toggles, nil means enabled. A disabled signal gets a no-op provider so Tracer, Meter
+	// and Logger stay usable; with logs disabled the Logger still writes to stdout (or
+	// LogHandlers) and only the OTLP export is off.
+	TracesEnabled  *bool
+	MetricsEnabled *bool
+	LogsEnabled    *bool
+
+	// OTLPTLS configures (mutual) TLS for every OTLP exporter in the YAML file
+	OTLPTLS *TLSConfig
+
+	// MetricReaderFiles are YAML files in the ConfigPath format whose meter_provider.readers are
+	// added to those of ConfigPath, so the same metrics are exported to several backends (e.g.
+	// the collector and a vendor during a migration). Only their readers are used; views,
+	// resource and OTLPTLS come from ConfigPath. Every reader keeps its own aggregation state
+	// and exports on its own interval, so each one adds the memory and CPU of a metrics pipeline.
+	MetricReaderFiles []string
+
+	// SpanProcessors are registered on the tracer provider built from the YAML file, after the
+	// processors it declares and in slice order. The SDK calls OnStart and OnEnd of every
+	// processor in registration order, so a processor changing attributes in OnStart runs after
+	// the YAML ones (whose batch processors only read the span when it ends) and before the
+	// dynamic attributes are set. They are kept across Reconfigure and shut down with the client.
+	// A DroppedSpansProcessor here also exposes its drops as spans_dropped_total.
+	SpanProcessors []sdktrace.SpanProcessor
+
+	// TracerProvider replaces the tracer_provider of the YAML file, for providers the YAML cannot
+	// describe such as the deterministic one of the telemetrytest package. The YAML samplers and
+	// ParentSampling do not apply to it; the wrappers (RouteSampling, ForceTraceHeader,
+	// AttributeKeyCase) do. It is kept across Reconfigure and shut down with the client, and
+	// SpanProcessors must be registered on it directly. Never set it in production unless it is
+	// a real provider built with the SDK.
+	TracerProvider *sdktrace.TracerProvider
+
+	// LogMaxAttrLength truncates string log attributes longer than this many bytes (0 disables)
+	LogMaxAttrLength int
+	// LogDropAttrs lists log attribute keys that are never written
+	LogDropAttrs []string
+
+	// RouteOTelErrorsToLogger logs OpenTelemetry SDK errors through the client logger at warn
+	// level with component=otel-sdk instead of the plain stderr lines of the OTel default handler
+	RouteOTelErrorsToLogger bool
+
+	// LogSpanLifecycle logs "span started"/"span ended" at debug level for spans started with
+	// StartSpan/StartChildSpan. Noisy, meant for local debugging.
+	LogSpanLifecycle bool
+
+	// ErrorOrigin makes LogError record the file:line it was called from as error.origin on
+	// the span and error_origin on the log. It costs a runtime.Caller per call.
+	ErrorOrigin bool
+
+	// ErrorLogCounter names a counter (normalized like NewCounter, e.g. "logged_errors" becomes
+	// logged_errors_total) that LogError increments with the call context. When the span in that
+	// context is sampled the SDK attaches an exemplar with its trace and span IDs, linking counter
+	// spikes to the logged errors. Empty, or with metrics disabled, LogError records no metric.
+	ErrorLogCounter string
+
+	// LogSpanEvents also attaches every log record to the recording span of its context as an
+	// event, capped by SpanAttributeCountLimit and SpanAttributeValueLengthLimit (see
+	// WithSpanEvents). It grows the spans, so keep it for services with few logs per span.
+	LogSpanEvents bool
+
+	// LogSource adds the caller file:line as "source" to the default stdout handler
+	LogSource bool
+
+	// LogTimeKey renames the time field of the default JSON or text handler (e.g. "@timestamp"
+	// for ELK) and LogTimeFormat formats it with a time layout (e.g. time.RFC3339Nano); empty
+	// keeps slog's "time" key and RFC 3339 millisecond format. Rejected with LogHandlers and with
+	// LogFormatCloudEvents, whose time attribute is fixed by the spec.
+	LogTimeKey    string
+	LogTimeFormat string
+
+	// LogSeverityTextKey and LogSeverityNumberKey add the level text (e.g. "ERROR") and the OTel
+	// severity number (e.g. 17) as attributes of every record, for pipelines mixing ingesters;
+	// see WithSeverityAttrs. Both empty keeps only slog's level. OTLP records carry the severity
+	// natively and omit them.
+	LogSeverityTextKey   string
+	LogSeverityNumberKey string
+
+	// LogAlwaysTraceSampled writes trace_sampled=false on records of unsampled traces too (by
+	// default it is only written when true), see WithAlwaysTraceSampled
+	LogAlwaysTraceSampled bool
+
+	// LogFormat selects the default stdout handler: LogFormatJSON (the default, also used when
+	// empty), LogFormatCloudEvents or LogFormatText. Must be empty when LogHandlers is set.
+	LogFormat string
+
+	// LogColorByTrace colors the LogFormatText lines after their trace ID when stdout is a
+	// terminal, see TraceColorHandler. For local development.
+	LogColorByTrace bool
+
+	// LogHandlers replaces the default stdout JSON handler; records fan out to every handler
+	// and to the OTLP logger provider from the YAML file. Handlers writing to a buffer should
+	// implement LogFlusher so Shutdown flushes them first.
+	LogHandlers []slog.Handler
+
+	// AuditHandlers receive the records of Audit instead of the default stdout JSON handler;
+	// the records also go to the OTLP logger provider under the <service>/audit scope. The log
+	// options (levels, LogDropAttrs, truncation, OTLPLogsSampledTracesOnly) do not apply to them.
+	AuditHandlers []slog.Handler
+
+	// OTLPLogsSampledTracesOnly exports through OTLP only the records of sampled traces, plus
+	// every error and the records logged outside a trace (see WithSampledTracesOnly). The
+	// stdout handler and LogHandlers still receive everything.
+	OTLPLogsSampledTracesOnly bool
+
+	// StartupLogBuffer buffers up to this many records logged through slog.Default() while
+	// NewClient sets up the SDK, and replays them through the client logger once the providers
+	// are ready (0 disables). Records beyond the limit are dropped and a warning with the
+	// dropped count is logged after the replay.
+	StartupLogBuffer int
+
+	// Limits enforced by the span attribute helpers; 0 uses the OTel defaults
+	// (DefaultSpanAttributeCountLimit attributes per call, unlimited value length)
+	SpanAttributeCountLimit       int
+	SpanAttributeValueLengthLimit int
+
+	// RouteSampling sets the trace ID ratio of root spans per route (e.g. "/health": 0.01);
+	// other routes use RouteSamplingDefault (nil keeps them all). See NewRouteSampler for how
+	// the route is determined.
+	RouteSampling        map[string]float64
+	RouteSamplingDefault *float64
+
+	// ParentSampling replaces the YAML sampler with a parent-based one that treats remote
+	// parents (from incoming requests) and local parents differently. When nil, the
+	// OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG env vars replace it if set.
+	ParentSampling *ParentSamplingConfig
+
+	// ForceTraceHeader lets a request force its trace to be sampled by sending this header
+	// (e.g. "X-Force-Trace") through HTTPMiddleware; empty disables it. The value must equal
+	// ForceTraceSecret, or be "1"/"true" without a secret, and the client address must be in
+	// ForceTraceAllowedNetworks (CIDRs) when set. Enabling it moves sampling from the SDK to a
+	// wrapper of the tracer provider, so the YAML sampler must not be jaeger_remote.
+	ForceTraceHeader          string
+	ForceTraceSecret          string
+	ForceTraceAllowedNetworks []string
+
+	// PrioritySampling lets WithPriority force the sampling of a request tree from code. Like
+	// ForceTraceHeader it moves sampling from the SDK to a wrapper of the tracer provider, so
+	// the YAML sampler must not be jaeger_remote.
+	PrioritySampling bool
+
+	// MaxConcurrentExports bounds how many exports of the exporters in the YAML file (spans,
+	// log records and metrics, all signals together) run at the same time; 0 (the default)
+	// leaves them unbounded. Bursts pile up exports mostly behind "simple" processors, which
+	// export on the goroutine ending the span or logging; an export waiting for a slot gives
+	// up when its context is done. For processors built in code see NewLimitedSpanExporter.
+	MaxConcurrentExports int
+
+	// DebugMetrics enables DebugMetricsHandler. It keeps a second in-process copy of every
+	// metric, so leave it off in production.
+	DebugMetrics bool
+
+	// ColdStartWindow is how long after NewClient HTTPMiddleware tags requests with cold_start
+	// (the first request is always tagged). 0 uses DefaultColdStartWindow, negative disables it.
+	ColdStartWindow time.Duration
+
+	// AttributeTransform rewrites or drops attributes passed to LogWithSpanAttributes and
+	// recorded by HTTPMetrics and WorkerMetrics, enforcing naming standards in one place. For
+	// metrics it runs after the cardinality guard, on the per-call attributes, context labels
+	// and dynamic attributes. For logs it runs before LogDropAttrs, truncation and the span
+	// attribute limits, so those see the transformed keys.
+	AttributeTransform AttributeTransform
+
+	// AttributeKeyCase rewrites the attribute keys of logs, spans and the metric helpers to one
+	// convention (KeyCaseSnake or KeyCaseDot) so mixed styles such as "userId", "user-id" and
+	// "user_id" end up as a single key. The default KeyCasePassthrough keeps the keys as written
+	// and costs nothing. Otherwise every attribute pays a cached map lookup (keys are converted
+	// once, up to 4096 distinct keys) and every span goes through an extra wrapper. For metrics
+	// it runs after AttributeTransform; instruments created directly on Meter are not rewritten.
+	AttributeKeyCase KeyCase
+
+	// HTTPStatusLevel maps a status code to the level used by LogHTTPRequest (defaults to DefaultHTTPStatusLevel)
+	HTTPStatusLevel func(statusCode int) slog.Level
+
+	// Clock replaces time.Now for the time of the log records and the start, end and event
+	// timestamps of the spans that do not set one, so golden-file tests get reproducible output
+	// (see telemetrytest.FixedClock). For tests only: never set it in production, every record
+	// and span would carry the same or a made-up time.
+	Clock func() time.Time
+}
+
+// TLSConfig holds certificate paths for OTLP exporters
+type TLSConfig struct {
+	CAFile   string // CA certificate used to verify the collector
+	CertFile string // Client certificate for mutual TLS
+	KeyFile  string // Client private key for mutual TLS
 }
 
 // TelemetryClient provides easy access to OpenTelemetry functionality
 type TelemetryClient struct {
-	shutdown func(context.Context) error
-	Tracer   trace.Tracer
-	Meter    metric.Meter
-	Logger   *slog.Logger
+	// sdkMu guards the SDK state and config, replaced by Reconfigure
+	sdkMu     sync.Mutex
+	sdk       *sdkState
+	config    Config
+	providers *swapProviders
+
+	logFlushers      []LogFlusher
+	auditHandlers    []slog.Handler
+	attrTransform    AttributeTransform
+	keys             *keyNormalizer // nil unless Config.AttributeKeyCase
+	httpStatusLevel  func(statusCode int) slog.Level
+	labelGuard       *cardinalityGuard
+	dynamicAttrs     *dynamicAttrs
+	attrLimits       attributeLimits
+	debugMetrics     http.Handler
+	logSpanLifecycle bool
+	errorOrigin      bool
+	errorLogCounter  metric.Int64Counter // nil without Config.ErrorLogCounter
+	coldStart        *coldStartTracker
+	forceTrace       *forceTraceGuard
+
+	routeMetricsOnce sync.Once
+	routeMetrics     *HTTPMetrics
+	counters         sync.Map // normalized name -> metric.Int64Counter
+	countHistograms  sync.Map // normalized name -> metric.Int64Histogram, see RecordCount
+	eventInstruments sync.Map // metric name -> metric.Float64Counter or Float64Histogram, see Record
+
+	codecMetricsOnce sync.Once
+	codecMetrics     *codecMetrics
+
+	renderMetricsOnce sync.Once
+	renderMetrics     *renderMetrics
+
+	dbMetricsOnce sync.Once
+	dbMetrics     *dbMetrics
+
+	dependencyMetricsOnce sync.Once
+	dependencyMetrics     *dependencyMetrics
+
+	rateLimitMetricsOnce sync.Once
+	rateLimitMetrics     *rateLimitMetrics
+
+	stateMetricsOnce sync.Once
+	stateMetrics     *stateMetrics
+
+	serviceName    string
+	serviceVersion string
+	commitSHA      string
+
+	tenantOverflowOnce sync.Once
+	buildInfoOnce      sync.Once
+	buildInfoErr       error
+	processMetricsOnce sync.Once
+	processMetricsErr  error
+
+	Tracer trace.Tracer
+	Meter  metric.Meter
+	Logger *slog.Logger
 }
 
 // Setup initializes OpenTelemetry with configuration file
@@ -36,6 +288,51 @@ func Setup(ctx context.Context, confPath string) (func(context.Context) error, e
 
 // SetupWithConfig initializes OpenTelemetry with detailed configuration
 func SetupWithConfig(ctx context.Context, config Config) (func(context.Context) error, error) {
+	state, err := setupSDK(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	if len(config.SpanProcessors) == 0 && config.TracerProvider == nil {
+		return state.shutdown, nil
+	}
+	return func(ctx context.Context) error {
+		return errors.Join(state.shutdown(ctx), shutdownSpanProcessors(ctx, config.SpanProcessors),
+			shutdownTracerProvider(ctx, config.TracerProvider))
+	}, nil
+}
+
+// sdkState holds the providers set as global by the SDK setup and what NewClient needs besides them
+type sdkState struct {
+	shutdown       func(context.Context) error
+	debugMetrics   http.Handler
+	tracerProvider *sdktrace.TracerProvider // nil when traces are disabled
+
+	tracer trace.TracerProvider
+	meter  metric.MeterProvider
+	logger log.LoggerProvider
+}
+
+func setupSDK(ctx context.Context, config Config) (*sdkState, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return buildSDK(ctx, config)
+}
+
+// shutdownTracerProvider shuts down Config.TracerProvider, which the SDK shutdown leaves alone
+// since it outlives Reconfigure
+func shutdownTracerProvider(ctx context.Context, provider *sdktrace.TracerProvider) error {
+	if provider == nil {
+		return nil
+	}
+	if err := provider.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to shut down tracer provider: %w", err)
+	}
+	return nil
+}
+
+// buildSDK creates the SDK from the YAML file and config and sets its providers as global
+func buildSDK(ctx context.Context, config Config) (*sdkState, error) {
 	b, err := os.ReadFile(config.ConfigPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
@@ -51,6 +348,9 @@ func SetupWithConfig(ctx context.Context, config Config) (func(context.Context)
 	if config.Environment != "" {
 		os.Setenv("ENVIRONMENT", config.Environment)
 	}
+	if config.ServiceNamespace != "" {
+		os.Setenv("SERVICE_NAMESPACE", config.ServiceNamespace)
+	}
 
 	// Additional attributes
 	for key, value := range config.Attributes {
@@ -64,19 +364,326 @@ func SetupWithConfig(ctx context.Context, config Config) (func(context.Context)
 		return nil, fmt.Errorf("failed to parse YAML config: %w", err)
 	}
 
+	if config.ServiceNamespace != "" {
+		setResourceAttribute(conf, "service.namespace", config.ServiceNamespace)
+	}
+	if config.SchemaURL != "" {
+		if err := setResourceSchemaURL(conf, config.SchemaURL); err != nil {
+			return nil, err
+		}
+	}
+	if !isEnabled(config.TracesEnabled) || config.TracerProvider != nil {
+		conf.TracerProvider = nil
+	}
+	if !isEnabled(config.MetricsEnabled) {
+		conf.MeterProvider = nil
+	}
+	if !isEnabled(config.LogsEnabled) {
+		conf.LoggerProvider = nil
+	}
+	var extraReaders []otelconf.MetricReader
+	if len(config.MetricReaderFiles) > 0 && isEnabled(config.MetricsEnabled) {
+		if extraReaders, err = readMetricReaders(config.MetricReaderFiles); err != nil {
+			return nil, err
+		}
+		if conf.MeterProvider == nil {
+			conf.MeterProvider = &otelconf.MeterProvider{}
+		}
+	}
+	useExponentialHistogram(conf, exponentialHistogramMeter, "http_request_duration_seconds")
+	// Sampler precedence: Config fields, then the OTEL_TRACES_SAMPLER env vars, then the YAML
+	if config.ParentSampling != nil {
+		applyParentSampling(conf, *config.ParentSampling)
+	} else {
+		applyEnvSampler(conf)
+	}
+	var forcedSampler sdktrace.Sampler
+	if (config.ForceTraceHeader != "" || config.PrioritySampling) && conf.TracerProvider != nil {
+		option := "ForceTraceHeader"
+		if config.ForceTraceHeader == "" {
+			option = "PrioritySampling"
+		}
+		if forcedSampler, err = takeOverSampler(conf, option); err != nil {
+			return nil, err
+		}
+	}
+	if config.OTLPTLS != nil {
+		if err := applyOTLPTLS(conf, *config.OTLPTLS); err != nil {
+			return nil, err
+		}
+	}
+	// Added after OTLPTLS, which only targets the exporters of ConfigPath
+	if len(extraReaders) > 0 {
+		conf.MeterProvider.Readers = append(conf.MeterProvider.Readers, extraReaders...)
+	}
+
+	yamlPipelines := takePipelines(conf)
+
 	sdk, err := otelconf.NewSDK(otelconf.WithContext(ctx), otelconf.WithOpenTelemetryConfiguration(*conf))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create OpenTelemetry SDK: %w", err)
 	}
+	pipelines, err := yamlPipelines.build(ctx, newExportLimiter(config.MaxConcurrentExports))
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to create OpenTelemetry SDK: %w", err), sdk.Shutdown(ctx))
+	}
+	shutdown := func(ctx context.Context) error {
+		return errors.Join(pipelines.shutdown(ctx), sdk.Shutdown(ctx))
+	}
+
+	sdkTracerProvider, _ := sdk.TracerProvider().(*sdktrace.TracerProvider)
+	if config.TracerProvider != nil {
+		sdkTracerProvider = config.TracerProvider
+	}
+	var tracerProvider trace.TracerProvider = sdk.TracerProvider()
+	if sdkTracerProvider != nil {
+		tracerProvider = sdkTracerProvider
+	}
+	if config.Clock != nil {
+		tracerProvider = &clockTracerProvider{provider: tracerProvider, clock: config.Clock}
+	}
+	if keys := newKeyNormalizer(config.AttributeKeyCase); keys != nil {
+		// Innermost, so the samplers still match the keys as written (e.g. http.route)
+		tracerProvider = &keyCaseTracerProvider{provider: tracerProvider, keys: keys}
+	}
+	if forcedSampler != nil {
+		tracerProvider = &samplingTracerProvider{provider: tracerProvider, sampler: forceSampler{base: forcedSampler}}
+	}
+	if len(config.RouteSampling) > 0 {
+		defaultRatio := 1.0
+		if config.RouteSamplingDefault != nil {
+			defaultRatio = *config.RouteSamplingDefault
+		}
+		tracerProvider = &rootSamplingTracerProvider{
+			provider: tracerProvider,
+			sampler:  NewRouteSampler(config.RouteSampling, defaultRatio),
+		}
+	}
+
+	state := &sdkState{shutdown: orderedShutdown(pipelines.logger, sdk.TracerProvider(), pipelines.meter, shutdown)}
+	state.tracerProvider = sdkTracerProvider
+	if state.tracerProvider != nil {
+		// The YAML processors first, as otelconf would have registered them
+		for _, processor := range pipelines.spanProcessors {
+			state.tracerProvider.RegisterSpanProcessor(processor)
+		}
+		if config.TracerProvider == nil {
+			for _, processor := range config.SpanProcessors {
+				state.tracerProvider.RegisterSpanProcessor(retainedProcessor{processor})
+			}
+		}
+	}
+	meterProvider := pipelines.meter
+	if config.DebugMetrics {
+		debugProvider, handler, err := newDebugMeterProvider(meterProvider)
+		if err != nil {
+			return nil, errors.Join(err, shutdown(ctx))
+		}
+		meterProvider, state.debugMetrics = debugProvider, handler
+		state.shutdown = orderedShutdown(pipelines.logger, sdk.TracerProvider(), pipelines.meter, func(ctx context.Context) error {
+			return errors.Join(shutdown(ctx), debugProvider.shutdown(ctx))
+		})
+	}
+
+	state.tracer, state.meter, state.logger = tracerProvider, meterProvider, pipelines.logger
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetMeterProvider(meterProvider)
+	global.SetLoggerProvider(pipelines.logger)
+
+	// Export failures are otherwise only printed by the default handler
+	exportErrors.routeToLogger.Store(config.RouteOTelErrorsToLogger)
+	otel.SetErrorHandler(exportErrors)
+	if err := exportErrors.registerExportFailures(otel.Meter("github.com/mmacanmunhoz/otel-helpers/telemetry")); err != nil {
+		return nil, errors.Join(err, state.shutdown(ctx))
+	}
+	return state, nil
+}
+
+// orderedShutdown flushes the providers in a fixed order before shutting them down: logs
+// first, so the last lines (including those about the trace and metric exports) are not
+// lost, then traces, then metrics. Every step runs even if a previous one failed.
+func orderedShutdown(logs log.LoggerProvider, traces trace.TracerProvider, metrics metric.MeterProvider, shutdown func(context.Context) error) func(context.Context) error {
+	return func(ctx context.Context) error {
+		var errs []error
+		for _, provider := range []struct {
+			signal   string
+			provider any
+		}{
+			{"logs", logs},
+			{"traces", traces},
+			{"metrics", metrics},
+		} {
+			// The noop providers of disabled signals have nothing to flush
+			flusher, ok := provider.provider.(interface{ ForceFlush(context.Context) error })
+			if !ok {
+				continue
+			}
+			if err := flusher.ForceFlush(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("failed to flush %s: %w", provider.signal, err))
+			}
+		}
+		if err := shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to shut down providers: %w", err))
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// setResourceAttribute sets a resource attribute, overriding any value from the YAML file
+func setResourceAttribute(conf *otelconf.OpenTelemetryConfiguration, name string, value any) {
+	if conf.Resource == nil {
+		conf.Resource = &otelconf.Resource{}
+	}
+	for i, attr := range conf.Resource.Attributes {
+		if attr.Name == name {
+			conf.Resource.Attributes[i].Value = value
+			return
+		}
+	}
+	conf.Resource.Attributes = append(conf.Resource.Attributes, otelconf.AttributeNameValue{Name: name, Value: value})
+}
+
+// setResourceSchemaURL validates schemaURL and sets it on the resource, which otelconf then
+// builds with resource.NewWithAttributes
+func setResourceSchemaURL(conf *otelconf.OpenTelemetryConfiguration, schemaURL string) error {
+	parsed, err := url.Parse(schemaURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid SchemaURL %q: must be an absolute http(s) URL, e.g. https://opentelemetry.io/schemas/1.26.0", schemaURL)
+	}
+	if conf.Resource == nil {
+		conf.Resource = &otelconf.Resource{}
+	}
+	conf.Resource.SchemaUrl = &schemaURL
+	return nil
+}
 
-	otel.SetTracerProvider(sdk.TracerProvider())
-	otel.SetMeterProvider(sdk.MeterProvider())
-	return sdk.Shutdown, nil
+// useExponentialHistogram adds a view giving the named instrument of meterName a base-2
+// exponential histogram aggregation. Instruments of the same name in other meters are unaffected.
+func useExponentialHistogram(conf *otelconf.OpenTelemetryConfiguration, meterName, instrumentName string) {
+	if conf.MeterProvider == nil {
+		return
+	}
+
+	maxSize, maxScale, recordMinMax := 160, 20, true
+	conf.MeterProvider.Views = append(conf.MeterProvider.Views, otelconf.View{
+		Selector: &otelconf.ViewSelector{InstrumentName: &instrumentName, MeterName: &meterName},
+		Stream: &otelconf.ViewStream{Aggregation: &otelconf.ViewStreamAggregation{
+			Base2ExponentialBucketHistogram: &otelconf.ViewStreamAggregationBase2ExponentialBucketHistogram{
+				MaxSize:      &maxSize,
+				MaxScale:     &maxScale,
+				RecordMinMax: &recordMinMax,
+			},
+		}},
+	})
+}
+
+// applyOTLPTLS validates the certificate files and sets them on all OTLP exporters
+func applyOTLPTLS(conf *otelconf.OpenTelemetryConfiguration, tlsConfig TLSConfig) error {
+	if (tlsConfig.CertFile == "") != (tlsConfig.KeyFile == "") {
+		return fmt.Errorf("OTLP TLS: CertFile and KeyFile must be set together")
+	}
+
+	var caFile, certFile, keyFile *string
+	for _, file := range []struct {
+		name string
+		path string
+		dst  **string
+	}{
+		{"CA certificate", tlsConfig.CAFile, &caFile},
+		{"client certificate", tlsConfig.CertFile, &certFile},
+		{"client key", tlsConfig.KeyFile, &keyFile},
+	} {
+		if file.path == "" {
+			continue
+		}
+		if _, err := os.ReadFile(file.path); err != nil {
+			return fmt.Errorf("OTLP TLS: failed to read %s %q: %w", file.name, file.path, err)
+		}
+		path := file.path
+		*file.dst = &path
+	}
+
+	setTLS := func(otlp *otelconf.OTLP) {
+		if otlp != nil {
+			otlp.Certificate, otlp.ClientCertificate, otlp.ClientKey = caFile, certFile, keyFile
+		}
+	}
+
+	if conf.TracerProvider != nil {
+		for _, processor := range conf.TracerProvider.Processors {
+			if processor.Batch != nil {
+				setTLS(processor.Batch.Exporter.OTLP)
+			}
+			if processor.Simple != nil {
+				setTLS(processor.Simple.Exporter.OTLP)
+			}
+		}
+	}
+	if conf.LoggerProvider != nil {
+		for _, processor := range conf.LoggerProvider.Processors {
+			if processor.Batch != nil {
+				setTLS(processor.Batch.Exporter.OTLP)
+			}
+			if processor.Simple != nil {
+				setTLS(processor.Simple.Exporter.OTLP)
+			}
+		}
+	}
+	if conf.MeterProvider != nil {
+		for _, reader := range conf.MeterProvider.Readers {
+			if reader.Periodic != nil && reader.Periodic.Exporter.OTLP != nil {
+				otlp := reader.Periodic.Exporter.OTLP
+				otlp.Certificate, otlp.ClientCertificate, otlp.ClientKey = caFile, certFile, keyFile
+			}
+		}
+	}
+
+	return nil
+}
+
+// readMetricReaders returns the meter_provider.readers of the YAML files, with the same
+// environment variable substitution as ConfigPath
+func readMetricReaders(paths []string) ([]otelconf.MetricReader, error) {
+	var readers []otelconf.MetricReader
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read metric readers file: %w", err)
+		}
+		conf, err := otelconf.ParseYAML([]byte(os.ExpandEnv(string(b))))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse metric readers file %s: %w", path, err)
+		}
+		if conf.MeterProvider == nil || len(conf.MeterProvider.Readers) == 0 {
+			return nil, fmt.Errorf("metric readers file %s has no meter_provider.readers", path)
+		}
+		readers = append(readers, conf.MeterProvider.Readers...)
+	}
+	return readers, nil
 }
 
 // NewClient creates a new telemetry client with common functionality
 func NewClient(ctx context.Context, config Config) (*TelemetryClient, error) {
-	shutdown, err := SetupWithConfig(ctx, config)
+	var startupBuffer *bufferingHandler
+	if config.StartupLogBuffer > 0 {
+		startupBuffer = newBufferingHandler(config.StartupLogBuffer)
+		previous := slog.Default()
+		slog.SetDefault(slog.New(startupBuffer))
+		defer func() {
+			slog.SetDefault(previous)
+			if startupBuffer != nil {
+				// Setup failed, hand the records back to the original default logger
+				startupBuffer.replay(previous.Handler())
+			}
+		}()
+	}
+
+	forceTrace, err := newForceTraceGuard(config)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := setupSDK(ctx, config)
 	if err != nil {
 		return nil, err
 	}
@@ -87,17 +694,189 @@ func NewClient(ctx context.Context, config Config) (*TelemetryClient, error) {
 	}
 
 	// Create logger with correlation support
-	logger := NewCorrelatedLogger(slog.NewJSONHandler(os.Stdout, nil))
+	handlerOptions := &slog.HandlerOptions{AddSource: config.LogSource}
+	var handlers []slog.Handler
+	switch config.LogFormat {
+	case "", LogFormatJSON:
+		jsonOptions := *handlerOptions
+		jsonOptions.ReplaceAttr = replaceTimeAttr(config.LogTimeKey, config.LogTimeFormat)
+		handlers = []slog.Handler{slog.NewJSONHandler(os.Stdout, &jsonOptions)}
+	case LogFormatCloudEvents:
+		handlers = []slog.Handler{NewCloudEventsHandler(os.Stdout, serviceName, handlerOptions)}
+	case LogFormatText:
+		textOptions := *handlerOptions
+		textOptions.ReplaceAttr = replaceTimeAttr(config.LogTimeKey, config.LogTimeFormat)
+		if config.LogColorByTrace {
+			handlers = []slog.Handler{NewTraceColorHandler(os.Stdout, &textOptions)}
+		} else {
+			handlers = []slog.Handler{slog.NewTextHandler(os.Stdout, &textOptions)}
+		}
+	default:
+		return nil, errors.Join(fmt.Errorf("unsupported log format %q", config.LogFormat), state.shutdown(ctx))
+	}
+	if len(config.LogHandlers) > 0 {
+		handlers = append([]slog.Handler{}, config.LogHandlers...)
+	}
+	// The client and the globals use the swap providers, so Reconfigure can replace the SDK
+	providers := newSwapProviders(state)
+	providers.install()
 
-	return &TelemetryClient{
-		shutdown: shutdown,
-		Tracer:   otel.Tracer(serviceName),
-		Meter:    otel.Meter(serviceName),
-		Logger:   logger,
-	}, nil
+	var otlpOpts []OTLPHandlerOption
+	if config.OTLPLogsSampledTracesOnly {
+		otlpOpts = append(otlpOpts, WithSampledTracesOnly())
+	}
+	otlpHandler := NewOTLPHandler(providers.logger, serviceName, otlpOpts...)
+	// Disabled logs only skip the OTLP export, stdout (or LogHandlers) keeps the records
+	if isEnabled(config.LogsEnabled) {
+		handlers = append(handlers, otlpHandler)
+	}
+	auditHandlers := []slog.Handler{slog.NewJSONHandler(os.Stdout, handlerOptions)}
+	if len(config.AuditHandlers) > 0 {
+		auditHandlers = append([]slog.Handler{}, config.AuditHandlers...)
+	}
+	auditOTLPHandler := NewOTLPHandler(providers.logger, serviceName+"/audit")
+	auditHandlers = append(auditHandlers, auditOTLPHandler)
+
+	var logFlushers []LogFlusher
+	for _, handler := range append(handlers, config.AuditHandlers...) {
+		if flusher, ok := handler.(LogFlusher); ok {
+			logFlushers = append(logFlushers, flusher)
+		}
+	}
+	baseHandler := NewMultiHandler(handlers...)
+	dynamic := newDynamicAttrs()
+	handlerOpts := []HandlerOption{withDynamicAttrs(dynamic), WithRPCAttrs()}
+	if config.LogMaxAttrLength > 0 {
+		handlerOpts = append(handlerOpts, WithAttrTruncation(config.LogMaxAttrLength))
+	}
+	if len(config.LogDropAttrs) > 0 {
+		handlerOpts = append(handlerOpts, WithDroppedAttrs(config.LogDropAttrs...))
+	}
+	if config.LogSpanEvents {
+		handlerOpts = append(handlerOpts, WithSpanEvents(config.SpanAttributeCountLimit, config.SpanAttributeValueLengthLimit))
+	}
+	if config.LogAlwaysTraceSampled {
+		handlerOpts = append(handlerOpts, WithAlwaysTraceSampled())
+	}
+	if config.Clock != nil {
+		handlerOpts = append(handlerOpts, withClock(config.Clock))
+	}
+	keys := newKeyNormalizer(config.AttributeKeyCase)
+	if keys != nil {
+		handlerOpts = append(handlerOpts, WithKeyCase(config.AttributeKeyCase))
+	}
+	if config.LogSeverityTextKey != "" || config.LogSeverityNumberKey != "" {
+		numberKey := config.LogSeverityNumberKey
+		if numberKey == "" {
+			numberKey = DefaultSeverityNumberKey
+		}
+		handlerOpts = append(handlerOpts, WithSeverityAttrs(config.LogSeverityTextKey, numberKey))
+		// The OTLP handler sees the keys after WithKeyCase
+		otlpHandler.severityKeys = map[string]bool{keys.key(config.LogSeverityTextKey): true, keys.key(numberKey): true}
+	}
+	logger := NewCorrelatedLogger(baseHandler, handlerOpts...)
+	exportErrors.setLogger(logger)
+	if startupBuffer != nil {
+		startupBuffer.replay(logger.Handler())
+		startupBuffer = nil
+	}
+
+	if state.tracerProvider != nil {
+		state.tracerProvider.RegisterSpanProcessor(dynamicAttrsProcessor{dynamic: dynamic, keys: keys})
+	}
+
+	httpStatusLevel := config.HTTPStatusLevel
+	if httpStatusLevel == nil {
+		httpStatusLevel = DefaultHTTPStatusLevel
+	}
+
+	c := &TelemetryClient{
+		sdk:              state,
+		config:           config,
+		providers:        providers,
+		logFlushers:      logFlushers,
+		auditHandlers:    auditHandlers,
+		attrTransform:    keys.transform(config.AttributeTransform),
+		keys:             keys,
+		serviceName:      serviceName,
+		serviceVersion:   config.ServiceVersion,
+		commitSHA:        config.CommitSHA,
+		httpStatusLevel:  httpStatusLevel,
+		labelGuard:       newCardinalityGuard(),
+		dynamicAttrs:     dynamic,
+		logSpanLifecycle: config.LogSpanLifecycle,
+		errorOrigin:      config.ErrorOrigin,
+		coldStart:        newColdStartTracker(config.ColdStartWindow),
+		forceTrace:       forceTrace,
+		attrLimits:       newAttributeLimits(config.SpanAttributeCountLimit, config.SpanAttributeValueLengthLimit),
+		Tracer:           providers.tracer.Tracer(serviceName),
+		Meter:            providers.meter.Meter(serviceName),
+		Logger:           logger,
+	}
+	if state.debugMetrics != nil {
+		c.debugMetrics = http.HandlerFunc(c.serveDebugMetrics)
+	}
+	if config.ErrorLogCounter != "" && isEnabled(config.MetricsEnabled) {
+		if c.errorLogCounter, err = c.NewCounter(config.ErrorLogCounter, "Total number of errors logged with LogError"); err != nil {
+			return nil, errors.Join(err, state.shutdown(ctx))
+		}
+	}
+	if isEnabled(config.MetricsEnabled) {
+		if err := registerDroppedSpans(c.Meter, config.SpanProcessors); err != nil {
+			return nil, errors.Join(err, state.shutdown(ctx))
+		}
+	}
+	return c, nil
 }
 
-// Shutdown gracefully shuts down telemetry
+// Bool returns a pointer to v, for the optional Config toggles
+func Bool(v bool) *bool {
+	return &v
+}
+
+// Float64 returns a pointer to v, for the optional Config ratios
+func Float64(v float64) *float64 {
+	return &v
+}
+
+func isEnabled(toggle *bool) bool {
+	return toggle == nil || *toggle
+}
+
+// contextOrBackground normalizes a nil context so helpers never panic on misuse
+func contextOrBackground(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
+
+// LogFlusher is implemented by log handlers that buffer their output (e.g. a JSON handler over
+// a bufio.Writer). Shutdown flushes them before the providers.
+type LogFlusher interface {
+	Flush() error
+}
+
+// Shutdown gracefully shuts down telemetry, in order: the LogFlusher handlers, then the
+// logger, tracer and meter providers are flushed, then the providers are shut down, and
+// finally Config.SpanProcessors and Config.TracerProvider. The errors of every step are joined.
 func (c *TelemetryClient) Shutdown(ctx context.Context) error {
-	return c.shutdown(ctx)
+	var errs []error
+	for _, flusher := range c.logFlushers {
+		if err := flusher.Flush(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to flush log handler: %w", err))
+		}
+	}
+	c.sdkMu.Lock()
+	defer c.sdkMu.Unlock()
+	if err := c.sdk.shutdown(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if err := shutdownSpanProcessors(ctx, c.config.SpanProcessors); err != nil {
+		errs = append(errs, err)
+	}
+	if err := shutdownTracerProvider(ctx, c.config.TracerProvider); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
 }
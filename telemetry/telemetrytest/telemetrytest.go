@@ -0,0 +1,88 @@
+// Package telemetrytest makes the output of a telemetry client reproducible for golden-file
+// (snapshot) tests: trace and span IDs come from a counter and every timestamp from a fixed
+// clock, so the correlated log lines and the recorded spans are the same on every run.
+//
+// It is for tests only and must never be used in production: the IDs repeat across processes,
+// so traces from different instances would be merged by the backend, and every record and span
+// would carry the same time.
+package telemetrytest
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/mmacanmunhoz/otel-helpers/telemetry"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Epoch is a convenient fixed time for FixedClock and Deterministic
+var Epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// FixedClock returns a clock that always reads t, for telemetry.Config.Clock
+func FixedClock(t time.Time) func() time.Time {
+	return func() time.Time { return t }
+}
+
+// IDGenerator is an sdktrace.IDGenerator handing out sequential IDs: the first trace is
+// 00000000000000000000000000000001 and the first span 0000000000000001. The trace and span
+// counters are shared by every trace, so the IDs depend only on the order spans start in.
+type IDGenerator struct {
+	mu      sync.Mutex
+	traceID uint64
+	spanID  uint64
+}
+
+// NewIDGenerator returns an IDGenerator starting at 1
+func NewIDGenerator() *IDGenerator {
+	return &IDGenerator{}
+}
+
+// NewIDs returns the next trace ID and span ID
+func (g *IDGenerator) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.traceID++
+	g.spanID++
+	var traceID trace.TraceID
+	binary.BigEndian.PutUint64(traceID[8:], g.traceID)
+	return traceID, spanID(g.spanID)
+}
+
+// NewSpanID returns the next span ID
+func (g *IDGenerator) NewSpanID(ctx context.Context, traceID trace.TraceID) trace.SpanID {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.spanID++
+	return spanID(g.spanID)
+}
+
+func spanID(n uint64) trace.SpanID {
+	var id trace.SpanID
+	binary.BigEndian.PutUint64(id[:], n)
+	return id
+}
+
+// NewTracerProvider returns a tracer provider sampling every span, with sequential IDs, that
+// exports synchronously to exporter so the spans are there as soon as they end
+func NewTracerProvider(exporter sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithIDGenerator(NewIDGenerator()),
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithSyncer(exporter),
+	)
+}
+
+// Deterministic returns config with a tracer provider from NewTracerProvider recording into
+// the returned exporter, replacing the tracer_provider of the YAML file, and a clock fixed at
+// start. Pass the result to telemetry.NewClient; LogHandlers writing to a buffer then get the
+// same lines on every run. Never use it outside tests.
+func Deterministic(config telemetry.Config, start time.Time) (telemetry.Config, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	config.TracerProvider = NewTracerProvider(exporter)
+	config.Clock = FixedClock(start)
+	return config, exporter
+}
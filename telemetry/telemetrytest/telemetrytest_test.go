@@ -0,0 +1,82 @@
+package telemetrytest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mmacanmunhoz/otel-helpers/telemetry"
+)
+
+// run builds a Deterministic client, logs and records a span tree with it, and returns the log
+// lines and the recorded spans
+func run(t *testing.T) (logs, spans []byte) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "otel.yaml")
+	if err := os.WriteFile(path, []byte("file_format: \"0.3\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	config, exporter := Deterministic(telemetry.Config{
+		ConfigPath:  path,
+		LogHandlers: []slog.Handler{slog.NewJSONHandler(&buf, nil)},
+	}, Epoch)
+	client, err := telemetry.NewClient(context.Background(), config)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	ctx, parent := client.StartSpan(context.Background(), "parent")
+	client.Logger.InfoContext(ctx, "in parent", "step", 1)
+	childCtx, child := client.StartSpan(ctx, "child")
+	client.AddSpanEvent(childCtx, "cache_miss", map[string]any{"key": "user:1"})
+	client.Logger.WarnContext(childCtx, "in child")
+	child.End()
+	parent.End()
+	// Shutdown resets the in-memory exporter
+	recorded := exporter.GetSpans()
+	if err := client.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	var out bytes.Buffer
+	for _, span := range recorded {
+		fmt.Fprintf(&out, "%s trace=%s span=%s parent=%s start=%s end=%s\n", span.Name,
+			span.SpanContext.TraceID(), span.SpanContext.SpanID(), span.Parent.SpanID(),
+			span.StartTime.Format(time.RFC3339Nano), span.EndTime.Format(time.RFC3339Nano))
+		for _, event := range span.Events {
+			fmt.Fprintf(&out, "  event %s time=%s attrs=%v\n", event.Name,
+				event.Time.Format(time.RFC3339Nano), event.Attributes)
+		}
+	}
+	return buf.Bytes(), out.Bytes()
+}
+
+func TestDeterministic(t *testing.T) {
+	firstLogs, firstSpans := run(t)
+	secondLogs, secondSpans := run(t)
+
+	if !bytes.Equal(firstLogs, secondLogs) {
+		t.Errorf("log lines differ between runs:\n%s\nthen\n%s", firstLogs, secondLogs)
+	}
+	if !bytes.Equal(firstSpans, secondSpans) {
+		t.Errorf("spans differ between runs:\n%s\nthen\n%s", firstSpans, secondSpans)
+	}
+
+	// The runs must record something worth comparing: correlated logs and the span tree
+	for _, want := range []string{`"time":"2024-01-01T00:00:00Z"`, `"trace_id":"00000000000000000000000000000001"`, `"msg":"in child"`} {
+		if !bytes.Contains(firstLogs, []byte(want)) {
+			t.Errorf("logs do not contain %s:\n%s", want, firstLogs)
+		}
+	}
+	for _, want := range []string{"child trace=00000000000000000000000000000001 span=0000000000000002 parent=0000000000000001 start=2024-01-01T00:00:00Z", "event cache_miss time=2024-01-01T00:00:00Z"} {
+		if !bytes.Contains(firstSpans, []byte(want)) {
+			t.Errorf("spans do not contain %q:\n%s", want, firstSpans)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package telemetry
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// MaxTenantMeters is the number of distinct tenants TenantMeter creates meters for. Later
+// tenants share the meter of the "other" tenant.
+const MaxTenantMeters = maxLabelValues
+
+// TenantMeter returns a meter for tenant ("unknown" when empty), with the tenant attribute set
+// on its instrumentation scope. Each tenant gets its own instruments, so the attributes and the
+// SDK cardinality limits of one tenant do not affect the series of another. Meters are cached:
+// calling it again with the same tenant returns the same meter. Past MaxTenantMeters distinct
+// tenants, new tenants get the meter of tenant "other" and a warning is logged once.
+func (c *TelemetryClient) TenantMeter(tenant string) metric.Meter {
+	if tenant == "" {
+		tenant = "unknown"
+	}
+	scoped := c.labelGuard.value("tenant.meter", tenant)
+	if scoped == overflowLabelValue && tenant != overflowLabelValue {
+		c.tenantOverflowOnce.Do(func() {
+			c.Logger.Warn("tenant meter limit reached, further tenants share the \"other\" meter",
+				"limit", MaxTenantMeters,
+				"tenant", tenant,
+			)
+		})
+	}
+	return c.providers.meter.Meter(c.serviceName, metric.WithInstrumentationAttributes(attribute.String("tenant", scoped)))
+}
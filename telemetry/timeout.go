@@ -0,0 +1,229 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TimeoutMiddleware runs the handler with a context that expires after d. If the handler has
+// not returned by then the request is answered with 503 (like http.TimeoutHandler), marked as an
+// error on the span and logged. Place it inside HTTPMiddleware (or use WithTimeout) so the span
+// and the request metrics cover it: HTTPMiddleware then counts the request once in
+// http_errors_total with error_type="handler_timeout" instead of "server_error", under its
+// route and metrics. On its own it records the timeout to the WithHTTPMetrics metrics (the
+// shared ones by default) under the WithRoute route, or the path capped like the other labels.
+// WithStructuredErrors applies to the 503 body; the other options are ignored.
+//
+// A panic of the handler after the timeout can no longer reach the response; it is recorded
+// like the recovered panics of HTTPMiddleware (logged and counted with source=http).
+//
+// Unlike http.TimeoutHandler the response is not buffered, so streaming keeps working: when the
+// handler already started responding the timeout is still recorded, but the status cannot
+// change and the response is cut short. Writes after the timeout fail with http.ErrHandlerTimeout.
+func (c *TelemetryClient) TimeoutMiddleware(d time.Duration, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	cfg := &middlewareConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			r = r.WithContext(ctx)
+
+			tw := &timeoutWriter{w: w, header: w.Header().Clone()}
+			done := make(chan struct{})
+			panicked := make(chan any)
+			abandoned := make(chan struct{}) // closed once nobody waits for the handler
+			defer close(abandoned)
+			go func() {
+				defer func() {
+					recovered := recover()
+					if recovered == nil {
+						return
+					}
+					select {
+					case panicked <- recovered:
+					case <-abandoned:
+						if recovered != http.ErrAbortHandler {
+							c.recordPanic(ctx, recovered, "http", "panic in HTTP handler after timeout or client disconnect")
+						}
+					}
+				}()
+				next.ServeHTTP(tw, r)
+				close(done)
+			}()
+
+			select {
+			case recovered := <-panicked:
+				// Re-panic in the serving goroutine so HTTPMiddleware can recover it
+				panic(recovered)
+			case <-done:
+				return
+			case <-ctx.Done():
+			}
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				// The client went away, there is nobody to answer
+				return
+			}
+
+			wroteHeader := tw.timeout(func() {
+				cfg.writeError(ctx, w, r, http.StatusServiceUnavailable)
+			})
+
+			span := trace.SpanFromContext(ctx)
+			span.SetStatus(codes.Error, "handler timeout")
+			span.RecordError(fmt.Errorf("handler did not complete within %s", d))
+			if !markRequestError(ctx, "handler_timeout") {
+				httpMetrics := cfg.httpMetrics
+				if httpMetrics == nil {
+					httpMetrics = c.sharedHTTPMetrics()
+				}
+				route := cfg.route
+				if route == "" {
+					route = c.labelGuard.value("http.route", r.URL.Path)
+				}
+				httpMetrics.RecordError(ctx, "handler_timeout", route)
+			}
+			c.Logger.WarnContext(ctx, "HTTP handler timed out",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"timeout", d.String(),
+				"response_started", wroteHeader,
+			)
+		})
+	}
+}
+
+// timeoutWriter forwards the handler writes until the timeout, then rejects them. The handler
+// gets its own header map so the timeout response never races with it.
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+
+	mu          sync.Mutex
+	timedOut    bool
+	wroteHeader bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(statusCode int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.writeHeaderLocked(statusCode)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+	return tw.w.Write(b)
+}
+
+func (tw *timeoutWriter) writeHeaderLocked(statusCode int) {
+	dst := tw.w.Header()
+	for key, values := range tw.header {
+		dst[key] = values
+	}
+	tw.wroteHeader = true
+	tw.w.WriteHeader(statusCode)
+}
+
+// Flush keeps streaming (SSE) handlers working through the wrapper
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	if flusher, ok := tw.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// timeout stops forwarding writes and, if the handler has not responded yet, runs respond.
+// It reports whether the handler had already started the response.
+func (tw *timeoutWriter) timeout(respond func()) bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.timedOut = true
+	if !tw.wroteHeader {
+		respond()
+	}
+	return tw.wroteHeader
+}
+
+// Outcomes recorded by RunWithDeadline
+const (
+	OutcomeCompleted = "completed"
+	OutcomeError     = "error"
+	OutcomeTimeout   = "timeout"
+	OutcomeCanceled  = "canceled"
+)
+
+// RunWithDeadline runs fn in a name span with a context that expires after d, and records how
+// it ended in deadline_operations_total, labeled with operation and outcome: completed, error
+// (fn failed before the deadline), timeout (the deadline passed) or canceled (the parent context
+// was canceled). fn must honor the context; it runs on the calling goroutine, so RunWithDeadline
+// returns only when fn does. Every outcome but completed sets an error status on the span. The
+// error of fn is returned, joined with the context error when fn did not wrap it, so
+// errors.Is(err, context.DeadlineExceeded) holds on timeouts.
+func (c *TelemetryClient) RunWithDeadline(ctx context.Context, name string, d time.Duration, fn func(ctx context.Context) error) error {
+	ctx, span := c.StartSpan(ctx, name, trace.WithAttributes(attribute.Int64("deadline.timeout_ms", d.Milliseconds())))
+	defer span.End()
+
+	deadlineCtx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+	err := fn(deadlineCtx)
+
+	outcome := OutcomeCompleted
+	if ctxErr := deadlineCtx.Err(); err != nil && ctxErr != nil {
+		outcome = OutcomeTimeout
+		if ctx.Err() != nil && !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			outcome = OutcomeCanceled
+		}
+		if !errors.Is(err, ctxErr) {
+			err = errors.Join(err, ctxErr)
+		}
+	} else if err != nil {
+		outcome = OutcomeError
+	}
+
+	span.SetAttributes(attribute.String("deadline.outcome", outcome))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, outcome)
+	}
+
+	counter, counterErr := c.NewCounter("deadline_operations", "Total number of operations run with a deadline, by outcome")
+	if counterErr != nil {
+		c.Logger.ErrorContext(ctx, "failed to create deadline counter", "error", counterErr)
+		return err
+	}
+	counter.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("operation", c.labelGuard.value("deadline.operation", name)),
+		attribute.String("outcome", outcome),
+	))
+	return err
+}
@@ -0,0 +1,92 @@
+package telemetry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestTimeoutMiddlewareRecordsOnce(t *testing.T) {
+	const route = "/slow/{id}"
+	tests := []struct {
+		name string
+		wrap func(tt *testTelemetry, metrics *HTTPMetrics, h http.Handler) http.Handler
+	}{
+		{
+			name: "WithTimeout",
+			wrap: func(tt *testTelemetry, metrics *HTTPMetrics, h http.Handler) http.Handler {
+				return tt.client.HTTPMiddleware(metrics, WithRoute(route), WithTimeout(20*time.Millisecond))(h)
+			},
+		},
+		{
+			name: "inside HTTPMiddleware",
+			wrap: func(tt *testTelemetry, metrics *HTTPMetrics, h http.Handler) http.Handler {
+				h = tt.client.TimeoutMiddleware(20 * time.Millisecond)(h)
+				return tt.client.HTTPMiddleware(metrics, WithRoute(route))(h)
+			},
+		},
+		{
+			name: "standalone",
+			wrap: func(tt *testTelemetry, metrics *HTTPMetrics, h http.Handler) http.Handler {
+				return tt.client.TimeoutMiddleware(20*time.Millisecond, WithRoute(route), WithHTTPMetrics(metrics))(h)
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTestTelemetry(t, Config{})
+			metrics, err := tt.client.NewHTTPMetrics()
+			if err != nil {
+				t.Fatal(err)
+			}
+			handler := tc.wrap(tt, metrics, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				<-r.Context().Done()
+			}))
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow/42", nil))
+
+			if rec.Code != http.StatusServiceUnavailable {
+				t.Fatalf("status = %d, want 503", rec.Code)
+			}
+			if got := tt.sum(t, "http_errors_total"); got != 1 {
+				t.Errorf("http_errors_total = %v, want 1", got)
+			}
+			if got := tt.sum(t, "http_errors_total", attribute.String("error_type", "handler_timeout"), attribute.String("endpoint", route)); got != 1 {
+				t.Errorf("http_errors_total{handler_timeout,%s} = %v, want 1", route, got)
+			}
+		})
+	}
+}
+
+func TestTimeoutMiddlewareLatePanic(t *testing.T) {
+	tt := newTestTelemetry(t, Config{})
+	release := make(chan struct{})
+	panicked := make(chan struct{})
+	handler := tt.client.HTTPMiddleware(tt.client.sharedHTTPMetrics(), WithRoute("/late"), WithTimeout(10*time.Millisecond))(
+		http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			defer close(panicked)
+			<-release
+			panic("late")
+		}),
+	)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/late", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", rec.Code)
+	}
+	close(release)
+	<-panicked
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !tt.hasMetric(t, "panics_total") && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := tt.sum(t, "panics_total", attribute.String("source", "http")); got != 1 {
+		t.Errorf("panics_total{source=http} = %v, want 1", got)
+	}
+}
@@ -0,0 +1,110 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"hash/fnv"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceColors are the ANSI foreground colors assigned to traces, readable on dark and light
+// terminals
+var traceColors = []string{
+	"\x1b[31m", "\x1b[32m", "\x1b[33m", "\x1b[34m", "\x1b[35m", "\x1b[36m",
+	"\x1b[91m", "\x1b[92m", "\x1b[93m", "\x1b[94m", "\x1b[95m", "\x1b[96m",
+}
+
+const colorReset = "\x1b[0m"
+
+// TraceColorHandler is slog's text handler coloring each line after the trace of the record
+// context, so the logs of one request share a color when concurrent requests interleave. The
+// color is a stable hash of the trace ID; records without a trace are left uncolored. It is a
+// development aid: colors are only written when w is a terminal and NO_COLOR is not set,
+// otherwise the output is the plain text handler's.
+type TraceColorHandler struct {
+	handler slog.Handler
+	out     *colorWriter
+}
+
+// colorWriter wraps every line the text handler writes in the color of the record being
+// handled. The text handler writes a record in a single call.
+type colorWriter struct {
+	mu      sync.Mutex // serializes records, so color is the one of the record being written
+	w       io.Writer
+	enabled bool
+	color   string
+}
+
+func (cw *colorWriter) Write(p []byte) (int, error) {
+	if cw.color == "" {
+		return cw.w.Write(p)
+	}
+	line := bytes.TrimSuffix(p, []byte("\n"))
+	colored := make([]byte, 0, len(p)+len(cw.color)+len(colorReset))
+	colored = append(colored, cw.color...)
+	colored = append(colored, line...)
+	colored = append(colored, colorReset...)
+	if len(line) < len(p) {
+		colored = append(colored, '\n')
+	}
+	if _, err := cw.w.Write(colored); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// NewTraceColorHandler creates a text handler writing to w. opts may be nil and is passed to
+// slog.NewTextHandler.
+func NewTraceColorHandler(w io.Writer, opts *slog.HandlerOptions) *TraceColorHandler {
+	out := &colorWriter{w: w, enabled: isTerminal(w) && os.Getenv("NO_COLOR") == ""}
+	return &TraceColorHandler{handler: slog.NewTextHandler(out, opts), out: out}
+}
+
+// isTerminal reports whether w is a character device, such as an interactive terminal
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// traceColor returns the color of traceID, the same for every record of the trace
+func traceColor(traceID trace.TraceID) string {
+	hash := fnv.New32a()
+	hash.Write(traceID[:])
+	return traceColors[hash.Sum32()%uint32(len(traceColors))]
+}
+
+func (h *TraceColorHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *TraceColorHandler) Handle(ctx context.Context, record slog.Record) error {
+	if !h.out.enabled {
+		return h.handler.Handle(ctx, record)
+	}
+
+	color := ""
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		color = traceColor(spanContext.TraceID())
+	}
+	h.out.mu.Lock()
+	defer h.out.mu.Unlock()
+	h.out.color = color
+	return h.handler.Handle(ctx, record)
+}
+
+func (h *TraceColorHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &TraceColorHandler{handler: h.handler.WithAttrs(attrs), out: h.out}
+}
+
+func (h *TraceColorHandler) WithGroup(name string) slog.Handler {
+	return &TraceColorHandler{handler: h.handler.WithGroup(name), out: h.out}
+}
@@ -0,0 +1,399 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceIDHeader is the response header used by SetTraceResponseHeader
+const TraceIDHeader = "X-Trace-ID"
+
+// TraceIDFromContext returns the trace ID of the active span, if any
+func (c *TelemetryClient) TraceIDFromContext(ctx context.Context) (string, bool) {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return "", false
+	}
+	return spanContext.TraceID().String(), true
+}
+
+// SpanIDFromContext returns the span ID of the active span, if any
+func (c *TelemetryClient) SpanIDFromContext(ctx context.Context) (string, bool) {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return "", false
+	}
+	return spanContext.SpanID().String(), true
+}
+
+// SetTraceResponseHeader writes the active trace ID to the response so clients can reference it in support tickets
+func (c *TelemetryClient) SetTraceResponseHeader(w http.ResponseWriter, ctx context.Context) {
+	if traceID, ok := c.TraceIDFromContext(ctx); ok {
+		w.Header().Set(TraceIDHeader, traceID)
+	}
+}
+
+// TraceCarrier holds a span context in W3C form, to hand it to goroutine pools or over
+// channels and queues. It is a plain value, safe to copy and to marshal as JSON.
+type TraceCarrier struct {
+	TraceParent string `json:"traceparent,omitempty"`
+	TraceState  string `json:"tracestate,omitempty"`
+}
+
+// CaptureTrace captures the active span context. Only the span context is kept: labels,
+// baggage and the context deadline stay behind. The carrier is empty without an active span.
+func (c *TelemetryClient) CaptureTrace(ctx context.Context) TraceCarrier {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(contextOrBackground(ctx), carrier)
+	return TraceCarrier{TraceParent: carrier["traceparent"], TraceState: carrier["tracestate"]}
+}
+
+// RestoreTrace attaches the captured span context to ctx, so the correlated logger and the
+// spans started from it join the original trace. An empty or invalid carrier returns ctx as is.
+func (c *TelemetryClient) RestoreTrace(ctx context.Context, carrier TraceCarrier) context.Context {
+	ctx = contextOrBackground(ctx)
+	if carrier.TraceParent == "" {
+		return ctx
+	}
+	return propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier{
+		"traceparent": carrier.TraceParent,
+		"tracestate":  carrier.TraceState,
+	})
+}
+
+// SetTraceState returns a context whose span context carries key=value in its W3C tracestate
+// (e.g. vendor routing or sampling hints). Span contexts are immutable: the active span keeps
+// recording through the returned context, but only the returned context carries the new
+// state, to child spans started from it and to outgoing requests. The span itself is
+// exported with the state it started with. Invalid keys or values (per the W3C spec) and
+// contexts without an active span return an error and ctx unchanged.
+func (c *TelemetryClient) SetTraceState(ctx context.Context, key, value string) (context.Context, error) {
+	ctx = contextOrBackground(ctx)
+	span := trace.SpanFromContext(ctx)
+	spanContext := span.SpanContext()
+	if !spanContext.IsValid() {
+		return ctx, fmt.Errorf("cannot set tracestate %q: no active span", key)
+	}
+	state, err := spanContext.TraceState().Insert(key, value)
+	if err != nil {
+		return ctx, fmt.Errorf("invalid tracestate entry %q: %w", key, err)
+	}
+	return trace.ContextWithSpan(ctx, &traceStateSpan{Span: span, spanContext: spanContext.WithTraceState(state)}), nil
+}
+
+// TraceStateValue returns the value of key in the tracestate of the active span context
+func (c *TelemetryClient) TraceStateValue(ctx context.Context, key string) (string, bool) {
+	value := trace.SpanContextFromContext(contextOrBackground(ctx)).TraceState().Get(key)
+	return value, value != ""
+}
+
+// traceStateSpan is the active span seen with an updated span context
+type traceStateSpan struct {
+	trace.Span
+	spanContext trace.SpanContext
+}
+
+func (s *traceStateSpan) SpanContext() trace.SpanContext {
+	return s.spanContext
+}
+
+// StartJobSpan starts the root span of a scheduled or queued job, with kind consumer, linked to
+// the trace that triggered it. parentCarrier holds that trace as injected by the global
+// propagator (e.g. {"traceparent": "..."} stored in the job payload). A link keeps the
+// scheduler and executor traces separate but connected; a nil, empty or invalid carrier
+// just starts an unlinked root span.
+func (c *TelemetryClient) StartJobSpan(ctx context.Context, name string, parentCarrier map[string]string) (context.Context, trace.Span) {
+	opts := []trace.SpanStartOption{trace.WithNewRoot(), trace.WithSpanKind(trace.SpanKindConsumer)}
+	if len(parentCarrier) > 0 {
+		parent := otel.GetTextMapPropagator().Extract(context.Background(), propagation.MapCarrier(parentCarrier))
+		if spanContext := trace.SpanContextFromContext(parent); spanContext.IsValid() {
+			opts = append(opts, trace.WithLinks(trace.Link{SpanContext: spanContext}))
+		}
+	}
+	return c.StartSpan(ctx, name, opts...)
+}
+
+// StartSpan starts a span with the client tracer. Pass trace.WithSpanKind to set the kind:
+// SpanKindClient for outbound calls, SpanKindProducer/SpanKindConsumer for messaging. Backends
+// like Tempo rely on the kinds to build service graphs. Without it the span is internal.
+//
+// The start attributes are also kept in the returned context (merged over the parent's) so
+// StartChildSpan can copy them to child spans. With Config.LogSpanLifecycle the span start
+// and end are logged at debug level.
+func (c *TelemetryClient) StartSpan(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	ctx, span := c.Tracer.Start(contextOrBackground(ctx), name, opts...)
+	ctx = withStartAttrs(ctx, opts)
+	if c.logSpanLifecycle {
+		// Stored in the context too, so ending the span taken from it also logs
+		span = c.logLifecycle(ctx, name, span)
+		ctx = trace.ContextWithSpan(ctx, span)
+	}
+	return ctx, span
+}
+
+// EnsureSpan starts a span only when ctx has no recording span, for helpers called both
+// standalone and from handlers that already trace the work. Otherwise it returns ctx unchanged
+// and a no-op finish function; call finish when done in both cases.
+func (c *TelemetryClient) EnsureSpan(ctx context.Context, name string) (context.Context, func()) {
+	ctx = contextOrBackground(ctx)
+	if trace.SpanFromContext(ctx).IsRecording() {
+		return ctx, func() {}
+	}
+	ctx, span := c.StartSpan(ctx, name)
+	return ctx, func() { span.End() }
+}
+
+// withStartAttrs records the span start attributes in the context for StartChildSpan
+func withStartAttrs(ctx context.Context, opts []trace.SpanStartOption) context.Context {
+	startConfig := trace.NewSpanStartConfig(opts...)
+	attrs := startConfig.Attributes()
+	if len(attrs) == 0 {
+		return ctx
+	}
+	inherited := map[attribute.Key]attribute.Value{}
+	for key, value := range spanAttrsFromContext(ctx) {
+		inherited[key] = value
+	}
+	for _, attr := range attrs {
+		inherited[attr.Key] = attr.Value
+	}
+	return context.WithValue(ctx, spanAttrsKey{}, inherited)
+}
+
+// logLifecycle logs "span started" and wraps the span to log "span ended" with its duration.
+// Both use the span context so the lines carry its trace and span IDs.
+func (c *TelemetryClient) logLifecycle(ctx context.Context, name string, span trace.Span) trace.Span {
+	if !c.Logger.Enabled(ctx, slog.LevelDebug) {
+		return span
+	}
+	c.Logger.DebugContext(ctx, "span started", "span_name", name)
+	return &lifecycleSpan{Span: span, ctx: ctx, name: name, logger: c.Logger, start: time.Now()}
+}
+
+// lifecycleSpan logs once when the span ends
+type lifecycleSpan struct {
+	trace.Span
+	ctx    context.Context
+	name   string
+	logger *slog.Logger
+	start  time.Time
+	ended  atomic.Bool
+}
+
+func (s *lifecycleSpan) End(options ...trace.SpanEndOption) {
+	if !s.ended.Swap(true) {
+		// Logged before ending, while the span still records and correlates
+		s.logger.DebugContext(s.ctx, "span ended", "span_name", s.name, "duration_ms", time.Since(s.start).Milliseconds())
+	}
+	s.Span.End(options...)
+}
+
+// StartChildSpan starts a span copying the inherit attributes from its ancestors. A started
+// span cannot be read back, so only attributes given to StartSpan/StartChildSpan at start
+// time (kept in the context) can be inherited; later SetAttributes calls are not visible.
+// Missing keys are skipped.
+func (c *TelemetryClient) StartChildSpan(ctx context.Context, name string, inherit ...string) (context.Context, trace.Span) {
+	ctx = contextOrBackground(ctx)
+	available := spanAttrsFromContext(ctx)
+
+	attrs := make([]attribute.KeyValue, 0, len(inherit))
+	for _, key := range inherit {
+		if value, ok := available[attribute.Key(key)]; ok {
+			attrs = append(attrs, attribute.KeyValue{Key: attribute.Key(key), Value: value})
+		}
+	}
+	return c.StartSpan(ctx, name, trace.WithAttributes(attrs...))
+}
+
+type spanAttrsKey struct{}
+
+// spanAttrsFromContext returns the start attributes recorded by StartSpan
+func spanAttrsFromContext(ctx context.Context) map[attribute.Key]attribute.Value {
+	attrs, _ := ctx.Value(spanAttrsKey{}).(map[attribute.Key]attribute.Value)
+	return attrs
+}
+
+// StructAttrOption configures SetSpanAttrsFromStruct
+type StructAttrOption func(*structAttrConfig)
+
+type structAttrConfig struct {
+	skipZero bool
+}
+
+// SkipZeroValues omits fields holding their type's zero value
+func SkipZeroValues() StructAttrOption {
+	return func(cfg *structAttrConfig) {
+		cfg.skipZero = true
+	}
+}
+
+// taggedField is a struct field carrying an otel tag
+type taggedField struct {
+	index []int
+	key   string
+}
+
+// structFieldCache maps reflect.Type to its []taggedField
+var structFieldCache sync.Map
+
+// SetSpanAttrsFromStruct sets the fields of v tagged `otel:"key"` as attributes on the active span.
+// Untagged fields and fields tagged "-" are skipped. The tagged fields of each struct type are
+// resolved with reflection once and cached, so later calls only pay for reading the field values.
+func (c *TelemetryClient) SetSpanAttrsFromStruct(ctx context.Context, v any, opts ...StructAttrOption) {
+	span := trace.SpanFromContext(contextOrBackground(ctx))
+	if !span.IsRecording() {
+		return
+	}
+
+	cfg := &structAttrConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return
+	}
+
+	var attrs []attribute.KeyValue
+	for _, field := range taggedFields(value.Type()) {
+		fieldValue, err := value.FieldByIndexErr(field.index)
+		if err != nil || (cfg.skipZero && fieldValue.IsZero()) {
+			continue
+		}
+		attrs = append(attrs, attributeFromValue(field.key, fieldValue.Interface()))
+	}
+	span.SetAttributes(c.attrLimits.apply(attrs)...)
+}
+
+// AddSpanEvent adds an event with the given attributes to the active span
+func (c *TelemetryClient) AddSpanEvent(ctx context.Context, name string, attrs map[string]any) {
+	span := trace.SpanFromContext(contextOrBackground(ctx))
+	if !span.IsRecording() {
+		return
+	}
+
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for _, key := range slices.Sorted(maps.Keys(attrs)) {
+		kvs = append(kvs, attributeFromValue(key, attrs[key]))
+	}
+	span.AddEvent(name, trace.WithAttributes(c.attrLimits.apply(kvs)...))
+}
+
+// DefaultSpanAttributeCountLimit matches the OTel SDK default attribute count limit
+const DefaultSpanAttributeCountLimit = 128
+
+// attributeLimits caps the attributes set by a single helper call
+type attributeLimits struct {
+	count  int
+	length int
+}
+
+func newAttributeLimits(count, length int) attributeLimits {
+	if count <= 0 {
+		count = DefaultSpanAttributeCountLimit
+	}
+	return attributeLimits{count: count, length: length}
+}
+
+// apply truncates long string values on rune boundaries and drops attributes beyond the count limit,
+// keeping the last slot for otel.dropped_attributes when anything was dropped. attrs is not modified.
+func (l attributeLimits) apply(attrs []attribute.KeyValue) []attribute.KeyValue {
+	dropped := 0
+	if len(attrs) > l.count {
+		dropped = len(attrs) - l.count + 1
+		attrs = attrs[:l.count-1]
+	}
+	limited := make([]attribute.KeyValue, len(attrs), len(attrs)+1)
+	copy(limited, attrs)
+
+	if l.length > 0 {
+		for i, attr := range limited {
+			switch attr.Value.Type() {
+			case attribute.STRING:
+				if value := attr.Value.AsString(); len(value) > l.length {
+					limited[i] = attr.Key.String(truncateString(value, l.length))
+				}
+			case attribute.STRINGSLICE:
+				values := attr.Value.AsStringSlice()
+				for j, value := range values {
+					values[j] = truncateString(value, l.length)
+				}
+				limited[i] = attr.Key.StringSlice(values)
+			}
+		}
+	}
+
+	if dropped > 0 {
+		limited = append(limited, attribute.Int("otel.dropped_attributes", dropped))
+	}
+	return limited
+}
+
+func taggedFields(t reflect.Type) []taggedField {
+	if cached, ok := structFieldCache.Load(t); ok {
+		return cached.([]taggedField)
+	}
+
+	var fields []taggedField
+	for _, field := range reflect.VisibleFields(t) {
+		key, _, _ := strings.Cut(field.Tag.Get("otel"), ",")
+		if key == "" || key == "-" || !field.IsExported() {
+			continue
+		}
+		fields = append(fields, taggedField{index: field.Index, key: key})
+	}
+
+	structFieldCache.Store(t, fields)
+	return fields
+}
+
+// attributeFromValue converts a Go value into a span attribute
+func attributeFromValue(key string, value any) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case float64:
+		return attribute.Float64(key, v)
+	case []string:
+		return attribute.StringSlice(key, v)
+	case []bool:
+		return attribute.BoolSlice(key, v)
+	case []int:
+		return attribute.IntSlice(key, v)
+	case []int64:
+		return attribute.Int64Slice(key, v)
+	case []float64:
+		return attribute.Float64Slice(key, v)
+	case fmt.Stringer:
+		return attribute.String(key, v.String())
+	default:
+		return attribute.String(key, fmt.Sprint(v))
+	}
+}
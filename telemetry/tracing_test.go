@@ -0,0 +1,379 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSpanKinds(t *testing.T) {
+	tests := []struct {
+		name  string
+		start func(tt *testTelemetry)
+		want  trace.SpanKind
+	}{
+		{
+			name: "HTTPMiddleware",
+			start: func(tt *testTelemetry) {
+				tt.client.HTTPMiddleware(tt.client.sharedHTTPMetrics())(http.NotFoundHandler()).
+					ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+			},
+			want: trace.SpanKindServer,
+		},
+		{
+			name: "StartSpan default",
+			start: func(tt *testTelemetry) {
+				_, span := tt.client.StartSpan(context.Background(), "work")
+				span.End()
+			},
+			want: trace.SpanKindInternal,
+		},
+		{
+			name: "StartSpan client",
+			start: func(tt *testTelemetry) {
+				_, span := tt.client.StartSpan(context.Background(), "call", trace.WithSpanKind(trace.SpanKindClient))
+				span.End()
+			},
+			want: trace.SpanKindClient,
+		},
+		{
+			name: "StartSpan producer",
+			start: func(tt *testTelemetry) {
+				_, span := tt.client.StartSpan(context.Background(), "publish", trace.WithSpanKind(trace.SpanKindProducer))
+				span.End()
+			},
+			want: trace.SpanKindProducer,
+		},
+		{
+			name: "StartJobSpan",
+			start: func(tt *testTelemetry) {
+				_, span := tt.client.StartJobSpan(context.Background(), "job", nil)
+				span.End()
+			},
+			want: trace.SpanKindConsumer,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTestTelemetry(t, Config{})
+			tc.start(tt)
+			spans := tt.spans.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("got %d spans, want 1", len(spans))
+			}
+			if got := spans[0].SpanKind(); got != tc.want {
+				t.Errorf("kind = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCaptureRestoreTraceRoundTrip(t *testing.T) {
+	tt := newTestTelemetry(t, Config{})
+	ctx, parent := tt.client.StartSpan(context.Background(), "enqueue")
+	ctx, err := tt.client.SetTraceState(ctx, "vendor", "route-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Through JSON and a channel, as a queued job would
+	payload, err := json.Marshal(tt.client.CaptureTrace(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := make(chan []byte, 1)
+	jobs <- payload
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var carrier TraceCarrier
+		if err := json.Unmarshal(<-jobs, &carrier); err != nil {
+			t.Error(err)
+			return
+		}
+		workerCtx := tt.client.RestoreTrace(context.Background(), carrier)
+		tt.client.Logger.InfoContext(workerCtx, "processing")
+		_, child := tt.client.StartSpan(workerCtx, "process")
+		child.End()
+	}()
+	<-done
+	parent.End()
+
+	want := parent.SpanContext()
+	line := tt.logs.lines(t)[0]
+	if line["trace_id"] != want.TraceID().String() || line["span_id"] != want.SpanID().String() {
+		t.Errorf("worker log trace_id/span_id = %v/%v, want %s/%s", line["trace_id"], line["span_id"], want.TraceID(), want.SpanID())
+	}
+	child := tt.spans.Ended()[0]
+	if child.Name() != "process" || child.Parent().SpanID() != want.SpanID() || child.SpanContext().TraceID() != want.TraceID() {
+		t.Errorf("child span %q parent = %v, want a child of %v", child.Name(), child.Parent(), want)
+	}
+	if got := child.SpanContext().TraceState().Get("vendor"); got != "route-a" {
+		t.Errorf("tracestate vendor = %q, want route-a", got)
+	}
+}
+
+func TestRestoreTraceWithoutSpan(t *testing.T) {
+	tests := []struct {
+		name    string
+		carrier TraceCarrier
+	}{
+		{name: "empty"},
+		{name: "invalid", carrier: TraceCarrier{TraceParent: "00-not-a-trace-01"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTestTelemetry(t, Config{})
+			if got := tt.client.CaptureTrace(context.Background()); got != (TraceCarrier{}) {
+				t.Errorf("CaptureTrace without a span = %+v, want empty", got)
+			}
+			ctx := tt.client.RestoreTrace(context.Background(), tc.carrier)
+			if trace.SpanContextFromContext(ctx).IsValid() {
+				t.Error("restored an invalid carrier into a valid span context")
+			}
+		})
+	}
+}
+
+func TestEnsureSpan(t *testing.T) {
+	tests := []struct {
+		name      string
+		ctx       func(tt *testTelemetry) (context.Context, trace.Span)
+		wantSpans []string
+	}{
+		{
+			name:      "no span",
+			ctx:       func(tt *testTelemetry) (context.Context, trace.Span) { return context.Background(), nil },
+			wantSpans: []string{"helper"},
+		},
+		{
+			name: "non-recording remote span",
+			ctx: func(tt *testTelemetry) (context.Context, trace.Span) {
+				sc := trace.NewSpanContext(trace.SpanContextConfig{
+					TraceID:    trace.TraceID{1},
+					SpanID:     trace.SpanID{1},
+					TraceFlags: trace.FlagsSampled,
+					Remote:     true,
+				})
+				return trace.ContextWithRemoteSpanContext(context.Background(), sc), nil
+			},
+			wantSpans: []string{"helper"},
+		},
+		{
+			name: "existing span",
+			ctx: func(tt *testTelemetry) (context.Context, trace.Span) {
+				return tt.client.StartSpan(context.Background(), "handler")
+			},
+			wantSpans: []string{"handler"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTestTelemetry(t, Config{})
+			parentCtx, parent := tc.ctx(tt)
+
+			ctx, finish := tt.client.EnsureSpan(parentCtx, "helper")
+			helperSpan := trace.SpanFromContext(ctx)
+			if !helperSpan.IsRecording() {
+				t.Error("EnsureSpan returned a context without a recording span")
+			}
+			if parent != nil && helperSpan.SpanContext().SpanID() != parent.SpanContext().SpanID() {
+				t.Error("EnsureSpan started a span under an existing recording span")
+			}
+			finish()
+			if parent != nil {
+				parent.End()
+			}
+
+			var got []string
+			for _, span := range tt.spans.Ended() {
+				got = append(got, span.Name())
+			}
+			if len(got) != len(tc.wantSpans) {
+				t.Fatalf("ended spans = %v, want %v", got, tc.wantSpans)
+			}
+			for i := range got {
+				if got[i] != tc.wantSpans[i] {
+					t.Errorf("ended spans = %v, want %v", got, tc.wantSpans)
+				}
+			}
+		})
+	}
+}
+
+func TestAttributeLimitsApply(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits attributeLimits
+		attrs  []attribute.KeyValue
+		want   []attribute.KeyValue
+	}{
+		{
+			name:   "ascii",
+			limits: newAttributeLimits(0, 4),
+			attrs:  []attribute.KeyValue{attribute.String("q", "select")},
+			want:   []attribute.KeyValue{attribute.String("q", "sele")},
+		},
+		{
+			name:   "cut inside a two byte rune",
+			limits: newAttributeLimits(0, 4),
+			attrs:  []attribute.KeyValue{attribute.String("city", "açaí")}, // a ç(2) a í(2)
+			want:   []attribute.KeyValue{attribute.String("city", "aça")},
+		},
+		{
+			name:   "cut inside a four byte rune",
+			limits: newAttributeLimits(0, 6),
+			attrs:  []attribute.KeyValue{attribute.String("msg", "ok 🚀 go")},
+			want:   []attribute.KeyValue{attribute.String("msg", "ok ")},
+		},
+		{
+			name:   "slice values",
+			limits: newAttributeLimits(0, 3),
+			attrs:  []attribute.KeyValue{attribute.StringSlice("tags", []string{"ação", "ok", "日本"})},
+			want:   []attribute.KeyValue{attribute.StringSlice("tags", []string{"aç", "ok", "日"})},
+		},
+		{
+			name:   "short values kept",
+			limits: newAttributeLimits(0, 8),
+			attrs:  []attribute.KeyValue{attribute.String("city", "são"), attribute.Int("n", 12345678910)},
+			want:   []attribute.KeyValue{attribute.String("city", "são"), attribute.Int("n", 12345678910)},
+		},
+		{
+			name:   "count limit",
+			limits: newAttributeLimits(2, 0),
+			attrs:  []attribute.KeyValue{attribute.String("a", "1"), attribute.String("b", "2"), attribute.String("c", "3")},
+			want:   []attribute.KeyValue{attribute.String("a", "1"), attribute.Int("otel.dropped_attributes", 2)},
+		},
+		{
+			name:   "count limit of one",
+			limits: newAttributeLimits(1, 0),
+			attrs:  []attribute.KeyValue{attribute.String("a", "1"), attribute.String("b", "2")},
+			want:   []attribute.KeyValue{attribute.Int("otel.dropped_attributes", 2)},
+		},
+		{
+			name:   "at the count limit",
+			limits: newAttributeLimits(2, 0),
+			attrs:  []attribute.KeyValue{attribute.String("a", "1"), attribute.String("b", "2")},
+			want:   []attribute.KeyValue{attribute.String("a", "1"), attribute.String("b", "2")},
+		},
+		{
+			name:   "count limit with truncation",
+			limits: newAttributeLimits(2, 1),
+			attrs:  []attribute.KeyValue{attribute.String("a", "long"), attribute.String("b", "long"), attribute.String("c", "long")},
+			want:   []attribute.KeyValue{attribute.String("a", "l"), attribute.Int("otel.dropped_attributes", 2)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A backing array with spare capacity shows an append past the kept attributes
+			attrs := make([]attribute.KeyValue, len(tt.attrs), len(tt.attrs)+1)
+			copy(attrs, tt.attrs)
+			input := slices.Clone(attrs[:cap(attrs)])
+
+			got := tt.limits.apply(attrs)
+			if len(got) > tt.limits.count {
+				t.Errorf("apply returned %d attributes, over the count limit of %d", len(got), tt.limits.count)
+			}
+			if !slices.Equal(attrs[:cap(attrs)], input) {
+				t.Errorf("apply modified its input: %v, was %v", attrs[:cap(attrs)], input)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("apply = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].Key != tt.want[i].Key || got[i].Value != tt.want[i].Value {
+					t.Errorf("attr %d = %v, want %v", i, got[i], tt.want[i])
+				}
+				if value := got[i].Value.Emit(); !utf8.ValidString(value) {
+					t.Errorf("attr %d = %q, not valid UTF-8", i, value)
+				}
+			}
+		})
+	}
+}
+
+func TestAttributeLimitsSortedKeys(t *testing.T) {
+	attrs := map[string]any{"d": 4, "c": 3, "b": 2, "a": 1}
+	want := []attribute.KeyValue{attribute.Int("a", 1), attribute.Int("b", 2), attribute.Int("otel.dropped_attributes", 2)}
+	tests := []struct {
+		name  string
+		call  func(c *TelemetryClient, ctx context.Context)
+		attrs func(span sdktrace.ReadOnlySpan) []attribute.KeyValue
+	}{
+		{
+			name:  "AddSpanEvent",
+			call:  func(c *TelemetryClient, ctx context.Context) { c.AddSpanEvent(ctx, "event", attrs) },
+			attrs: func(span sdktrace.ReadOnlySpan) []attribute.KeyValue { return span.Events()[0].Attributes },
+		},
+		{
+			name: "LogWithSpanAttributes",
+			call: func(c *TelemetryClient, ctx context.Context) {
+				c.LogWithSpanAttributes(ctx, slog.LevelInfo, "attrs", attrs)
+			},
+			attrs: func(span sdktrace.ReadOnlySpan) []attribute.KeyValue { return span.Attributes() },
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTestTelemetry(t, Config{SpanAttributeCountLimit: 3})
+			// Map iteration order changes between runs, the kept attributes must not
+			for range 10 {
+				ctx, span := tt.client.StartSpan(context.Background(), "op")
+				tc.call(tt.client, ctx)
+				span.End()
+			}
+			for _, span := range tt.spans.Ended() {
+				if got := tc.attrs(span); !slices.Equal(got, want) {
+					t.Fatalf("attributes = %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLogSpanLifecycleContextSpan(t *testing.T) {
+	tests := []struct {
+		name string
+		end  func(ctx context.Context, span trace.Span)
+	}{
+		{name: "returned span", end: func(ctx context.Context, span trace.Span) { span.End() }},
+		{name: "context span", end: func(ctx context.Context, span trace.Span) { trace.SpanFromContext(ctx).End() }},
+		{name: "both", end: func(ctx context.Context, span trace.Span) {
+			trace.SpanFromContext(ctx).End()
+			span.End()
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTestTelemetry(t, Config{LogSpanLifecycle: true})
+			ctx, span := tt.client.StartSpan(context.Background(), "work")
+			tc.end(ctx, span)
+
+			var ended int
+			for _, line := range tt.logs.lines(t) {
+				if line["msg"] != "span ended" {
+					continue
+				}
+				ended++
+				if line["span_id"] != span.SpanContext().SpanID().String() {
+					t.Errorf("span_id = %v, want %s", line["span_id"], span.SpanContext().SpanID())
+				}
+			}
+			if ended != 1 {
+				t.Errorf("logged %d \"span ended\" lines, want 1", ended)
+			}
+			if len(tt.spans.Ended()) != 1 {
+				t.Errorf("ended %d spans, want 1", len(tt.spans.Ended()))
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package telemetry
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// AttributeTransform rewrites an attribute before it is recorded, e.g. to lowercase keys or
+// rename legacy ones. Returning false drops the attribute.
+type AttributeTransform func(key string, value any) (string, any, bool)
+
+// apply runs the transform over metric attributes, returning a new slice
+func (t AttributeTransform) apply(attrs []attribute.KeyValue) []attribute.KeyValue {
+	if t == nil {
+		return attrs
+	}
+	transformed := make([]attribute.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		key, value, ok := t(string(attr.Key), attr.Value.AsInterface())
+		if ok {
+			transformed = append(transformed, attributeFromValue(key, value))
+		}
+	}
+	return transformed
+}
+
+// applyMap runs the transform over the attributes of LogWithSpanAttributes
+func (t AttributeTransform) applyMap(attrs map[string]any) map[string]any {
+	if t == nil {
+		return attrs
+	}
+	transformed := make(map[string]any, len(attrs))
+	for key, value := range attrs {
+		if key, value, ok := t(key, value); ok {
+			transformed[key] = value
+		}
+	}
+	return transformed
+}
@@ -0,0 +1,41 @@
+package telemetry
+
+import (
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestAttributeTransformKeepsKinds(t *testing.T) {
+	lower := AttributeTransform(func(key string, value any) (string, any, bool) {
+		return strings.ToLower(key), value, true
+	})
+	tests := []struct {
+		name string
+		attr attribute.KeyValue
+	}{
+		{name: "string", attr: attribute.String("Tenant", "acme")},
+		{name: "bool", attr: attribute.Bool("Cached", true)},
+		{name: "int64", attr: attribute.Int64("Retries", 3)},
+		{name: "float64", attr: attribute.Float64("Ratio", 0.5)},
+		{name: "string slice", attr: attribute.StringSlice("Tags", []string{"a", "b"})},
+		{name: "bool slice", attr: attribute.BoolSlice("Flags", []bool{true, false})},
+		{name: "int64 slice", attr: attribute.Int64Slice("Codes", []int64{200, 404})},
+		{name: "float64 slice", attr: attribute.Float64Slice("Weights", []float64{0.1, 0.9})},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := lower.apply([]attribute.KeyValue{tc.attr})
+			if len(got) != 1 {
+				t.Fatalf("got %d attributes, want 1", len(got))
+			}
+			if want := attribute.Key(strings.ToLower(string(tc.attr.Key))); got[0].Key != want {
+				t.Errorf("key = %q, want %q", got[0].Key, want)
+			}
+			if got[0].Value != tc.attr.Value {
+				t.Errorf("value = %s %v, want %s %v", got[0].Value.Type(), got[0].Value.Emit(), tc.attr.Value.Type(), tc.attr.Value.Emit())
+			}
+		})
+	}
+}
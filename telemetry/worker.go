@@ -0,0 +1,114 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// WorkerMetrics provides common metrics for background workers and queue consumers
+type WorkerMetrics struct {
+	ProcessedTotal     metric.Int64Counter
+	ProcessingDuration metric.Float64Histogram
+	FailuresTotal      metric.Int64Counter
+
+	name       string
+	meter      metric.Meter
+	labelGuard *cardinalityGuard
+	dynamic    *dynamicAttrs
+	transform  AttributeTransform
+}
+
+// NewWorkerMetrics creates worker metrics namespaced with the worker name:
+// <name>_processed_total, <name>_processing_duration_seconds, <name>_failures_total and,
+// through QueueDepth, <name>_queue_depth
+func (c *TelemetryClient) NewWorkerMetrics(name string) (*WorkerMetrics, error) {
+	if !instrumentNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid worker name %q: must start with a letter and contain only letters, digits, '_', '.', '-' or '/'", name)
+	}
+
+	processedTotal, err := c.Meter.Int64Counter(
+		name+"_processed_total",
+		metric.WithDescription("Total number of items processed by the "+name+" worker"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create processed counter: %w", err)
+	}
+
+	processingDuration, err := c.Meter.Float64Histogram(
+		name+"_processing_duration_seconds",
+		metric.WithDescription("Duration of item processing by the "+name+" worker in seconds"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create processing duration histogram: %w", err)
+	}
+
+	failuresTotal, err := c.Meter.Int64Counter(
+		name+"_failures_total",
+		metric.WithDescription("Total number of items the "+name+" worker failed to process"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create failures counter: %w", err)
+	}
+
+	return &WorkerMetrics{
+		ProcessedTotal:     processedTotal,
+		ProcessingDuration: processingDuration,
+		FailuresTotal:      failuresTotal,
+		name:               name,
+		meter:              c.Meter,
+		labelGuard:         c.labelGuard,
+		dynamic:            c.dynamicAttrs,
+		transform:          c.attrTransform,
+	}, nil
+}
+
+// RecordProcessed records one successfully processed item
+func (m *WorkerMetrics) RecordProcessed(ctx context.Context) {
+	ctx = contextOrBackground(ctx)
+	m.ProcessedTotal.Add(ctx, 1, metric.WithAttributes(m.attributes(ctx)...))
+}
+
+// RecordDuration records how long processing one item took
+func (m *WorkerMetrics) RecordDuration(ctx context.Context, duration time.Duration) {
+	ctx = contextOrBackground(ctx)
+	m.ProcessingDuration.Record(ctx, duration.Seconds(), metric.WithAttributes(m.attributes(ctx)...))
+}
+
+// RecordFailure records an item that failed, classified by errorType (keep it low cardinality)
+func (m *WorkerMetrics) RecordFailure(ctx context.Context, errorType string) {
+	ctx = contextOrBackground(ctx)
+	m.FailuresTotal.Add(ctx, 1, metric.WithAttributes(m.attributes(ctx,
+		attribute.String("error_type", errorType),
+	)...))
+}
+
+// QueueDepth registers the <name>_queue_depth gauge, calling observe on every collection.
+// observe must be cheap and safe to call from the SDK's collection goroutine.
+func (m *WorkerMetrics) QueueDepth(observe func() int64) error {
+	_, err := m.meter.Int64ObservableGauge(
+		m.name+"_queue_depth",
+		metric.WithDescription("Number of items waiting in the "+m.name+" worker queue"),
+		metric.WithUnit("1"),
+		metric.WithInt64Callback(func(_ context.Context, observer metric.Int64Observer) error {
+			observer.Observe(observe())
+			return nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create queue depth gauge: %w", err)
+	}
+	return nil
+}
+
+// attributes adds the context labels and the dynamic attributes to attrs, without overriding
+// them, then applies the attribute transform
+func (m *WorkerMetrics) attributes(ctx context.Context, attrs ...attribute.KeyValue) []attribute.KeyValue {
+	return m.transform.apply(m.labelGuard.dynamicAttributes(m.dynamic, m.labelGuard.metricAttributes(ctx, attrs...)))
+}